@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
 	"github.com/WillKirkmanM/proxy/internal/proxy"
 )
 
@@ -22,7 +23,14 @@ import (
 // Time Complexity: O(1) - constant initialisation time
 // Space Complexity: O(1) - fixed memory allocation
 func main() {
+    // Dispatch subcommands before parsing server flags
+    // "proxy validate -config file" checks a configuration without serving traffic
+    if len(os.Args) > 1 && os.Args[1] == "validate" {
+        os.Exit(runValidate(os.Args[2:]))
+    }
+
     var configPath = flag.String("config", "config.yaml", "Path to configuration file")
+    var watchInterval = flag.Duration("watch", 0, "Poll interval for reloading the configuration file on change (0 disables)")
     flag.Parse()
 
     // Load configuration using singleton pattern
@@ -35,6 +43,12 @@ func main() {
 	}
 	cfg := config.GetInstance()
 
+    // Reject invalid configuration before any component is constructed
+    // All problems are reported together with their YAML paths
+    if err := cfg.Validate(loadbalancer.Algorithms()); err != nil {
+        log.Fatal(err)
+    }
+
 
     // Create proxy server instance using factory pattern
     // The factory handles complex initialisation logic and dependency injection
@@ -49,9 +63,22 @@ func main() {
     defer cancel()
 
     // Channel for OS signals - enables graceful shutdown on SIGINT/SIGTERM
+    // and configuration reload on SIGHUP
     // Buffer size of 1 prevents blocking on signal delivery
     sigChan := make(chan os.Signal, 1)
-    signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+    signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+    // File changes are funnelled into the same loop as SIGHUP
+    // so reloads are never applied concurrently
+    reloadChan := make(chan struct{}, 1)
+    if *watchInterval > 0 {
+        go config.Watch(ctx, *configPath, *watchInterval, func() {
+            select {
+            case reloadChan <- struct{}{}:
+            default: // A reload is already pending
+            }
+        })
+    }
 
     // Start server in separate goroutine to prevent blocking main thread
     // This allows concurrent signal handling and server operation
@@ -62,9 +89,22 @@ func main() {
         }
     }()
 
-    // Block until termination signal is received
+    // Block until termination signal is received, reloading on demand
     // This implements the main event loop pattern
-    <-sigChan
+    for waiting := true; waiting; {
+        select {
+        case sig := <-sigChan:
+            if sig == syscall.SIGHUP {
+                log.Println("Received SIGHUP, reloading configuration...")
+                reloadConfig(server, *configPath)
+                continue
+            }
+            waiting = false
+        case <-reloadChan:
+            log.Printf("Configuration file %s changed, reloading...", *configPath)
+            reloadConfig(server, *configPath)
+        }
+    }
     log.Println("Received termination signal, shutting down gracefully...")
 
     // Cancel context to signal all components to shutdown
@@ -80,4 +120,25 @@ func main() {
     }
 
     log.Println("Proxy server stopped")
+}
+
+// reloadConfig re-reads the configuration file and applies it to the server
+// A config that fails to load or validate is rejected and the running
+// configuration keeps serving; the reason is logged for the operator
+// Time Complexity: O(n) where n is config file size plus number of backends
+// Space Complexity: O(n) for the new configuration generation
+func reloadConfig(server *proxy.Server, path string) {
+    cfg, err := config.Load(path)
+    if err != nil {
+        log.Printf("Configuration reload rejected, keeping current configuration: %v", err)
+        return
+    }
+
+    if err := server.Reload(cfg); err != nil {
+        log.Printf("Configuration reload rejected, keeping current configuration: %v", err)
+        return
+    }
+
+    config.SetInstance(cfg)
+    log.Printf("Configuration reloaded from %s", path)
 }
\ No newline at end of file
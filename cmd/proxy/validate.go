@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// runValidate implements the "validate" subcommand
+// Loads the configuration file and reports every validation problem
+// Returns process exit code: 0 when valid, 1 when loading or validation fails
+// Time Complexity: O(n) where n is config file size
+// Space Complexity: O(n) for parsed configuration
+func runValidate(args []string) int {
+    flags := flag.NewFlagSet("validate", flag.ExitOnError)
+    configPath := flags.String("config", "config.yaml", "Path to configuration file")
+    flags.Parse(args)
+
+    cfg, err := config.Load(*configPath)
+    if err != nil {
+        fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+        return 1
+    }
+
+    if err := cfg.Validate(loadbalancer.Algorithms()); err != nil {
+        // Print one problem per line so CI logs are easy to scan
+        var validationErr *config.ValidationError
+        if errors.As(err, &validationErr) {
+            for _, fieldErr := range validationErr.Errors {
+                fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, fieldErr)
+            }
+        } else {
+            fmt.Fprintf(os.Stderr, "%s: %v\n", *configPath, err)
+        }
+        return 1
+    }
+
+    fmt.Printf("%s: configuration is valid\n", *configPath)
+    return 0
+}
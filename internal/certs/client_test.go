@@ -0,0 +1,66 @@
+package certs
+
+import (
+	"crypto/tls"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestNewClientTLSConfigMutualTLS verifies the client certificate is presented
+// and the server is verified against the configured CA bundle
+func TestNewClientTLSConfigMutualTLS(t *testing.T) {
+    var presented string
+    server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if len(r.TLS.PeerCertificates) > 0 {
+            presented = r.TLS.PeerCertificates[0].Subject.CommonName
+        }
+    }))
+    server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+    server.StartTLS()
+    defer server.Close()
+
+    dir := t.TempDir()
+    caFile := filepath.Join(dir, "ca.pem")
+    caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+    if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+        t.Fatalf("failed to write CA bundle: %v", err)
+    }
+    certFile, keyFile := writeCertificate(t, dir, "client", time.Now().Add(time.Hour), "client.example.com")
+
+    tlsConfig, err := NewClientTLSConfig(ClientOptions{CertFile: certFile, KeyFile: keyFile, CAFile: caFile})
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+    resp, err := client.Get(server.URL)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    resp.Body.Close()
+
+    if presented != "client.example.com" {
+        t.Errorf("Expected client certificate client.example.com, got %q", presented)
+    }
+}
+
+// TestNewClientTLSConfigErrors verifies unreadable certificates and CA bundles are reported
+func TestNewClientTLSConfigErrors(t *testing.T) {
+    dir := t.TempDir()
+    if _, err := NewClientTLSConfig(ClientOptions{CertFile: filepath.Join(dir, "missing.crt")}); err == nil {
+        t.Error("Expected error for missing client key pair")
+    }
+
+    emptyCA := filepath.Join(dir, "empty.pem")
+    if err := os.WriteFile(emptyCA, []byte("not a certificate"), 0o600); err != nil {
+        t.Fatalf("failed to write CA bundle: %v", err)
+    }
+    if _, err := NewClientTLSConfig(ClientOptions{CAFile: emptyCA}); err == nil {
+        t.Error("Expected error for CA bundle without certificates")
+    }
+}
@@ -0,0 +1,134 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeCertificate generates a self-signed certificate for names and writes
+// the PEM encoded pair into dir as <base>.crt and <base>.key
+// Returns the certificate and key file paths
+func writeCertificate(t *testing.T, dir, base string, notAfter time.Time, names ...string) (string, string) {
+    t.Helper()
+
+    key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+    if err != nil {
+        t.Fatalf("failed to generate key: %v", err)
+    }
+
+    serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+    template := &x509.Certificate{
+        SerialNumber: serial,
+        Subject:      pkix.Name{CommonName: names[0]},
+        DNSNames:     names,
+        NotBefore:    time.Now().Add(-time.Hour),
+        NotAfter:     notAfter,
+        KeyUsage:     x509.KeyUsageDigitalSignature,
+        ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+    }
+    der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+    if err != nil {
+        t.Fatalf("failed to create certificate: %v", err)
+    }
+    keyDER, err := x509.MarshalECPrivateKey(key)
+    if err != nil {
+        t.Fatalf("failed to marshal key: %v", err)
+    }
+
+    certFile := filepath.Join(dir, base+".crt")
+    keyFile := filepath.Join(dir, base+".key")
+    certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+    keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+    if err := os.WriteFile(certFile, certPEM, 0o600); err != nil {
+        t.Fatalf("failed to write certificate: %v", err)
+    }
+    if err := os.WriteFile(keyFile, keyPEM, 0o600); err != nil {
+        t.Fatalf("failed to write key: %v", err)
+    }
+    return certFile, keyFile
+}
+
+// leafName parses a served certificate and returns its common name
+func leafName(t *testing.T, raw [][]byte) string {
+    t.Helper()
+    leaf, err := x509.ParseCertificate(raw[0])
+    if err != nil {
+        t.Fatalf("failed to parse certificate: %v", err)
+    }
+    return leaf.Subject.CommonName
+}
+
+// TestReloaderServesCertificate verifies the initial pair is served
+func TestReloaderServesCertificate(t *testing.T) {
+    dir := t.TempDir()
+    certFile, keyFile := writeCertificate(t, dir, "server", time.Now().Add(time.Hour), "first.example.com")
+
+    reloader, err := NewReloader(certFile, keyFile)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    cert, _ := reloader.GetCertificate(nil)
+    if name := leafName(t, cert.Certificate); name != "first.example.com" {
+        t.Errorf("Expected first.example.com, got %s", name)
+    }
+}
+
+// TestReloaderMissingFiles verifies startup fails when the pair cannot be loaded
+func TestReloaderMissingFiles(t *testing.T) {
+    dir := t.TempDir()
+    if _, err := NewReloader(filepath.Join(dir, "missing.crt"), filepath.Join(dir, "missing.key")); err == nil {
+        t.Error("Expected error for missing certificate files")
+    }
+}
+
+// TestReloaderReloadPartialRotation verifies a mismatched pair keeps the previous certificate
+// and that the completed rotation replaces it
+func TestReloaderReloadPartialRotation(t *testing.T) {
+    dir := t.TempDir()
+    certFile, keyFile := writeCertificate(t, dir, "server", time.Now().Add(time.Hour), "first.example.com")
+
+    reloader, err := NewReloader(certFile, keyFile)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    rotatedCert, rotatedKey := writeCertificate(t, t.TempDir(), "server", time.Now().Add(time.Hour), "second.example.com")
+    copyFile(t, rotatedCert, certFile)
+
+    if err := reloader.Reload(); err == nil {
+        t.Fatal("Expected error for certificate without matching key")
+    }
+    if name := leafName(t, reloader.Certificate().Certificate); name != "first.example.com" {
+        t.Fatalf("Expected previous certificate during partial rotation, got %s", name)
+    }
+
+    copyFile(t, rotatedKey, keyFile)
+    if err := reloader.Reload(); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if name := leafName(t, reloader.Certificate().Certificate); name != "second.example.com" {
+        t.Errorf("Expected rotated certificate, got %s", name)
+    }
+}
+
+// copyFile replaces dst with the contents of src
+func copyFile(t *testing.T, src, dst string) {
+    t.Helper()
+    data, err := os.ReadFile(src)
+    if err != nil {
+        t.Fatalf("failed to read %s: %v", src, err)
+    }
+    if err := os.WriteFile(dst, data, 0o600); err != nil {
+        t.Fatalf("failed to write %s: %v", dst, err)
+    }
+}
@@ -0,0 +1,311 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pair identifies a PEM certificate file and its private key file
+type Pair struct {
+    CertFile string
+    KeyFile  string
+}
+
+// CertificateInfo describes a loaded certificate for monitoring
+// Reported through Options.OnUpdate whenever the served set changes
+type CertificateInfo struct {
+    File       string    // Certificate file the entry was loaded from
+    CommonName string    // Subject common name of the leaf certificate
+    Names      []string  // DNS names the certificate is selected for
+    NotAfter   time.Time // Expiry time of the leaf certificate
+}
+
+// Options configures which certificates a Store serves
+// Pairs from Dir are discovered as <name>.crt with a sibling <name>.key
+type Options struct {
+    Default  *Pair                   // Served when no name matches; first loaded certificate otherwise
+    Pairs    []Pair                  // Explicitly configured pairs
+    Dir      string                  // Directory scanned for additional pairs, empty disables
+    OnUpdate func([]CertificateInfo) // Called after the served set is (re)built, may be nil
+}
+
+// Store selects certificates by SNI server name for tls.Config.GetCertificate
+// Matches exact DNS names first, then single-label wildcards (*.example.com),
+// then falls back to the default certificate
+// Time Complexity: O(1) per handshake - map lookups on an immutable index
+// Space Complexity: O(c + n) where c is certificates and n is DNS names
+type Store struct {
+    options   Options
+    reloaders map[string]*Reloader      // Loaded pairs keyed by certificate file
+    index     atomic.Pointer[certIndex] // Name lookup table used by handshakes
+    mutex     sync.Mutex                // Serialises refreshes of reloaders and index
+}
+
+// certIndex is an immutable name lookup table rebuilt on every change
+type certIndex struct {
+    exact    map[string]*tls.Certificate // Full DNS name to certificate
+    wildcard map[string]*tls.Certificate // Parent domain of *.domain to certificate
+    fallback *tls.Certificate            // Default certificate for unmatched names
+}
+
+// NewStore loads every configured certificate and builds the SNI index
+// Fails if any initial pair cannot be loaded so misconfiguration surfaces at startup
+// Time Complexity: O(c) where c is number of certificate pairs
+// Space Complexity: O(c) for loaded certificates
+func NewStore(options Options) (*Store, error) {
+    s := &Store{
+        options:   options,
+        reloaders: make(map[string]*Reloader),
+    }
+    if err := s.refresh(true, true); err != nil {
+        return nil, err
+    }
+    if s.index.Load().fallback == nil {
+        return nil, fmt.Errorf("no TLS certificates configured")
+    }
+    return s, nil
+}
+
+// Reload re-reads all certificate pairs and rescans the directory
+// Pairs that fail to load keep serving their previous certificate
+// Returns the first load error encountered, if any
+// Time Complexity: O(c) where c is number of certificate pairs
+// Space Complexity: O(c) for reloaded certificates
+func (s *Store) Reload() error {
+    return s.refresh(true, false)
+}
+
+// Watch polls certificate files and the directory, reloading on change
+// Failed reloads are logged and retried on the next change
+// Blocks until the context is cancelled
+// Time Complexity: O(c) per poll where c is number of certificate pairs
+// Space Complexity: O(c) for reloaded certificates
+func (s *Store) Watch(ctx context.Context, interval time.Duration) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            if err := s.refresh(false, false); err != nil {
+                log.Printf("Certificate reload failed, keeping current certificate: %v", err)
+            }
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// GetCertificate selects a certificate for the client's SNI server name
+// Signature matches tls.Config.GetCertificate
+// Time Complexity: O(1) - map lookups
+// Space Complexity: O(1) - no allocations beyond name normalisation
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+    index := s.index.Load()
+
+    name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+    if name != "" {
+        if cert, ok := index.exact[name]; ok {
+            return cert, nil
+        }
+        // A wildcard covers exactly one label, so only the immediate parent is checked
+        if dot := strings.IndexByte(name, '.'); dot > 0 {
+            if cert, ok := index.wildcard[name[dot+1:]]; ok {
+                return cert, nil
+            }
+        }
+    }
+    return index.fallback, nil
+}
+
+// Certificates returns information about every served certificate
+// Ordered with the default certificate first, then configured and directory pairs
+// Time Complexity: O(c) where c is number of certificates
+// Space Complexity: O(c) for the returned slice
+func (s *Store) Certificates() []CertificateInfo {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+    return s.certificateInfos(s.pairs())
+}
+
+// refresh reconciles loaded pairs with configuration and rebuilds the index on change
+// force reloads every pair; otherwise only pairs whose files changed are reloaded
+// strict aborts on the first load error, used at startup
+// Time Complexity: O(c) where c is number of certificate pairs
+// Space Complexity: O(c) for the rebuilt index
+func (s *Store) refresh(force, strict bool) error {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    pairs := s.pairs()
+    reloaders := make(map[string]*Reloader, len(pairs))
+    changed := len(pairs) != len(s.reloaders)
+    var firstErr error
+
+    for _, pair := range pairs {
+        reloader, exists := s.reloaders[pair.CertFile]
+        switch {
+        case !exists:
+            var err error
+            if reloader, err = NewReloader(pair.CertFile, pair.KeyFile); err != nil {
+                if strict {
+                    return err
+                }
+                if firstErr == nil {
+                    firstErr = err
+                }
+                continue
+            }
+            changed = true
+        case force || reloader.changed():
+            if err := reloader.Reload(); err != nil {
+                if strict {
+                    return err
+                }
+                if firstErr == nil {
+                    firstErr = err
+                }
+            } else {
+                changed = true
+            }
+        }
+        reloaders[pair.CertFile] = reloader
+    }
+
+    s.reloaders = reloaders
+    if changed || s.index.Load() == nil {
+        s.index.Store(s.buildIndex(pairs))
+        if s.options.OnUpdate != nil {
+            s.options.OnUpdate(s.certificateInfos(pairs))
+        }
+    }
+    return firstErr
+}
+
+// pairs lists configured pairs in priority order: default, explicit, directory
+// Duplicate certificate files are listed once at their first position
+// Time Complexity: O(c + d) where d is number of directory entries
+// Space Complexity: O(c) for the returned slice
+func (s *Store) pairs() []Pair {
+    var pairs []Pair
+    if s.options.Default != nil {
+        pairs = append(pairs, *s.options.Default)
+    }
+    pairs = append(pairs, s.options.Pairs...)
+    pairs = append(pairs, scanDir(s.options.Dir)...)
+
+    seen := make(map[string]bool, len(pairs))
+    unique := pairs[:0]
+    for _, pair := range pairs {
+        if !seen[pair.CertFile] {
+            seen[pair.CertFile] = true
+            unique = append(unique, pair)
+        }
+    }
+    return unique
+}
+
+// scanDir finds <name>.crt files with a matching <name>.key in dir
+// An unreadable directory yields no pairs so a temporary error keeps current certificates
+// Time Complexity: O(d log d) where d is number of directory entries
+// Space Complexity: O(d) for the returned slice
+func scanDir(dir string) []Pair {
+    if dir == "" {
+        return nil
+    }
+    certFiles, err := filepath.Glob(filepath.Join(dir, "*.crt"))
+    if err != nil {
+        return nil
+    }
+    sort.Strings(certFiles)
+
+    var pairs []Pair
+    for _, certFile := range certFiles {
+        keyFile := strings.TrimSuffix(certFile, ".crt") + ".key"
+        if _, err := os.Stat(keyFile); err == nil {
+            pairs = append(pairs, Pair{CertFile: certFile, KeyFile: keyFile})
+        }
+    }
+    return pairs
+}
+
+// buildIndex creates the SNI lookup table from loaded pairs
+// Earlier pairs win when several certificates claim the same name
+// Time Complexity: O(n) where n is total DNS names across certificates
+// Space Complexity: O(n) for the lookup maps
+func (s *Store) buildIndex(pairs []Pair) *certIndex {
+    index := &certIndex{
+        exact:    make(map[string]*tls.Certificate),
+        wildcard: make(map[string]*tls.Certificate),
+    }
+
+    for _, pair := range pairs {
+        reloader, ok := s.reloaders[pair.CertFile]
+        if !ok {
+            continue
+        }
+        cert := reloader.Certificate()
+        if index.fallback == nil {
+            index.fallback = cert
+        }
+        for _, name := range certificateNames(cert) {
+            if parent, isWildcard := strings.CutPrefix(name, "*."); isWildcard {
+                if _, exists := index.wildcard[parent]; !exists {
+                    index.wildcard[parent] = cert
+                }
+                continue
+            }
+            if _, exists := index.exact[name]; !exists {
+                index.exact[name] = cert
+            }
+        }
+    }
+    return index
+}
+
+// certificateInfos describes loaded certificates in pair order
+// Time Complexity: O(c) where c is number of certificates
+// Space Complexity: O(c) for the returned slice
+func (s *Store) certificateInfos(pairs []Pair) []CertificateInfo {
+    infos := make([]CertificateInfo, 0, len(pairs))
+    for _, pair := range pairs {
+        reloader, ok := s.reloaders[pair.CertFile]
+        if !ok {
+            continue
+        }
+        cert := reloader.Certificate()
+        infos = append(infos, CertificateInfo{
+            File:       pair.CertFile,
+            CommonName: cert.Leaf.Subject.CommonName,
+            Names:      certificateNames(cert),
+            NotAfter:   cert.Leaf.NotAfter,
+        })
+    }
+    return infos
+}
+
+// certificateNames returns the lowercased names a certificate is valid for
+// Uses DNS SANs, falling back to the common name for legacy certificates
+// Time Complexity: O(n) where n is number of SAN entries
+// Space Complexity: O(n) for the returned slice
+func certificateNames(cert *tls.Certificate) []string {
+    names := cert.Leaf.DNSNames
+    if len(names) == 0 && cert.Leaf.Subject.CommonName != "" {
+        names = []string{cert.Leaf.Subject.CommonName}
+    }
+
+    lowered := make([]string, len(names))
+    for i, name := range names {
+        lowered[i] = strings.ToLower(name)
+    }
+    return lowered
+}
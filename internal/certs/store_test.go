@@ -0,0 +1,152 @@
+package certs
+
+import (
+	"context"
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serverName returns the common name of the certificate selected for sni
+func serverName(t *testing.T, store *Store, sni string) string {
+    t.Helper()
+    cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: sni})
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return leafName(t, cert.Certificate)
+}
+
+// TestStoreSNISelection verifies exact, wildcard and default selection
+func TestStoreSNISelection(t *testing.T) {
+    dir := t.TempDir()
+    expiry := time.Now().Add(time.Hour)
+    defaultCert, defaultKey := writeCertificate(t, dir, "default", expiry, "default.example.com")
+    apiCert, apiKey := writeCertificate(t, dir, "api", expiry, "api.example.com")
+    wildCert, wildKey := writeCertificate(t, dir, "wild", expiry, "*.example.com")
+
+    store, err := NewStore(Options{
+        Default: &Pair{CertFile: defaultCert, KeyFile: defaultKey},
+        Pairs: []Pair{
+            {CertFile: apiCert, KeyFile: apiKey},
+            {CertFile: wildCert, KeyFile: wildKey},
+        },
+    })
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    tests := []struct {
+        sni      string
+        expected string
+    }{
+        {"api.example.com", "api.example.com"},
+        {"API.Example.com.", "api.example.com"},
+        {"www.example.com", "*.example.com"},
+        {"deep.www.example.com", "default.example.com"},
+        {"example.com", "default.example.com"},
+        {"other.org", "default.example.com"},
+        {"", "default.example.com"},
+    }
+    for _, tt := range tests {
+        if name := serverName(t, store, tt.sni); name != tt.expected {
+            t.Errorf("SNI %q: expected %s, got %s", tt.sni, tt.expected, name)
+        }
+    }
+}
+
+// TestStoreDirectory verifies pairs are discovered from a directory
+// and the first certificate becomes the default without an explicit one
+func TestStoreDirectory(t *testing.T) {
+    dir := t.TempDir()
+    expiry := time.Now().Add(time.Hour)
+    writeCertificate(t, dir, "a", expiry, "a.example.com")
+    writeCertificate(t, dir, "b", expiry, "b.example.com")
+
+    // A certificate without a key must be ignored
+    orphanCert, orphanKey := writeCertificate(t, dir, "orphan", expiry, "orphan.example.com")
+    os.Remove(orphanKey)
+
+    var updates [][]CertificateInfo
+    store, err := NewStore(Options{
+        Dir:      dir,
+        OnUpdate: func(infos []CertificateInfo) { updates = append(updates, infos) },
+    })
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if name := serverName(t, store, "b.example.com"); name != "b.example.com" {
+        t.Errorf("Expected b.example.com, got %s", name)
+    }
+    if name := serverName(t, store, "orphan.example.com"); name != "a.example.com" {
+        t.Errorf("Expected default a.example.com for orphan, got %s", name)
+    }
+
+    infos := store.Certificates()
+    if len(infos) != 2 || infos[0].File == orphanCert {
+        t.Fatalf("Expected 2 certificates, got %+v", infos)
+    }
+    if infos[0].NotAfter.Sub(expiry).Abs() > time.Second {
+        t.Errorf("Expected expiry near %v, got %v", expiry, infos[0].NotAfter)
+    }
+    if len(updates) != 1 || len(updates[0]) != 2 {
+        t.Errorf("Expected one update with 2 certificates, got %v", updates)
+    }
+}
+
+// TestStoreEmpty verifies a store without certificates is rejected
+func TestStoreEmpty(t *testing.T) {
+    if _, err := NewStore(Options{Dir: t.TempDir()}); err == nil {
+        t.Error("Expected error for store without certificates")
+    }
+}
+
+// TestStoreWatch verifies new directory entries and rotated files are picked up
+func TestStoreWatch(t *testing.T) {
+    dir := t.TempDir()
+    expiry := time.Now().Add(time.Hour)
+    certFile, keyFile := writeCertificate(t, dir, "a", expiry, "a.example.com")
+
+    store, err := NewStore(Options{Dir: dir})
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    go store.Watch(ctx, 5*time.Millisecond)
+
+    // Add a new pair and rotate the existing one
+    writeCertificate(t, dir, "b", expiry, "b.example.com")
+    rotatedCert, rotatedKey := writeCertificate(t, t.TempDir(), "a", expiry, "a.example.com", "rotated.example.com")
+    copyFile(t, rotatedKey, keyFile)
+    copyFile(t, rotatedCert, certFile)
+
+    waitFor(t, func() bool {
+        return serverName(t, store, "b.example.com") == "b.example.com" &&
+            len(store.Certificates()[0].Names) == 2
+    })
+}
+
+// waitFor polls condition until it holds or a second elapses
+func waitFor(t *testing.T, condition func() bool) {
+    t.Helper()
+    deadline := time.Now().Add(time.Second)
+    for time.Now().Before(deadline) {
+        if condition() {
+            return
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+    t.Fatal("condition not met before deadline")
+}
+
+// TestScanDirSkipsUnreadable verifies a missing directory yields no pairs
+func TestScanDirSkipsUnreadable(t *testing.T) {
+    if pairs := scanDir(filepath.Join(t.TempDir(), "missing")); len(pairs) != 0 {
+        t.Errorf("Expected no pairs, got %v", pairs)
+    }
+}
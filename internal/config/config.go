@@ -1,13 +1,22 @@
 package config
 
 import (
+	"crypto/tls"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
+
+	"gopkg.in/yaml.v3"
 )
 
 var (
     instance *Config
     once     sync.Once
+    mutex    sync.RWMutex
 )
 
 // Config represents the complete proxy server configuration
@@ -18,7 +27,9 @@ type Config struct {
     Cache       CacheConfig       `yaml:"cache" json:"cache"`
     RateLimit   RateLimitConfig   `yaml:"rateLimit" json:"rateLimit"`
     LoadBalance LoadBalanceConfig `yaml:"loadBalance" json:"loadBalance"`
+    Routing     RoutingConfig     `yaml:"routing" json:"routing"`
     Health      HealthConfig      `yaml:"health" json:"health"`
+    WebSocket   WebSocketConfig   `yaml:"websocket" json:"websocket"`
     Tracing     TracingConfig     `yaml:"tracing" json:"tracing"`
 }
 
@@ -29,8 +40,104 @@ type ServerConfig struct {
     ReadTimeout  time.Duration `yaml:"readTimeout" json:"readTimeout" default:"30s"`
     WriteTimeout time.Duration `yaml:"writeTimeout" json:"writeTimeout" default:"30s"`
     IdleTimeout  time.Duration `yaml:"idleTimeout" json:"idleTimeout" default:"60s"`
+    H2C          bool          `yaml:"h2c" json:"h2c"` // Accept cleartext HTTP/2 with prior knowledge next to HTTP/1.1, without TLS only
     TLSCertFile  string        `yaml:"tlsCertFile" json:"tlsCertFile"`
     TLSKeyFile   string        `yaml:"tlsKeyFile" json:"tlsKeyFile"`
+
+    // Additional certificates selected by SNI; tlsCertFile is the default when set
+    TLSCertificates   []TLSCertificateConfig `yaml:"tlsCertificates" json:"tlsCertificates"`
+    TLSCertificateDir string                 `yaml:"tlsCertificateDir" json:"tlsCertificateDir"`
+
+    // TLS handshake policy, applied only when a certificate is configured
+    TLSMinVersion     string        `yaml:"tlsMinVersion" json:"tlsMinVersion" default:"1.2"`
+    TLSCipherSuites   []string      `yaml:"tlsCipherSuites" json:"tlsCipherSuites"`
+    TLSALPNProtocols  []string      `yaml:"tlsAlpnProtocols" json:"tlsAlpnProtocols" default:"h2,http/1.1"`
+    TLSReloadInterval time.Duration `yaml:"tlsReloadInterval" json:"tlsReloadInterval" default:"1m"`
+
+    // Client certificate authentication on the listener
+    TLSClientAuth   string `yaml:"tlsClientAuth" json:"tlsClientAuth" default:"none"`
+    TLSClientCAFile string `yaml:"tlsClientCAFile" json:"tlsClientCAFile"`
+}
+
+// TLSCertificateConfig identifies a certificate/key pair served by SNI
+// The pair is selected for every DNS name in the certificate's SANs
+type TLSCertificateConfig struct {
+    CertFile string `yaml:"certFile" json:"certFile"`
+    KeyFile  string `yaml:"keyFile" json:"keyFile"`
+}
+
+// TLSEnabled reports whether HTTPS should be served
+// Requires a default pair, an SNI certificate list or a certificate directory
+// Time Complexity: O(1) - string and length checks
+// Space Complexity: O(1) - no allocations
+func (s *ServerConfig) TLSEnabled() bool {
+    return (s.TLSCertFile != "" && s.TLSKeyFile != "") || len(s.TLSCertificates) > 0 || s.TLSCertificateDir != ""
+}
+
+// TLSMinVersionID converts TLSMinVersion ("1.0" to "1.3") to a crypto/tls constant
+// Time Complexity: O(1) - switch lookup
+// Space Complexity: O(1) - no allocations
+func (s *ServerConfig) TLSMinVersionID() (uint16, error) {
+    switch s.TLSMinVersion {
+    case "1.0":
+        return tls.VersionTLS10, nil
+    case "1.1":
+        return tls.VersionTLS11, nil
+    case "1.2", "":
+        return tls.VersionTLS12, nil
+    case "1.3":
+        return tls.VersionTLS13, nil
+    default:
+        return 0, fmt.Errorf("unsupported TLS version %q (supported: 1.0, 1.1, 1.2, 1.3)", s.TLSMinVersion)
+    }
+}
+
+// TLSClientAuthType converts TLSClientAuth to a crypto/tls client auth policy
+// Accepted values: none, request, require, verify-if-given, require-and-verify
+// Time Complexity: O(1) - switch lookup
+// Space Complexity: O(1) - no allocations
+func (s *ServerConfig) TLSClientAuthType() (tls.ClientAuthType, error) {
+    switch s.TLSClientAuth {
+    case "none", "":
+        return tls.NoClientCert, nil
+    case "request":
+        return tls.RequestClientCert, nil
+    case "require":
+        return tls.RequireAnyClientCert, nil
+    case "verify-if-given":
+        return tls.VerifyClientCertIfGiven, nil
+    case "require-and-verify":
+        return tls.RequireAndVerifyClientCert, nil
+    default:
+        return 0, fmt.Errorf("unsupported client auth %q (supported: none, request, require, verify-if-given, require-and-verify)", s.TLSClientAuth)
+    }
+}
+
+// TLSCipherSuiteIDs converts TLSCipherSuites names to crypto/tls identifiers
+// Names use the IANA form reported by tls.CipherSuiteName, e.g.
+// TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256; suites Go considers insecure are rejected
+// Returns nil when no suites are configured so Go's defaults apply
+// Time Complexity: O(c*s) where c is configured and s is supported suite count
+// Space Complexity: O(c) for the identifier slice
+func (s *ServerConfig) TLSCipherSuiteIDs() ([]uint16, error) {
+    if len(s.TLSCipherSuites) == 0 {
+        return nil, nil
+    }
+
+    supported := make(map[string]uint16)
+    for _, suite := range tls.CipherSuites() {
+        supported[suite.Name] = suite.ID
+    }
+
+    ids := make([]uint16, 0, len(s.TLSCipherSuites))
+    for _, name := range s.TLSCipherSuites {
+        id, ok := supported[name]
+        if !ok {
+            return nil, fmt.Errorf("unsupported or insecure cipher suite %q", name)
+        }
+        ids = append(ids, id)
+    }
+    return ids, nil
 }
 
 // CacheConfig defines caching middleware configuration
@@ -52,24 +159,456 @@ type RateLimitConfig struct {
 // BackendConfig represents individual backend server configuration
 // Includes URL and weight for load balancing algorithms
 type BackendConfig struct {
-    URL    string `yaml:"url" json:"url"`
-    Weight int    `yaml:"weight" json:"weight" default:"1"`
+    URL    string           `yaml:"url" json:"url"`
+    Weight int              `yaml:"weight" json:"weight" default:"1"`
+    TLS    BackendTLSConfig `yaml:"tls" json:"tls"`
+
+    HealthPath string `yaml:"healthPath" json:"healthPath"` // Overrides health.path for this backend
+}
+
+// BackendTLSConfig defines TLS settings for connecting to an HTTPS backend
+// Supports client certificates for backends that enforce mutual TLS
+// and private CA bundles for internally issued server certificates
+type BackendTLSConfig struct {
+    CertFile           string `yaml:"certFile" json:"certFile"`
+    KeyFile            string `yaml:"keyFile" json:"keyFile"`
+    CAFile             string `yaml:"caFile" json:"caFile"`
+    ServerName         string `yaml:"serverName" json:"serverName"`
+    InsecureSkipVerify bool   `yaml:"insecureSkipVerify" json:"insecureSkipVerify"`
 }
 
 // LoadBalanceConfig defines load balancing configuration
 // Specifies backend servers and balancing algorithm
+// Options carries settings of algorithms registered outside this package
 type LoadBalanceConfig struct {
     Algorithm string          `yaml:"algorithm" json:"algorithm" default:"round-robin"`
     Backends  []BackendConfig `yaml:"backends" json:"backends"`
+    Transport TransportConfig `yaml:"transport" json:"transport"`
+    Retry     RetryConfig     `yaml:"retry" json:"retry"`
+
+    CircuitBreaker   CircuitBreakerConfig   `yaml:"circuitBreaker" json:"circuitBreaker"`
+    OutlierDetection OutlierDetectionConfig `yaml:"outlierDetection" json:"outlierDetection"`
+    Hash             HashConfig             `yaml:"hash" json:"hash"`
+    Sticky           StickyConfig           `yaml:"sticky" json:"sticky"`
+    P2C              P2CConfig              `yaml:"p2c" json:"p2c"`
+    SlowStart        SlowStartConfig        `yaml:"slowStart" json:"slowStart"`
+    Mirror           MirrorConfig           `yaml:"mirror" json:"mirror"`
+    Options          AlgorithmOptions       `yaml:"options" json:"options"`
+}
+
+// AlgorithmOptions holds raw options blocks keyed by algorithm name
+// Each registered algorithm decodes and validates its own block, so adding an
+// algorithm with typed settings needs no change to the configuration types
+type AlgorithmOptions map[string]yaml.Node
+
+// For returns the options block of algorithm, matched ignoring case, or nil
+// Time Complexity: O(a) where a is number of options blocks
+// Space Complexity: O(1) - no allocations
+func (o AlgorithmOptions) For(algorithm string) *yaml.Node {
+    for name, node := range o {
+        if strings.EqualFold(name, algorithm) {
+            return &node
+        }
+    }
+    return nil
+}
+
+// TransportConfig tunes the connection pool shared by all backends of a pool
+// Zero durations disable the corresponding timeout
+type TransportConfig struct {
+    MaxIdleConns          int           `yaml:"maxIdleConns" json:"maxIdleConns" default:"100"`
+    MaxIdleConnsPerHost   int           `yaml:"maxIdleConnsPerHost" json:"maxIdleConnsPerHost" default:"32"`
+    MaxConnsPerHost       int           `yaml:"maxConnsPerHost" json:"maxConnsPerHost"` // 0 means unlimited
+    IdleConnTimeout       time.Duration `yaml:"idleConnTimeout" json:"idleConnTimeout" default:"90s"`
+    DialTimeout           time.Duration `yaml:"dialTimeout" json:"dialTimeout" default:"10s"`
+    KeepAlive             time.Duration `yaml:"keepAlive" json:"keepAlive" default:"30s"`
+    TLSHandshakeTimeout   time.Duration `yaml:"tlsHandshakeTimeout" json:"tlsHandshakeTimeout" default:"10s"`
+    ResponseHeaderTimeout time.Duration `yaml:"responseHeaderTimeout" json:"responseHeaderTimeout"`
+    ExpectContinueTimeout time.Duration `yaml:"expectContinueTimeout" json:"expectContinueTimeout" default:"1s"`
+    HTTP2                 bool          `yaml:"http2" json:"http2" default:"true"` // Negotiate HTTP/2 with TLS backends
+    H2C                   bool          `yaml:"h2c" json:"h2c"`                    // Speak cleartext HTTP/2 to http:// backends, requires http2
+}
+
+// DefaultTransportConfig returns the transport settings applied when none are configured
+func DefaultTransportConfig() TransportConfig {
+    return defaults[TransportConfig]()
+}
+
+// RetryConfig controls retrying failed requests on another backend of the pool
+// Only idempotent methods, or requests carrying an Idempotency-Key header, are retried
+// unless RetryNonIdempotent is set; requests with a body additionally need it to fit
+// in MaxBodyBytes so it can be replayed
+type RetryConfig struct {
+    Enabled            bool          `yaml:"enabled" json:"enabled" default:"false"`
+    MaxAttempts        int           `yaml:"maxAttempts" json:"maxAttempts" default:"3"`       // Total attempts including the first
+    InitialBackoff     time.Duration `yaml:"initialBackoff" json:"initialBackoff" default:"25ms"` // Doubled after every retry
+    MaxBackoff         time.Duration `yaml:"maxBackoff" json:"maxBackoff" default:"1s"`
+    Budget             time.Duration `yaml:"budget" json:"budget" default:"5s"` // No retry starts after this much time, 0 means no limit
+    RetryOn            []int         `yaml:"retryOn" json:"retryOn" default:"502,503,504"` // Backend status codes that are retried
+    RetryNonIdempotent bool          `yaml:"retryNonIdempotent" json:"retryNonIdempotent"`
+    MaxBodyBytes       int64         `yaml:"maxBodyBytes" json:"maxBodyBytes"` // Request bodies up to this size are buffered for replay, 0 disables
+}
+
+// DefaultRetryConfig returns the retry settings applied when none are configured
+func DefaultRetryConfig() RetryConfig {
+    return defaults[RetryConfig]()
+}
+
+// CircuitBreakerConfig controls the per-backend circuit breakers of a pool
+// A breaker opens after ConsecutiveFailures failures in a row, or when the failure
+// rate over Window reaches ErrorRate with at least MinRequests requests; a zero
+// value disables that trigger. Open backends leave rotation for OpenDuration, then
+// HalfOpenProbes concurrent requests probe them and as many successes close the breaker
+type CircuitBreakerConfig struct {
+    Enabled             bool          `yaml:"enabled" json:"enabled" default:"false"`
+    ConsecutiveFailures int           `yaml:"consecutiveFailures" json:"consecutiveFailures" default:"5"`
+    ErrorRate           float64       `yaml:"errorRate" json:"errorRate" default:"0.5"`
+    MinRequests         int           `yaml:"minRequests" json:"minRequests" default:"20"`
+    Window              time.Duration `yaml:"window" json:"window" default:"10s"`
+    OpenDuration        time.Duration `yaml:"openDuration" json:"openDuration" default:"30s"`
+    HalfOpenProbes      int           `yaml:"halfOpenProbes" json:"halfOpenProbes" default:"1"`
+}
+
+// DefaultCircuitBreakerConfig returns the breaker settings applied when none are configured
+func DefaultCircuitBreakerConfig() CircuitBreakerConfig {
+    return defaults[CircuitBreakerConfig]()
+}
+
+// DefaultPoolName names the pool built from the loadBalance section
+// Routes reference it like any other pool
+const DefaultPoolName = "default"
+
+// RoutingConfig defines the routing table and the named pools it dispatches to
+// Routes are evaluated by descending priority, ties in configuration order
+// Requests matching no route go to DefaultPool, or receive 404 when it is empty
+// DefaultPool defaults to the loadBalance pool, or to empty when loadBalance has no backends
+type RoutingConfig struct {
+    Pools       []PoolConfig  `yaml:"pools" json:"pools"`
+    Splits      []SplitConfig `yaml:"splits" json:"splits"`
+    Routes      []RouteConfig `yaml:"routes" json:"routes"`
+    DefaultPool string        `yaml:"defaultPool" json:"defaultPool" default:"default"`
+}
+
+// SplitConfig divides traffic between a stable and a canary pool
+// Routes and defaultPool name a split like a pool. Requests matching any of
+// Headers or Cookies go to Canary, as does Percent of the remaining traffic.
+// Cookie conditions use the same name/value/regex fields as header conditions
+type SplitConfig struct {
+    Name    string              `yaml:"name" json:"name"`
+    Stable  string              `yaml:"stable" json:"stable"`
+    Canary  string              `yaml:"canary" json:"canary"`
+    Percent float64             `yaml:"percent" json:"percent"`
+    Headers []HeaderMatchConfig `yaml:"headers" json:"headers"`
+    Cookies []HeaderMatchConfig `yaml:"cookies" json:"cookies"`
+    Sticky  SplitStickyConfig   `yaml:"sticky" json:"sticky"`
+}
+
+// SplitStickyConfig keeps a client on the side of a split it was first sent to
+// A pin is ignored once its side no longer receives traffic, so setting the
+// percent to 0 or 100 moves every client
+type SplitStickyConfig struct {
+    Enabled  bool          `yaml:"enabled" json:"enabled"`
+    Cookie   string        `yaml:"cookie" json:"cookie" default:"proxy_variant"`
+    TTL      time.Duration `yaml:"ttl" json:"ttl"` // Cookie lifetime, 0 for a session cookie
+    Secure   bool          `yaml:"secure" json:"secure"`
+    HTTPOnly bool          `yaml:"httpOnly" json:"httpOnly" default:"true"`
+}
+
+// OutlierDetectionConfig controls passive health checking of a pool from live traffic
+// A backend is ejected right away after Consecutive5xx 5xx responses or
+// ConsecutiveConnectErrors transport failures in a row. Every Interval, backends
+// with at least MinRequests requests are compared once MinHosts qualify: those whose
+// error rate exceeds the pool median by ErrorRateDeviation, or whose mean latency
+// exceeds LatencyDeviation times the pool median, are ejected. A zero value
+// disables that check. Ejections last BaseEjectionTime times the number of recent
+// ejections, up to MaxEjectionTime, and never exceed MaxEjectionPercent of the pool
+type OutlierDetectionConfig struct {
+    Enabled                  bool          `yaml:"enabled" json:"enabled" default:"false"`
+    Interval                 time.Duration `yaml:"interval" json:"interval" default:"10s"`
+    Consecutive5xx           int           `yaml:"consecutive5xx" json:"consecutive5xx" default:"5"`
+    ConsecutiveConnectErrors int           `yaml:"consecutiveConnectErrors" json:"consecutiveConnectErrors" default:"5"`
+    ErrorRateDeviation       float64       `yaml:"errorRateDeviation" json:"errorRateDeviation" default:"0.3"`
+    LatencyDeviation         float64       `yaml:"latencyDeviation" json:"latencyDeviation" default:"3"`
+    MinRequests              int           `yaml:"minRequests" json:"minRequests" default:"10"`
+    MinHosts                 int           `yaml:"minHosts" json:"minHosts" default:"3"`
+    BaseEjectionTime         time.Duration `yaml:"baseEjectionTime" json:"baseEjectionTime" default:"30s"`
+    MaxEjectionTime          time.Duration `yaml:"maxEjectionTime" json:"maxEjectionTime" default:"5m"`
+    MaxEjectionPercent       int           `yaml:"maxEjectionPercent" json:"maxEjectionPercent" default:"10"`
+}
+
+// DefaultOutlierDetectionConfig returns the outlier detection settings applied when none are configured
+func DefaultOutlierDetectionConfig() OutlierDetectionConfig {
+    return defaults[OutlierDetectionConfig]()
+}
+
+// HashConfig selects the request key of the consistent-hash algorithm
+// Key is one of client-ip, header, cookie or path. Header and cookie keys read
+// the value named by Name; path keys hash the Segment-th path segment, or the
+// whole path when Segment is 0. Requests lacking the key hash their client IP
+type HashConfig struct {
+    Key          string `yaml:"key" json:"key" default:"client-ip"`
+    Name         string `yaml:"name" json:"name"`
+    Segment      int    `yaml:"segment" json:"segment"`
+    VirtualNodes int    `yaml:"virtualNodes" json:"virtualNodes" default:"160"` // Ring points per unit of backend weight
+}
+
+// Algorithm names whose settings live in their own configuration blocks
+const (
+    ConsistentHashAlgorithm = "consistent-hash" // Uses HashConfig
+    P2CEWMAAlgorithm        = "p2c-ewma"        // Uses P2CConfig
+)
+
+// Consistent hash keys
+const (
+    HashKeyClientIP = "client-ip"
+    HashKeyHeader   = "header"
+    HashKeyCookie   = "cookie"
+    HashKeyPath     = "path"
+)
+
+// DefaultHashConfig returns the consistent-hash settings applied when none are configured
+func DefaultHashConfig() HashConfig {
+    return defaults[HashConfig]()
+}
+
+// P2CConfig tunes the p2c-ewma algorithm
+// Decay is the time constant of the latency moving average: older response
+// times lose weight exponentially, so a backend's score follows recent behaviour.
+// A failed request counts as at least FailurePenalty, so a backend that fails
+// fast never looks faster than the backends that answer
+type P2CConfig struct {
+    Decay          time.Duration `yaml:"decay" json:"decay" default:"10s"`
+    FailurePenalty time.Duration `yaml:"failurePenalty" json:"failurePenalty" default:"1s"`
+}
+
+// DefaultP2CConfig returns the p2c-ewma settings applied when none are configured
+func DefaultP2CConfig() P2CConfig {
+    return defaults[P2CConfig]()
+}
+
+// SlowStartConfig ramps up traffic to backends returning to rotation
+// A backend that recovers, returns from ejection or is added receives MinWeight
+// of its share at first, growing to its full share over Window. Aggression
+// shapes the curve: 1 is linear, higher values ramp up faster at the start
+type SlowStartConfig struct {
+    Enabled    bool          `yaml:"enabled" json:"enabled"`
+    Window     time.Duration `yaml:"window" json:"window" default:"30s"`
+    MinWeight  float64       `yaml:"minWeight" json:"minWeight" default:"0.1"`
+    Aggression float64       `yaml:"aggression" json:"aggression" default:"1"`
+}
+
+// DefaultSlowStartConfig returns the slow start settings applied when none are configured
+func DefaultSlowStartConfig() SlowStartConfig {
+    return defaults[SlowStartConfig]()
+}
+
+// MirrorConfig copies a sample of a pool's requests to a shadow backend
+// Percent of requests are sent to URL in the background, tagged with Header set
+// to the pool name. Shadow responses are discarded and only compared with the
+// pool's responses in metrics, so the shadow never affects clients. Requests with
+// bodies over MaxBodyBytes are not mirrored, and samples arriving while
+// MaxInFlight mirrored requests are outstanding are dropped
+type MirrorConfig struct {
+    Enabled      bool             `yaml:"enabled" json:"enabled"`
+    URL          string           `yaml:"url" json:"url"`
+    TLS          BackendTLSConfig `yaml:"tls" json:"tls"`
+    Percent      float64          `yaml:"percent" json:"percent" default:"100"`
+    MaxBodyBytes int64            `yaml:"maxBodyBytes" json:"maxBodyBytes" default:"65536"` // 0 mirrors only requests without a body
+    Header       string           `yaml:"header" json:"header" default:"X-Mirrored-From"`
+    Timeout      time.Duration    `yaml:"timeout" json:"timeout" default:"10s"`
+    MaxInFlight  int              `yaml:"maxInFlight" json:"maxInFlight" default:"100"`
+}
+
+// DefaultMirrorConfig returns the mirroring settings applied when none are configured
+func DefaultMirrorConfig() MirrorConfig {
+    return defaults[MirrorConfig]()
+}
+
+// StickyConfig controls cookie-based sticky sessions of a pool
+// The proxy sets Cookie on the first response and sends later requests carrying
+// it to the same backend while that backend is available; TTL 0 makes it a session cookie
+type StickyConfig struct {
+    Enabled  bool          `yaml:"enabled" json:"enabled" default:"false"`
+    Cookie   string        `yaml:"cookie" json:"cookie" default:"proxy_backend"`
+    TTL      time.Duration `yaml:"ttl" json:"ttl"`
+    Secure   bool          `yaml:"secure" json:"secure"`
+    HTTPOnly bool          `yaml:"httpOnly" json:"httpOnly" default:"true"`
+}
+
+// DefaultStickyConfig returns the sticky session settings applied when none are configured
+func DefaultStickyConfig() StickyConfig {
+    return defaults[StickyConfig]()
+}
+
+// PoolConfig defines a named upstream pool with its own algorithm and backends
+type PoolConfig struct {
+    Name      string          `yaml:"name" json:"name"`
+    Algorithm string          `yaml:"algorithm" json:"algorithm" default:"round-robin"`
+    Backends  []BackendConfig `yaml:"backends" json:"backends"`
+    Transport TransportConfig `yaml:"transport" json:"transport"`
+    Retry     RetryConfig     `yaml:"retry" json:"retry"`
+
+    CircuitBreaker   CircuitBreakerConfig   `yaml:"circuitBreaker" json:"circuitBreaker"`
+    OutlierDetection OutlierDetectionConfig `yaml:"outlierDetection" json:"outlierDetection"`
+    Hash             HashConfig             `yaml:"hash" json:"hash"`
+    Sticky           StickyConfig           `yaml:"sticky" json:"sticky"`
+    P2C              P2CConfig              `yaml:"p2c" json:"p2c"`
+    SlowStart        SlowStartConfig        `yaml:"slowStart" json:"slowStart"`
+    Mirror           MirrorConfig           `yaml:"mirror" json:"mirror"`
+    Options          AlgorithmOptions       `yaml:"options" json:"options"`
+}
+
+// RouteConfig matches requests and names the pool that serves them
+// Every configured condition must match; omitted conditions match anything
+// Host accepts an exact name or a "*.example.com" wildcard for any subdomain
+type RouteConfig struct {
+    Name       string              `yaml:"name" json:"name"`
+    Priority   int                 `yaml:"priority" json:"priority"`
+    Host       string              `yaml:"host" json:"host"`
+    PathPrefix string              `yaml:"pathPrefix" json:"pathPrefix"`
+    PathRegex  string              `yaml:"pathRegex" json:"pathRegex"`
+    Methods    []string            `yaml:"methods" json:"methods"`
+    Headers    []HeaderMatchConfig `yaml:"headers" json:"headers"`
+    GRPC       GRPCMatchConfig     `yaml:"grpc" json:"grpc"`
+    Pool       string              `yaml:"pool" json:"pool"`
+    Rewrite    RewriteConfig       `yaml:"rewrite" json:"rewrite"`
+    Redirect   RedirectConfig      `yaml:"redirect" json:"redirect"`
+}
+
+// GRPCMatchConfig matches gRPC calls, which are POSTed to /package.Service/Method
+// Service is the fully qualified service name; an empty Method matches every
+// method of the service. Requests that are not gRPC never match
+type GRPCMatchConfig struct {
+    Service string `yaml:"service" json:"service"`
+    Method  string `yaml:"method" json:"method"`
+}
+
+// Enabled reports whether the route matches gRPC calls
+func (g *GRPCMatchConfig) Enabled() bool {
+    return g.Service != ""
+}
+
+// RewriteConfig modifies a matched request before it is forwarded to the pool
+// Path, Host and query values may reference pathRegex capture groups as $1 or ${name}
+type RewriteConfig struct {
+    StripPrefix string            `yaml:"stripPrefix" json:"stripPrefix"` // Removed from the start of the path
+    AddPrefix   string            `yaml:"addPrefix" json:"addPrefix"`     // Prepended after StripPrefix, together replacing a prefix
+    Path        string            `yaml:"path" json:"path"`               // Replaces the whole path, exclusive with the prefix options
+    Host        string            `yaml:"host" json:"host"`               // Host header sent to the backend
+    AddQuery    map[string]string `yaml:"addQuery" json:"addQuery"`       // Query parameters set on the request
+    RemoveQuery []string          `yaml:"removeQuery" json:"removeQuery"` // Query parameters removed from the request
+}
+
+// IsZero reports whether no rewrite is configured
+func (r *RewriteConfig) IsZero() bool {
+    return r.StripPrefix == "" && r.AddPrefix == "" && r.Path == "" && r.Host == "" &&
+        len(r.AddQuery) == 0 && len(r.RemoveQuery) == 0
+}
+
+// RedirectConfig answers a matched request with a redirect instead of proxying it
+// URL may reference pathRegex capture groups; the request query is kept unless URL has its own
+// HTTPS redirects plain HTTP requests to the same host and path over HTTPS
+type RedirectConfig struct {
+    URL       string `yaml:"url" json:"url"`
+    HTTPS     bool   `yaml:"https" json:"https"`
+    HTTPSPort int    `yaml:"httpsPort" json:"httpsPort"` // Port of the HTTPS listener, omitted from the URL when 0 or 443
+    Status    int    `yaml:"status" json:"status"`       // 301, 302, 307 or 308; 0 means 302
+}
+
+// Enabled reports whether the route redirects instead of proxying
+func (r *RedirectConfig) Enabled() bool {
+    return r.URL != "" || r.HTTPS
+}
+
+// HeaderMatchConfig matches a request header by exact value or regular expression
+// With neither Value nor Regex set the header only has to be present
+type HeaderMatchConfig struct {
+    Name  string `yaml:"name" json:"name"`
+    Value string `yaml:"value" json:"value"`
+    Regex string `yaml:"regex" json:"regex"`
 }
 
 // HealthConfig defines health check configuration
-// Controls health monitoring of backend servers
+// Controls active health monitoring of backend servers
+// Type selects an HTTP request, a plain TCP connect or a grpc.health.v1 Check.
+// A backend is marked down after Fall failed checks in a row and up again after
+// Rise successful ones. Each interval is randomised by up to Jitter of its length
+// so a fleet of proxies does not probe in lockstep
 type HealthConfig struct {
     Enabled  bool          `yaml:"enabled" json:"enabled" default:"true"`
+    Type     string        `yaml:"type" json:"type" default:"http"`
     Interval time.Duration `yaml:"interval" json:"interval" default:"30s"`
+    Jitter   float64       `yaml:"jitter" json:"jitter" default:"0.1"`
     Timeout  time.Duration `yaml:"timeout" json:"timeout" default:"5s"`
+    Rise     int           `yaml:"rise" json:"rise" default:"2"`
+    Fall     int           `yaml:"fall" json:"fall" default:"3"`
     Path     string        `yaml:"path" json:"path" default:"/health"`
+
+    // HTTP and gRPC checks
+    Host    string            `yaml:"host" json:"host"`       // Host header or gRPC authority, backend host when empty
+    Headers map[string]string `yaml:"headers" json:"headers"` // Extra request headers or gRPC metadata
+
+    // HTTP checks
+    ExpectedStatus []string `yaml:"expectedStatus" json:"expectedStatus" default:"200-299"` // Codes or ranges such as "200-299"
+    BodyContains   string   `yaml:"bodyContains" json:"bodyContains"`
+    BodyRegex      string   `yaml:"bodyRegex" json:"bodyRegex"`
+
+    // gRPC checks
+    GRPCService string `yaml:"grpcService" json:"grpcService"` // Service name to check, empty checks the whole server
+}
+
+// Health check types
+const (
+    HealthCheckHTTP = "http"
+    HealthCheckTCP  = "tcp"
+    HealthCheckGRPC = "grpc"
+)
+
+// StatusRange is an inclusive range of HTTP status codes
+type StatusRange struct {
+    Min int
+    Max int
+}
+
+// Contains reports whether code lies within the range
+func (r StatusRange) Contains(code int) bool {
+    return code >= r.Min && code <= r.Max
+}
+
+// ParseStatusRanges parses status codes and ranges such as "200-299" or "301"
+// Time Complexity: O(n) where n is number of entries
+// Space Complexity: O(n) for the parsed ranges
+func ParseStatusRanges(specs []string) ([]StatusRange, error) {
+    ranges := make([]StatusRange, 0, len(specs))
+    for _, spec := range specs {
+        low, high, isRange := strings.Cut(strings.TrimSpace(spec), "-")
+        min, err := strconv.Atoi(strings.TrimSpace(low))
+        if err != nil {
+            return nil, fmt.Errorf("invalid status %q", spec)
+        }
+        max := min
+        if isRange {
+            if max, err = strconv.Atoi(strings.TrimSpace(high)); err != nil {
+                return nil, fmt.Errorf("invalid status %q", spec)
+            }
+        }
+        if min < 100 || max > 599 || min > max {
+            return nil, fmt.Errorf("invalid status range %q", spec)
+        }
+        ranges = append(ranges, StatusRange{Min: min, Max: max})
+    }
+    return ranges, nil
+}
+
+// WebSocketConfig limits connections upgraded through the proxy, such as WebSockets
+// An upgraded connection is closed after IdleTimeout without traffic in either
+// direction, or once it has been open for MaxLifetime; 0 disables either limit.
+// WebSocket clients are sent a close frame first, on shutdown too, and the
+// connection is closed CloseTimeout later if the closing handshake has not ended it
+type WebSocketConfig struct {
+    IdleTimeout  time.Duration `yaml:"idleTimeout" json:"idleTimeout" default:"5m"`
+    MaxLifetime  time.Duration `yaml:"maxLifetime" json:"maxLifetime"`
+    CloseTimeout time.Duration `yaml:"closeTimeout" json:"closeTimeout" default:"5s"`
 }
 
 // TracingConfig defines OpenTelemetry tracing configuration
@@ -85,43 +624,12 @@ type TracingConfig struct {
 }
 
 // DefaultConfig returns configuration with sensible defaults
-// Provides baseline configuration for development and testing
+// Provides baseline configuration for development and testing; the values
+// come from the default tags, so they match a file that omits every setting
 func DefaultConfig() *Config {
-    return &Config{
-        Server: ServerConfig{
-            Port:         8080,
-            ReadTimeout:  30 * time.Second,
-            WriteTimeout: 30 * time.Second,
-            IdleTimeout:  60 * time.Second,
-        },
-        Cache: CacheConfig{
-            Enabled: true,
-            MaxSize: 1000,
-            TTL:     5 * time.Minute,
-        },
-        RateLimit: RateLimitConfig{
-            Enabled:    true,
-            Capacity:   100,
-            RefillRate: 10,
-        },
-        LoadBalance: LoadBalanceConfig{
-            Algorithm: "round-robin",
-            Backends:  []BackendConfig{},
-        },
-        Health: HealthConfig{
-            Enabled:  true,
-            Interval: 30 * time.Second,
-            Timeout:  5 * time.Second,
-            Path:     "/health",
-        },
-        Tracing: TracingConfig{
-            Enabled:        false,
-            ServiceName:    "proxy",
-            ServiceVersion: "1.0.0",
-            Environment:    "development",
-            SamplingRatio:  0.1,
-        },
-    }
+    cfg := defaults[Config]()
+    cfg.LoadBalance.Backends = []BackendConfig{}
+    return &cfg
 }
 
 // GetInstance returns the singleton config instance
@@ -132,6 +640,9 @@ func GetInstance() *Config {
     once.Do(func() {
         instance = DefaultConfig()
     })
+
+    mutex.RLock()
+    defer mutex.RUnlock()
     return instance
 }
 
@@ -140,24 +651,77 @@ func GetInstance() *Config {
 // Time Complexity: O(n) where n is config file size
 // Space Complexity: O(n) for parsing configuration
 func LoadConfig(path string) error {
-    cfg, err := loadFromFile(path)
+    cfg, err := Load(path)
     if err != nil {
         return err
     }
 
     // Update singleton instance
-    once.Do(func() {
-        instance = cfg
-    })
+    SetInstance(cfg)
     return nil
 }
 
+// SetInstance replaces the singleton configuration
+// Used after a hot reload has been validated and applied by the server
+// Time Complexity: O(1) - pointer swap under lock
+// Space Complexity: O(1) - no additional allocations
+func SetInstance(cfg *Config) {
+    once.Do(func() {})
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    instance = cfg
+}
+
+// Load reads configuration from file without updating the singleton
+// Used by tooling such as config validation that must not affect global state
+// Time Complexity: O(n) where n is config file size
+// Space Complexity: O(n) for parsing configuration
+func Load(path string) (*Config, error) {
+    return loadFromFile(path)
+}
+
 // loadFromFile reads configuration from YAML file
 // Supports environment variable interpolation
+// JSON files are parsed by the same decoder since JSON is a subset of YAML
+// Fields omitted from the file take the value of their default struct tag
 // Time Complexity: O(n) where n is file size
 // Space Complexity: O(n) for file content
 func loadFromFile(path string) (*Config, error) {
-    // TODO: Implement YAML file loading
-    // This is just a placeholder - you'll need to add actual file loading logic
-    return DefaultConfig(), nil
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("failed to read config file %s: %w", path, err)
+    }
+
+    // Decode into a node tree first so omitted keys can be detected
+    // This distinguishes "enabled: false" from a missing "enabled" key
+    var root yaml.Node
+    if err := yaml.Unmarshal(data, &root); err != nil {
+        return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+    }
+
+    // Expand ${VAR} and ${VAR:-fallback} references in values only, so
+    // interpolated text can never change the structure of the document
+    expandEnv(&root)
+
+    cfg := &Config{}
+    var document *yaml.Node
+    if len(root.Content) > 0 {
+        document = root.Content[0]
+        if err := document.Decode(cfg); err != nil {
+            return nil, fmt.Errorf("failed to decode config file %s: %w", path, err)
+        }
+    }
+
+    if err := applyDefaults(reflect.ValueOf(cfg).Elem(), document); err != nil {
+        return nil, fmt.Errorf("failed to apply config defaults: %w", err)
+    }
+
+    // An omitted defaultPool names the loadBalance pool only when that pool exists;
+    // a configuration made of routing pools alone answers unmatched requests with 404
+    if len(cfg.LoadBalance.Backends) == 0 && lookupKey(lookupKey(document, "routing"), "defaultPool") == nil {
+        cfg.Routing.DefaultPool = ""
+    }
+
+    return cfg, nil
 }
\ No newline at end of file
@@ -0,0 +1,249 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// writeConfigFile writes config content to a temporary file with given name
+// Returns the file path for use with loadFromFile
+func writeConfigFile(t *testing.T, name, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatalf("failed to write config file: %v", err)
+    }
+    return path
+}
+
+// TestLoadFromFileYAML verifies YAML values override defaults
+// Ensures durations, nested sections and backend lists are decoded
+func TestLoadFromFileYAML(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", `
+server:
+  port: 9090
+  readTimeout: 10s
+cache:
+  enabled: false
+loadBalance:
+  algorithm: weighted-round-robin
+  backends:
+    - url: "http://a.internal"
+      weight: 3
+    - url: "http://b.internal"
+`)
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if cfg.Server.Port != 9090 {
+        t.Errorf("Expected port 9090, got %d", cfg.Server.Port)
+    }
+    if cfg.Server.ReadTimeout != 10*time.Second {
+        t.Errorf("Expected read timeout 10s, got %v", cfg.Server.ReadTimeout)
+    }
+    if cfg.Cache.Enabled {
+        t.Error("Expected explicit false to override enabled default")
+    }
+    if len(cfg.LoadBalance.Backends) != 2 {
+        t.Fatalf("Expected 2 backends, got %d", len(cfg.LoadBalance.Backends))
+    }
+    if cfg.LoadBalance.Backends[0].Weight != 3 {
+        t.Errorf("Expected first backend weight 3, got %d", cfg.LoadBalance.Backends[0].Weight)
+    }
+}
+
+// TestLoadFromFileDefaults verifies omitted fields take their default tag
+// Covers top-level sections, nested fields and slice elements
+func TestLoadFromFileDefaults(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", `
+server:
+  port: 9090
+loadBalance:
+  backends:
+    - url: "http://a.internal"
+`)
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if cfg.Server.WriteTimeout != 30*time.Second {
+        t.Errorf("Expected default write timeout 30s, got %v", cfg.Server.WriteTimeout)
+    }
+    if !cfg.Cache.Enabled || cfg.Cache.TTL != 5*time.Minute {
+        t.Errorf("Expected default cache settings, got %+v", cfg.Cache)
+    }
+    if cfg.LoadBalance.Algorithm != "round-robin" {
+        t.Errorf("Expected default algorithm, got %q", cfg.LoadBalance.Algorithm)
+    }
+    if cfg.LoadBalance.Backends[0].Weight != 1 {
+        t.Errorf("Expected default backend weight 1, got %d", cfg.LoadBalance.Backends[0].Weight)
+    }
+    if retryOn := cfg.LoadBalance.Retry.RetryOn; !reflect.DeepEqual(retryOn, []int{502, 503, 504}) {
+        t.Errorf("Expected default retry status codes, got %v", retryOn)
+    }
+    if cfg.Tracing.SamplingRatio != 0.1 {
+        t.Errorf("Expected default sampling ratio 0.1, got %v", cfg.Tracing.SamplingRatio)
+    }
+}
+
+// TestLoadFromFileEmpty verifies an empty file yields the default configuration
+func TestLoadFromFileEmpty(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", "")
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    defaults := DefaultConfig()
+    if !reflect.DeepEqual(cfg.Server, defaults.Server) || !reflect.DeepEqual(cfg.Health, defaults.Health) || cfg.Tracing != defaults.Tracing {
+        t.Errorf("Expected defaults, got %+v", cfg)
+    }
+}
+
+// TestLoadFromFileJSON verifies JSON configuration files are accepted
+func TestLoadFromFileJSON(t *testing.T) {
+    path := writeConfigFile(t, "config.json", `{
+  "server": {"port": 7070, "idleTimeout": "2m"},
+  "loadBalance": {"backends": [{"url": "http://a.internal", "weight": 2}]}
+}`)
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if cfg.Server.Port != 7070 || cfg.Server.IdleTimeout != 2*time.Minute {
+        t.Errorf("Unexpected server config: %+v", cfg.Server)
+    }
+    if cfg.LoadBalance.Backends[0].Weight != 2 {
+        t.Errorf("Expected backend weight 2, got %d", cfg.LoadBalance.Backends[0].Weight)
+    }
+}
+
+// TestLoadFromFileEnvInterpolation verifies ${VAR} and ${VAR:-fallback} expansion
+func TestLoadFromFileEnvInterpolation(t *testing.T) {
+    t.Setenv("PROXY_TEST_BACKEND", "http://from-env.internal")
+    t.Setenv("PROXY_TEST_EMPTY", "")
+
+    path := writeConfigFile(t, "config.yaml", `
+server:
+  port: ${PROXY_TEST_PORT:-8181}
+  tlsKeyFile: "${PROXY_TEST_EMPTY:-/etc/key.pem}"
+loadBalance:
+  backends:
+    - url: "${PROXY_TEST_BACKEND}"
+    - url: "http://${PROXY_TEST_UNSET}fixed.internal"
+`)
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if cfg.Server.Port != 8181 {
+        t.Errorf("Expected fallback port 8181, got %d", cfg.Server.Port)
+    }
+    if cfg.Server.TLSKeyFile != "/etc/key.pem" {
+        t.Errorf("Expected fallback for empty variable, got %q", cfg.Server.TLSKeyFile)
+    }
+    if cfg.LoadBalance.Backends[0].URL != "http://from-env.internal" {
+        t.Errorf("Expected backend URL from env, got %q", cfg.LoadBalance.Backends[0].URL)
+    }
+    if cfg.LoadBalance.Backends[1].URL != "http://fixed.internal" {
+        t.Errorf("Expected unset variable to expand empty, got %q", cfg.LoadBalance.Backends[1].URL)
+    }
+}
+
+// TestLoadFromFileEnvStructure verifies expanded values cannot change the
+// structure of the document and comments are never expanded
+func TestLoadFromFileEnvStructure(t *testing.T) {
+    t.Setenv("PROXY_TEST_PATH", "/health?probe=a: b # not a comment\nrateLimit: {capacity: 1}")
+    t.Setenv("PROXY_TEST_INJECT", "x\nrateLimit: {capacity: 2}")
+
+    path := writeConfigFile(t, "config.yaml", `
+# capacity ${PROXY_TEST_INJECT}
+health:
+  path: ${PROXY_TEST_PATH}
+`)
+
+    cfg, err := loadFromFile(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if cfg.Health.Path != "/health?probe=a: b # not a comment\nrateLimit: {capacity: 1}" {
+        t.Errorf("Expected the variable to expand into a single value, got %q", cfg.Health.Path)
+    }
+    if cfg.RateLimit.Capacity != DefaultConfig().RateLimit.Capacity {
+        t.Errorf("Expected expanded text not to add settings, got capacity %d", cfg.RateLimit.Capacity)
+    }
+}
+
+// TestLoadFromFileErrors verifies missing and malformed files are reported
+func TestLoadFromFileErrors(t *testing.T) {
+    if _, err := loadFromFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+        t.Error("Expected error for missing file")
+    }
+
+    path := writeConfigFile(t, "config.yaml", "server:\n  port: [not-a-port\n")
+    if _, err := loadFromFile(path); err == nil {
+        t.Error("Expected error for malformed YAML")
+    }
+
+    path = writeConfigFile(t, "config.yaml", "server:\n  readTimeout: soon\n")
+    if _, err := loadFromFile(path); err == nil {
+        t.Error("Expected error for invalid duration")
+    }
+}
+
+// TestLoadConfigUpdatesInstance verifies LoadConfig replaces the singleton
+// Even when GetInstance was called first and initialised defaults
+func TestLoadConfigUpdatesInstance(t *testing.T) {
+    _ = GetInstance()
+
+    path := writeConfigFile(t, "config.yaml", "server:\n  port: 6060\n")
+    if err := LoadConfig(path); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if port := GetInstance().Server.Port; port != 6060 {
+        t.Errorf("Expected loaded port 6060, got %d", port)
+    }
+}
+
+// TestWatchDetectsChange verifies Watch calls onChange after the file is modified
+func TestWatchDetectsChange(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", "server:\n  port: 8080\n")
+
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+
+    changed := make(chan struct{}, 1)
+    go Watch(ctx, path, 5*time.Millisecond, func() {
+        select {
+        case changed <- struct{}{}:
+        default:
+        }
+    })
+
+    // Give the watcher time to record the initial file state
+    time.Sleep(20 * time.Millisecond)
+    if err := os.WriteFile(path, []byte("server:\n  port: 9090\n"), 0o600); err != nil {
+        t.Fatalf("failed to rewrite config file: %v", err)
+    }
+
+    select {
+    case <-changed:
+    case <-time.After(time.Second):
+        t.Fatal("Expected change notification after file modification")
+    }
+}
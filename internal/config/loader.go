@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// envPattern matches ${VAR} and ${VAR:-fallback} references
+// Variable names follow POSIX shell rules: letters, digits and underscores
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// durationType is used to detect time.Duration fields during default handling
+// Duration is an int64 kind so it needs explicit parsing of "30s" style values
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// expandEnv replaces environment variable references in the scalar values of node
+// ${VAR} expands to the variable value or an empty string when unset
+// ${VAR:-fallback} expands to fallback when the variable is unset or empty
+// Keys and comments are left alone, and a value containing ':', '#' or a
+// newline stays a single value. Expanded plain scalars are typed again by the
+// decoder like literal ones, while quoted scalars stay strings
+// Time Complexity: O(n) where n is total length of scalar values
+// Space Complexity: O(d) for recursion depth plus the expanded values
+func expandEnv(node *yaml.Node) {
+    switch node.Kind {
+    case yaml.DocumentNode, yaml.SequenceNode:
+        for _, child := range node.Content {
+            expandEnv(child)
+        }
+    case yaml.MappingNode:
+        for i := 1; i < len(node.Content); i += 2 {
+            expandEnv(node.Content[i])
+        }
+    case yaml.ScalarNode:
+        expanded := envPattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+            groups := envPattern.FindStringSubmatch(match)
+            value, _ := os.LookupEnv(groups[1])
+
+            // Fallback applies to both unset and empty variables, matching shell semantics
+            if value == "" && groups[2] != "" {
+                return groups[3]
+            }
+            return value
+        })
+        if expanded != node.Value {
+            node.Value = expanded
+            if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+                node.Tag = ""
+            }
+        }
+    }
+}
+
+// applyDefaults sets fields missing from the parsed document to their default tag
+// Walks the struct alongside its YAML mapping node so explicit zero values are kept
+// Recurses into nested structs and slices of structs such as backend lists
+// Time Complexity: O(f) where f is total number of fields across nested structs
+// Space Complexity: O(d) for recursion depth of nested structs
+func applyDefaults(v reflect.Value, node *yaml.Node) error {
+    t := v.Type()
+    for i := 0; i < t.NumField(); i++ {
+        field := t.Field(i)
+        if !field.IsExported() {
+            continue
+        }
+
+        value := v.Field(i)
+        child := lookupKey(node, yamlName(field))
+
+        switch {
+        case value.Kind() == reflect.Struct && value.Type() != durationType:
+            if err := applyDefaults(value, child); err != nil {
+                return err
+            }
+            continue
+        case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+            for j := 0; j < value.Len(); j++ {
+                var elemNode *yaml.Node
+                if child != nil && child.Kind == yaml.SequenceNode && j < len(child.Content) {
+                    elemNode = child.Content[j]
+                }
+                if err := applyDefaults(value.Index(j), elemNode); err != nil {
+                    return err
+                }
+            }
+            continue
+        }
+
+        tag, ok := field.Tag.Lookup("default")
+        if !ok || child != nil {
+            continue
+        }
+        if err := setFromString(value, tag); err != nil {
+            return fmt.Errorf("invalid default for %s.%s: %w", t.Name(), field.Name, err)
+        }
+    }
+    return nil
+}
+
+// Decode reads node into out, a pointer, and sets fields missing from node to
+// their default tag; a nil node leaves only the defaults
+// Algorithms use it for their options blocks so they follow the same rules as
+// the built-in configuration sections
+// Time Complexity: O(f) where f is total number of fields of out
+// Space Complexity: O(d) for recursion depth of nested structs
+func Decode(node *yaml.Node, out any) error {
+    if node != nil {
+        if err := node.Decode(out); err != nil {
+            return err
+        }
+    }
+    value := reflect.ValueOf(out)
+    if value.Kind() != reflect.Pointer || value.Elem().Kind() != reflect.Struct {
+        return nil
+    }
+    return applyDefaults(value.Elem(), node)
+}
+
+// defaults returns a T with every field set to its default tag
+// Default tags are fixed at compile time, so an invalid one is a programming error
+// Time Complexity: O(f) where f is total number of fields of T
+// Space Complexity: O(d) for recursion depth of nested structs
+func defaults[T any]() T {
+    var value T
+    if err := Decode(nil, &value); err != nil {
+        panic(err)
+    }
+    return value
+}
+
+// lookupKey returns the value node for key within a YAML mapping node
+// Returns nil when node is not a mapping or the key is absent
+// Time Complexity: O(k) where k is number of keys in the mapping
+// Space Complexity: O(1) - no allocations
+func lookupKey(node *yaml.Node, key string) *yaml.Node {
+    if node == nil || node.Kind != yaml.MappingNode {
+        return nil
+    }
+    for i := 0; i+1 < len(node.Content); i += 2 {
+        if node.Content[i].Value == key {
+            return node.Content[i+1]
+        }
+    }
+    return nil
+}
+
+// yamlName returns the key used for a struct field in YAML documents
+// Mirrors yaml.v3 behaviour of lowercasing the field name when untagged
+// Time Complexity: O(1) - tag lookup
+// Space Complexity: O(1) - returns substring of tag
+func yamlName(field reflect.StructField) string {
+    name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+    if name == "" {
+        return strings.ToLower(field.Name)
+    }
+    return name
+}
+
+// setFromString assigns a default tag value to a field based on its kind
+// Supports the scalar kinds used by Config plus comma-separated slices of them
+// Time Complexity: O(n) where n is length of the default value
+// Space Complexity: O(n) for parsed slice values
+func setFromString(value reflect.Value, raw string) error {
+    if value.Type() == durationType {
+        d, err := time.ParseDuration(raw)
+        if err != nil {
+            return err
+        }
+        value.SetInt(int64(d))
+        return nil
+    }
+
+    switch value.Kind() {
+    case reflect.String:
+        value.SetString(raw)
+    case reflect.Bool:
+        b, err := strconv.ParseBool(raw)
+        if err != nil {
+            return err
+        }
+        value.SetBool(b)
+    case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+        n, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+        if err != nil {
+            return err
+        }
+        value.SetInt(n)
+    case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+        n, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+        if err != nil {
+            return err
+        }
+        value.SetUint(n)
+    case reflect.Float32, reflect.Float64:
+        f, err := strconv.ParseFloat(raw, value.Type().Bits())
+        if err != nil {
+            return err
+        }
+        value.SetFloat(f)
+    case reflect.Slice:
+        parts := strings.Split(raw, ",")
+        slice := reflect.MakeSlice(value.Type(), len(parts), len(parts))
+        for i, part := range parts {
+            if err := setFromString(slice.Index(i), strings.TrimSpace(part)); err != nil {
+                return err
+            }
+        }
+        value.Set(slice)
+    default:
+        return fmt.Errorf("unsupported field kind %s", value.Kind())
+    }
+    return nil
+}
@@ -0,0 +1,714 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"maps"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Algorithms is the set of load balancing algorithms a configuration may name
+// It is passed to Validate rather than kept here, so validation sees exactly
+// the algorithms the caller will build pools with; loadbalancer.Algorithms
+// returns the registered ones. Names are compared case-insensitively
+type Algorithms interface {
+    Supported(name string) bool // Reports whether name is a known algorithm
+    Names() []string            // Sorted algorithm names for error messages
+
+    // ValidateOptions checks a pool's options block for algorithm name, nil when absent
+    ValidateOptions(name string, options *yaml.Node) error
+}
+
+// FieldError describes a single invalid configuration value
+// Path uses the YAML key names, e.g. loadBalance.backends[1].url
+type FieldError struct {
+    Path    string // YAML path of the offending field
+    Message string // Human readable description of the problem
+}
+
+// Error formats the field error as "path: message"
+func (e FieldError) Error() string {
+    return e.Path + ": " + e.Message
+}
+
+// ValidationError aggregates every problem found in a configuration
+// Reporting all problems at once avoids fix-one-rerun cycles in CI
+type ValidationError struct {
+    Errors []FieldError
+}
+
+// Error joins all field errors into a multi-line message
+// Time Complexity: O(e) where e is number of field errors
+// Space Complexity: O(e) for the joined message
+func (e *ValidationError) Error() string {
+    lines := make([]string, len(e.Errors))
+    for i, fieldErr := range e.Errors {
+        lines[i] = fieldErr.Error()
+    }
+    return fmt.Sprintf("invalid configuration (%d problems):\n  %s", len(e.Errors), strings.Join(lines, "\n  "))
+}
+
+// validator accumulates field errors while walking a configuration
+type validator struct {
+    algorithms Algorithms
+    errors     []FieldError
+}
+
+// addf records a field error with a formatted message
+func (v *validator) addf(path, format string, args ...any) {
+    v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// nonNegative records an error when a duration is negative
+func (v *validator) nonNegative(path string, d time.Duration) {
+    if d < 0 {
+        v.addf(path, "must not be negative, got %s", d)
+    }
+}
+
+// positive records an error when a duration is zero or negative
+func (v *validator) positive(path string, d time.Duration) {
+    if d <= 0 {
+        v.addf(path, "must be greater than zero, got %s", d)
+    }
+}
+
+// Validate checks the configuration for values that would fail at runtime
+// Pool algorithms must be among algorithms, usually loadbalancer.Algorithms()
+// Returns nil or a *ValidationError listing every problem with its YAML path
+// Sections that are disabled are not validated beyond their enabled flag
+// Time Complexity: O(b) where b is number of configured backends
+// Space Complexity: O(e) where e is number of problems found
+func (c *Config) Validate(algorithms Algorithms) error {
+    v := &validator{algorithms: algorithms}
+
+    c.Server.validate(v, "server")
+    c.Cache.validate(v, "cache")
+    c.RateLimit.validate(v, "rateLimit")
+    c.LoadBalance.validate(v, "loadBalance")
+    c.Routing.validate(v, "routing", len(c.LoadBalance.Backends) > 0)
+    c.Health.validate(v, "health")
+    c.WebSocket.validate(v, "websocket")
+    c.Tracing.validate(v, "tracing")
+
+    if len(v.errors) == 0 {
+        return nil
+    }
+    return &ValidationError{Errors: v.errors}
+}
+
+// validate checks listener port, timeouts and TLS file pairing
+func (s *ServerConfig) validate(v *validator, path string) {
+    if s.Port < 1 || s.Port > 65535 {
+        v.addf(path+".port", "must be between 1 and 65535, got %d", s.Port)
+    }
+    v.nonNegative(path+".readTimeout", s.ReadTimeout)
+    v.nonNegative(path+".writeTimeout", s.WriteTimeout)
+    v.nonNegative(path+".idleTimeout", s.IdleTimeout)
+
+    if s.TLSCertFile != "" && s.TLSKeyFile == "" {
+        v.addf(path+".tlsKeyFile", "must be set when tlsCertFile is set")
+    }
+    if s.TLSKeyFile != "" && s.TLSCertFile == "" {
+        v.addf(path+".tlsCertFile", "must be set when tlsKeyFile is set")
+    }
+
+    for i, cert := range s.TLSCertificates {
+        certPath := fmt.Sprintf("%s.tlsCertificates[%d]", path, i)
+        if cert.CertFile == "" {
+            v.addf(certPath+".certFile", "must be set")
+        }
+        if cert.KeyFile == "" {
+            v.addf(certPath+".keyFile", "must be set")
+        }
+    }
+
+    if _, err := s.TLSMinVersionID(); err != nil {
+        v.addf(path+".tlsMinVersion", "%v", err)
+    }
+    for i, name := range s.TLSCipherSuites {
+        probe := ServerConfig{TLSCipherSuites: []string{name}}
+        if _, err := probe.TLSCipherSuiteIDs(); err != nil {
+            v.addf(fmt.Sprintf("%s.tlsCipherSuites[%d]", path, i), "%v", err)
+        }
+    }
+    for i, protocol := range s.TLSALPNProtocols {
+        if protocol == "" {
+            v.addf(fmt.Sprintf("%s.tlsAlpnProtocols[%d]", path, i), "must not be empty")
+        }
+    }
+    v.nonNegative(path+".tlsReloadInterval", s.TLSReloadInterval)
+
+    clientAuth, err := s.TLSClientAuthType()
+    if err != nil {
+        v.addf(path+".tlsClientAuth", "%v", err)
+    }
+    if (clientAuth == tls.VerifyClientCertIfGiven || clientAuth == tls.RequireAndVerifyClientCert) && s.TLSClientCAFile == "" {
+        v.addf(path+".tlsClientCAFile", "must be set when tlsClientAuth is %s", s.TLSClientAuth)
+    }
+    if clientAuth != tls.NoClientCert && !s.TLSEnabled() {
+        v.addf(path+".tlsClientAuth", "requires TLS certificates to be configured")
+    }
+    if s.H2C && s.TLSEnabled() {
+        v.addf(path+".h2c", "applies only without TLS; HTTP/2 over TLS is negotiated through tlsAlpnProtocols")
+    }
+}
+
+// validate checks cache sizing when caching is enabled
+func (c *CacheConfig) validate(v *validator, path string) {
+    if !c.Enabled {
+        return
+    }
+    if c.MaxSize <= 0 {
+        v.addf(path+".maxSize", "must be greater than zero, got %d", c.MaxSize)
+    }
+    v.positive(path+".ttl", c.TTL)
+}
+
+// validate checks token bucket parameters when rate limiting is enabled
+func (r *RateLimitConfig) validate(v *validator, path string) {
+    if !r.Enabled {
+        return
+    }
+    if r.Capacity <= 0 {
+        v.addf(path+".capacity", "must be greater than zero, got %d", r.Capacity)
+    }
+    if r.RefillRate <= 0 {
+        v.addf(path+".refillRate", "must be greater than zero, got %d", r.RefillRate)
+    }
+}
+
+// validate checks the algorithm name and every backend entry
+// The section may only be left without backends when routing defines its own pools
+func (l *LoadBalanceConfig) validate(v *validator, path string) {
+    validatePool(v, path, l.Algorithm, l.Backends, l.Options)
+    l.Transport.validate(v, path+".transport")
+    l.Retry.validate(v, path+".retry")
+    l.CircuitBreaker.validate(v, path+".circuitBreaker")
+    l.OutlierDetection.validate(v, path+".outlierDetection")
+    l.Hash.validate(v, path+".hash", l.Algorithm)
+    l.Sticky.validate(v, path+".sticky")
+    l.P2C.validate(v, path+".p2c", l.Algorithm)
+    l.SlowStart.validate(v, path+".slowStart")
+    l.Mirror.validate(v, path+".mirror")
+}
+
+// validate checks trip thresholds and timings when circuit breaking is enabled
+func (c *CircuitBreakerConfig) validate(v *validator, path string) {
+    if !c.Enabled {
+        return
+    }
+    if c.ConsecutiveFailures < 0 {
+        v.addf(path+".consecutiveFailures", "must not be negative, got %d", c.ConsecutiveFailures)
+    }
+    if c.ErrorRate < 0 || c.ErrorRate > 1 {
+        v.addf(path+".errorRate", "must be between 0 and 1, got %g", c.ErrorRate)
+    }
+    if c.ConsecutiveFailures == 0 && c.ErrorRate == 0 {
+        v.addf(path, "consecutiveFailures or errorRate must be set")
+    }
+    if c.ErrorRate > 0 {
+        v.positive(path+".window", c.Window)
+        if c.MinRequests < 1 {
+            v.addf(path+".minRequests", "must be at least 1, got %d", c.MinRequests)
+        }
+    }
+    v.positive(path+".openDuration", c.OpenDuration)
+    if c.HalfOpenProbes < 1 {
+        v.addf(path+".halfOpenProbes", "must be at least 1, got %d", c.HalfOpenProbes)
+    }
+}
+
+// validate checks outlier detection thresholds when detection is enabled
+func (o *OutlierDetectionConfig) validate(v *validator, path string) {
+    if !o.Enabled {
+        return
+    }
+    v.positive(path+".interval", o.Interval)
+    if o.Consecutive5xx < 0 {
+        v.addf(path+".consecutive5xx", "must not be negative, got %d", o.Consecutive5xx)
+    }
+    if o.ConsecutiveConnectErrors < 0 {
+        v.addf(path+".consecutiveConnectErrors", "must not be negative, got %d", o.ConsecutiveConnectErrors)
+    }
+    if o.ErrorRateDeviation < 0 || o.ErrorRateDeviation > 1 {
+        v.addf(path+".errorRateDeviation", "must be between 0 and 1, got %g", o.ErrorRateDeviation)
+    }
+    if o.LatencyDeviation != 0 && o.LatencyDeviation <= 1 {
+        v.addf(path+".latencyDeviation", "must be 0 or greater than 1, got %g", o.LatencyDeviation)
+    }
+    if o.ErrorRateDeviation > 0 || o.LatencyDeviation > 0 {
+        if o.MinRequests < 1 {
+            v.addf(path+".minRequests", "must be at least 1, got %d", o.MinRequests)
+        }
+        if o.MinHosts < 2 {
+            v.addf(path+".minHosts", "must be at least 2, got %d", o.MinHosts)
+        }
+    }
+    v.positive(path+".baseEjectionTime", o.BaseEjectionTime)
+    if o.MaxEjectionTime < o.BaseEjectionTime {
+        v.addf(path+".maxEjectionTime", "must not be less than baseEjectionTime (%s), got %s", o.BaseEjectionTime, o.MaxEjectionTime)
+    }
+    if o.MaxEjectionPercent < 1 || o.MaxEjectionPercent > 100 {
+        v.addf(path+".maxEjectionPercent", "must be between 1 and 100, got %d", o.MaxEjectionPercent)
+    }
+}
+
+// validate checks the consistent-hash key and ring size when the pool hashes requests
+func (h *HashConfig) validate(v *validator, path, algorithm string) {
+    if !strings.EqualFold(algorithm, ConsistentHashAlgorithm) {
+        return
+    }
+    switch h.Key {
+    case HashKeyHeader, HashKeyCookie:
+        if h.Name == "" {
+            v.addf(path+".name", "must be set for %s keys", h.Key)
+        }
+    case HashKeyClientIP, HashKeyPath:
+    default:
+        v.addf(path+".key", "must be %s, %s, %s or %s, got %q", HashKeyClientIP, HashKeyHeader, HashKeyCookie, HashKeyPath, h.Key)
+    }
+    if h.Segment < 0 {
+        v.addf(path+".segment", "must not be negative, got %d", h.Segment)
+    }
+    if h.VirtualNodes < 1 {
+        v.addf(path+".virtualNodes", "must be at least 1, got %d", h.VirtualNodes)
+    }
+}
+
+// validate checks the latency decay and failure penalty when the pool uses p2c-ewma
+func (p *P2CConfig) validate(v *validator, path, algorithm string) {
+    if !strings.EqualFold(algorithm, P2CEWMAAlgorithm) {
+        return
+    }
+    v.positive(path+".decay", p.Decay)
+    v.positive(path+".failurePenalty", p.FailurePenalty)
+}
+
+// validate checks the ramp-up window and curve when slow start is enabled
+func (s *SlowStartConfig) validate(v *validator, path string) {
+    if !s.Enabled {
+        return
+    }
+    v.positive(path+".window", s.Window)
+    if s.MinWeight <= 0 || s.MinWeight > 1 {
+        v.addf(path+".minWeight", "must be greater than 0 and at most 1, got %g", s.MinWeight)
+    }
+    if s.Aggression <= 0 {
+        v.addf(path+".aggression", "must be positive, got %g", s.Aggression)
+    }
+}
+
+// validate checks the shadow backend and sampling limits when mirroring is enabled
+func (m *MirrorConfig) validate(v *validator, path string) {
+    if !m.Enabled {
+        return
+    }
+    // The shadow URL and TLS settings follow the rules of a pool backend
+    shadow := BackendConfig{URL: m.URL, TLS: m.TLS}
+    shadow.validate(v, path)
+    if m.Percent < 0 || m.Percent > 100 {
+        v.addf(path+".percent", "must be between 0 and 100, got %g", m.Percent)
+    }
+    if m.MaxBodyBytes < 0 {
+        v.addf(path+".maxBodyBytes", "must not be negative, got %d", m.MaxBodyBytes)
+    }
+    if m.Header == "" || strings.ContainsAny(m.Header, " \t:") {
+        v.addf(path+".header", "must be a valid header name, got %q", m.Header)
+    }
+    v.positive(path+".timeout", m.Timeout)
+    if m.MaxInFlight < 1 {
+        v.addf(path+".maxInFlight", "must be at least 1, got %d", m.MaxInFlight)
+    }
+}
+
+// validate checks the sticky cookie when sticky sessions are enabled
+func (s *StickyConfig) validate(v *validator, path string) {
+    if !s.Enabled {
+        return
+    }
+    if s.Cookie == "" || strings.ContainsAny(s.Cookie, " \t;,=\"") {
+        v.addf(path+".cookie", "must be a valid cookie name, got %q", s.Cookie)
+    }
+    v.nonNegative(path+".ttl", s.TTL)
+}
+
+// validate checks attempt count, backoff bounds and status codes when retries are enabled
+func (r *RetryConfig) validate(v *validator, path string) {
+    if !r.Enabled {
+        return
+    }
+    if r.MaxAttempts < 1 {
+        v.addf(path+".maxAttempts", "must be at least 1, got %d", r.MaxAttempts)
+    }
+    v.nonNegative(path+".initialBackoff", r.InitialBackoff)
+    v.nonNegative(path+".maxBackoff", r.MaxBackoff)
+    if r.MaxBackoff < r.InitialBackoff {
+        v.addf(path+".maxBackoff", "must not be less than initialBackoff %s, got %s", r.InitialBackoff, r.MaxBackoff)
+    }
+    v.nonNegative(path+".budget", r.Budget)
+    for i, code := range r.RetryOn {
+        if code < 100 || code > 599 {
+            v.addf(fmt.Sprintf("%s.retryOn[%d]", path, i), "must be an HTTP status code, got %d", code)
+        }
+    }
+    if r.MaxBodyBytes < 0 {
+        v.addf(path+".maxBodyBytes", "must not be negative, got %d", r.MaxBodyBytes)
+    }
+}
+
+// validate checks connection limits and timeouts are not negative
+func (t *TransportConfig) validate(v *validator, path string) {
+    if t.MaxIdleConns < 0 {
+        v.addf(path+".maxIdleConns", "must not be negative, got %d", t.MaxIdleConns)
+    }
+    if t.MaxIdleConnsPerHost < 0 {
+        v.addf(path+".maxIdleConnsPerHost", "must not be negative, got %d", t.MaxIdleConnsPerHost)
+    }
+    if t.MaxConnsPerHost < 0 {
+        v.addf(path+".maxConnsPerHost", "must not be negative, got %d", t.MaxConnsPerHost)
+    }
+    v.nonNegative(path+".idleConnTimeout", t.IdleConnTimeout)
+    v.nonNegative(path+".dialTimeout", t.DialTimeout)
+    v.nonNegative(path+".keepAlive", t.KeepAlive)
+    v.nonNegative(path+".tlsHandshakeTimeout", t.TLSHandshakeTimeout)
+    v.nonNegative(path+".responseHeaderTimeout", t.ResponseHeaderTimeout)
+    v.nonNegative(path+".expectContinueTimeout", t.ExpectContinueTimeout)
+    if t.H2C && !t.HTTP2 {
+        v.addf(path+".h2c", "requires http2")
+    }
+}
+
+// validatePool checks an algorithm name, the options blocks and the backend
+// list shared by all pool kinds; each options block is checked by its algorithm
+func validatePool(v *validator, path, algorithm string, backends []BackendConfig, options AlgorithmOptions) {
+    if !v.algorithms.Supported(algorithm) {
+        v.addf(path+".algorithm", "unsupported algorithm %q (supported: %s)",
+            algorithm, strings.Join(v.algorithms.Names(), ", "))
+    } else if options.For(algorithm) == nil {
+        if err := v.algorithms.ValidateOptions(algorithm, nil); err != nil {
+            v.addf(path+".options."+algorithm, "%v", err)
+        }
+    }
+    for _, name := range slices.Sorted(maps.Keys(options)) {
+        node := options[name]
+        if !v.algorithms.Supported(name) {
+            v.addf(path+".options."+name, "unsupported algorithm %q", name)
+        } else if err := v.algorithms.ValidateOptions(name, &node); err != nil {
+            v.addf(path+".options."+name, "%v", err)
+        }
+    }
+
+    seen := make(map[string]int, len(backends))
+    for i, backend := range backends {
+        backendPath := fmt.Sprintf("%s.backends[%d]", path, i)
+        backend.validate(v, backendPath)
+
+        if first, exists := seen[backend.URL]; exists && backend.URL != "" {
+            v.addf(backendPath+".url", "duplicates %s.backends[%d].url", path, first)
+            continue
+        }
+        seen[backend.URL] = i
+    }
+}
+
+// validate checks pool and split definitions and that every route references a
+// known pool or split
+// defaultPoolDefined reports whether loadBalance provides the implicit default pool
+func (r *RoutingConfig) validate(v *validator, path string, defaultPoolDefined bool) {
+    pools := make(map[string]bool, len(r.Pools)+1)
+    if defaultPoolDefined {
+        pools[DefaultPoolName] = true
+    }
+    if !defaultPoolDefined && len(r.Pools) == 0 {
+        v.addf("loadBalance.backends", "at least one backend is required")
+    }
+
+    for i, pool := range r.Pools {
+        poolPath := fmt.Sprintf("%s.pools[%d]", path, i)
+        switch {
+        case pool.Name == "":
+            v.addf(poolPath+".name", "must be set")
+        case pool.Name == DefaultPoolName:
+            v.addf(poolPath+".name", "%q is reserved for the loadBalance section", DefaultPoolName)
+        case pools[pool.Name]:
+            v.addf(poolPath+".name", "duplicate pool name %q", pool.Name)
+        }
+        pools[pool.Name] = true
+
+        if len(pool.Backends) == 0 {
+            v.addf(poolPath+".backends", "at least one backend is required")
+        }
+        validatePool(v, poolPath, pool.Algorithm, pool.Backends, pool.Options)
+        pool.Transport.validate(v, poolPath+".transport")
+        pool.Retry.validate(v, poolPath+".retry")
+        pool.CircuitBreaker.validate(v, poolPath+".circuitBreaker")
+        pool.OutlierDetection.validate(v, poolPath+".outlierDetection")
+        pool.Hash.validate(v, poolPath+".hash", pool.Algorithm)
+        pool.Sticky.validate(v, poolPath+".sticky")
+        pool.P2C.validate(v, poolPath+".p2c", pool.Algorithm)
+        pool.SlowStart.validate(v, poolPath+".slowStart")
+        pool.Mirror.validate(v, poolPath+".mirror")
+    }
+
+    // Splits are referenced like pools but must not divide traffic between splits
+    targets := make(map[string]bool, len(pools)+len(r.Splits))
+    for name := range pools {
+        targets[name] = true
+    }
+    for i, split := range r.Splits {
+        splitPath := fmt.Sprintf("%s.splits[%d]", path, i)
+        switch {
+        case split.Name == "":
+            v.addf(splitPath+".name", "must be set")
+        case targets[split.Name]:
+            v.addf(splitPath+".name", "duplicate pool or split name %q", split.Name)
+        }
+        targets[split.Name] = true
+        split.validate(v, splitPath, pools)
+    }
+
+    for i, route := range r.Routes {
+        route.validate(v, fmt.Sprintf("%s.routes[%d]", path, i), targets)
+    }
+
+    if r.DefaultPool != "" && !targets[r.DefaultPool] {
+        v.addf(path+".defaultPool", "unknown pool %q", r.DefaultPool)
+    }
+}
+
+// validate checks match conditions compile and the target pool exists
+// Redirect routes answer requests themselves and need no pool
+func (r *RouteConfig) validate(v *validator, path string, pools map[string]bool) {
+    switch {
+    case r.Pool == "" && !r.Redirect.Enabled():
+        v.addf(path+".pool", "must be set unless the route redirects")
+    case r.Pool != "" && !pools[r.Pool]:
+        v.addf(path+".pool", "unknown pool %q", r.Pool)
+    }
+    r.Rewrite.validate(v, path+".rewrite")
+    r.Redirect.validate(v, path+".redirect")
+    if r.Redirect.Enabled() && !r.Rewrite.IsZero() {
+        v.addf(path+".rewrite", "cannot be combined with redirect")
+    }
+
+    if r.PathPrefix != "" && !strings.HasPrefix(r.PathPrefix, "/") {
+        v.addf(path+".pathPrefix", "must start with /, got %q", r.PathPrefix)
+    }
+    if r.PathRegex != "" {
+        if _, err := regexp.Compile(r.PathRegex); err != nil {
+            v.addf(path+".pathRegex", "invalid regular expression: %v", err)
+        }
+    }
+    if strings.HasPrefix(r.Host, "*") && !strings.HasPrefix(r.Host, "*.") {
+        v.addf(path+".host", "wildcard must be of the form *.example.com, got %q", r.Host)
+    }
+    for i, method := range r.Methods {
+        if method == "" || strings.ContainsAny(method, " \t/") {
+            v.addf(fmt.Sprintf("%s.methods[%d]", path, i), "invalid method %q", method)
+        }
+    }
+
+    validateHeaderMatches(v, path+".headers", r.Headers)
+    r.GRPC.validate(v, path+".grpc")
+}
+
+// validate checks the service and method form the path of a gRPC call
+func (g *GRPCMatchConfig) validate(v *validator, path string) {
+    if g.Method != "" && g.Service == "" {
+        v.addf(path+".service", "must be set when method is set")
+    }
+    if strings.ContainsAny(g.Service, "/ ") || strings.HasPrefix(g.Service, ".") {
+        v.addf(path+".service", "must be a fully qualified name such as package.Service, got %q", g.Service)
+    }
+    if strings.ContainsAny(g.Method, "/ ") {
+        v.addf(path+".method", "must be a method name, got %q", g.Method)
+    }
+}
+
+// validate checks both sides of a split are distinct known pools and its
+// percentage, match conditions and sticky cookie are usable
+func (s *SplitConfig) validate(v *validator, path string, pools map[string]bool) {
+    for _, side := range []struct{ field, pool string }{{"stable", s.Stable}, {"canary", s.Canary}} {
+        switch {
+        case side.pool == "":
+            v.addf(path+"."+side.field, "must be set")
+        case !pools[side.pool]:
+            v.addf(path+"."+side.field, "unknown pool %q", side.pool)
+        }
+    }
+    if s.Stable != "" && s.Stable == s.Canary {
+        v.addf(path+".canary", "must differ from stable, got %q", s.Canary)
+    }
+    if s.Percent < 0 || s.Percent > 100 {
+        v.addf(path+".percent", "must be between 0 and 100, got %g", s.Percent)
+    }
+    validateHeaderMatches(v, path+".headers", s.Headers)
+    validateHeaderMatches(v, path+".cookies", s.Cookies)
+
+    if s.Sticky.Enabled {
+        if s.Sticky.Cookie == "" || strings.ContainsAny(s.Sticky.Cookie, " \t;,=\"") {
+            v.addf(path+".sticky.cookie", "must be a valid cookie name, got %q", s.Sticky.Cookie)
+        }
+        v.nonNegative(path+".sticky.ttl", s.Sticky.TTL)
+    }
+}
+
+// validateHeaderMatches checks name/value/regex match conditions under path
+func validateHeaderMatches(v *validator, path string, matches []HeaderMatchConfig) {
+    for i, match := range matches {
+        matchPath := fmt.Sprintf("%s[%d]", path, i)
+        if match.Name == "" {
+            v.addf(matchPath+".name", "must be set")
+        }
+        if match.Value != "" && match.Regex != "" {
+            v.addf(matchPath, "value and regex are mutually exclusive")
+        }
+        if match.Regex != "" {
+            if _, err := regexp.Compile(match.Regex); err != nil {
+                v.addf(matchPath+".regex", "invalid regular expression: %v", err)
+            }
+        }
+    }
+}
+
+// validate checks that a backend URL is absolute HTTP(S) and weight is usable
+func (b *BackendConfig) validate(v *validator, path string) {
+    if b.Weight < 0 {
+        v.addf(path+".weight", "must not be negative, got %d", b.Weight)
+    }
+    if b.HealthPath != "" && !strings.HasPrefix(b.HealthPath, "/") {
+        v.addf(path+".healthPath", "must start with /, got %q", b.HealthPath)
+    }
+
+    if b.URL == "" {
+        v.addf(path+".url", "must be set")
+        return
+    }
+    u, err := url.Parse(b.URL)
+    if err != nil {
+        v.addf(path+".url", "invalid URL: %v", err)
+        return
+    }
+    if u.Scheme != "http" && u.Scheme != "https" {
+        v.addf(path+".url", "scheme must be http or https, got %q", u.Scheme)
+    }
+    if u.Host == "" {
+        v.addf(path+".url", "must include a host")
+    }
+
+    if (b.TLS.CertFile == "") != (b.TLS.KeyFile == "") {
+        v.addf(path+".tls", "certFile and keyFile must be set together")
+    }
+    if b.TLS != (BackendTLSConfig{}) && u.Scheme != "https" {
+        v.addf(path+".tls", "requires an https backend URL")
+    }
+}
+
+// validate checks rewritten paths are absolute and prefix options are not mixed with path
+func (r *RewriteConfig) validate(v *validator, path string) {
+    if r.StripPrefix != "" && !strings.HasPrefix(r.StripPrefix, "/") {
+        v.addf(path+".stripPrefix", "must start with /, got %q", r.StripPrefix)
+    }
+    if r.AddPrefix != "" && !strings.HasPrefix(r.AddPrefix, "/") {
+        v.addf(path+".addPrefix", "must start with /, got %q", r.AddPrefix)
+    }
+    if r.Path != "" {
+        if !strings.HasPrefix(r.Path, "/") && !strings.HasPrefix(r.Path, "$") {
+            v.addf(path+".path", "must start with / or a capture group, got %q", r.Path)
+        }
+        if r.StripPrefix != "" || r.AddPrefix != "" {
+            v.addf(path+".path", "cannot be combined with stripPrefix or addPrefix")
+        }
+    }
+    for i, name := range r.RemoveQuery {
+        if name == "" {
+            v.addf(fmt.Sprintf("%s.removeQuery[%d]", path, i), "must not be empty")
+        }
+    }
+}
+
+// validate checks the redirect target and status code
+func (r *RedirectConfig) validate(v *validator, path string) {
+    switch r.Status {
+    case 0, 301, 302, 307, 308:
+    default:
+        v.addf(path+".status", "must be 301, 302, 307 or 308, got %d", r.Status)
+    }
+    if r.URL != "" && r.HTTPS {
+        v.addf(path, "url and https are mutually exclusive")
+    }
+    if r.HTTPSPort < 0 || r.HTTPSPort > 65535 {
+        v.addf(path+".httpsPort", "must be between 0 and 65535, got %d", r.HTTPSPort)
+    }
+}
+
+// validate checks probe timing and path when health checks are enabled
+func (h *HealthConfig) validate(v *validator, path string) {
+    if !h.Enabled {
+        return
+    }
+    v.positive(path+".interval", h.Interval)
+    v.positive(path+".timeout", h.Timeout)
+    if h.Interval > 0 && h.Timeout > h.Interval {
+        v.addf(path+".timeout", "must not exceed interval %s, got %s", h.Interval, h.Timeout)
+    }
+    if h.Jitter < 0 || h.Jitter > 1 {
+        v.addf(path+".jitter", "must be between 0 and 1, got %g", h.Jitter)
+    }
+    if h.Rise < 1 {
+        v.addf(path+".rise", "must be at least 1, got %d", h.Rise)
+    }
+    if h.Fall < 1 {
+        v.addf(path+".fall", "must be at least 1, got %d", h.Fall)
+    }
+
+    switch h.Type {
+    case HealthCheckHTTP:
+        if !strings.HasPrefix(h.Path, "/") {
+            v.addf(path+".path", "must start with /, got %q", h.Path)
+        }
+        if len(h.ExpectedStatus) == 0 {
+            v.addf(path+".expectedStatus", "must list at least one status")
+        } else if _, err := ParseStatusRanges(h.ExpectedStatus); err != nil {
+            v.addf(path+".expectedStatus", "%v", err)
+        }
+        if h.BodyRegex != "" {
+            if _, err := regexp.Compile(h.BodyRegex); err != nil {
+                v.addf(path+".bodyRegex", "invalid regex: %v", err)
+            }
+        }
+    case HealthCheckTCP, HealthCheckGRPC:
+    default:
+        v.addf(path+".type", "must be %s, %s or %s, got %q", HealthCheckHTTP, HealthCheckTCP, HealthCheckGRPC, h.Type)
+    }
+}
+
+// validate checks upgraded connection limits are not negative
+func (w *WebSocketConfig) validate(v *validator, path string) {
+    v.nonNegative(path+".idleTimeout", w.IdleTimeout)
+    v.nonNegative(path+".maxLifetime", w.MaxLifetime)
+    v.positive(path+".closeTimeout", w.CloseTimeout)
+}
+
+// validate checks sampling and exporter settings when tracing is enabled
+func (t *TracingConfig) validate(v *validator, path string) {
+    if !t.Enabled {
+        return
+    }
+    if t.ServiceName == "" {
+        v.addf(path+".serviceName", "must be set")
+    }
+    if t.SamplingRatio < 0 || t.SamplingRatio > 1 {
+        v.addf(path+".samplingRatio", "must be between 0 and 1, got %g", t.SamplingRatio)
+    }
+    if t.JaegerEndpoint == "" && t.OTLPEndpoint == "" {
+        v.addf(path, "jaegerEndpoint or otlpEndpoint must be set")
+    }
+}
@@ -0,0 +1,548 @@
+package config
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// algorithmSet implements Algorithms over a fixed list of names
+type algorithmSet []string
+
+// Supported reports whether name is in the set, ignoring case
+func (s algorithmSet) Supported(name string) bool {
+    return slices.ContainsFunc(s, func(known string) bool { return strings.EqualFold(known, name) })
+}
+
+// Names returns the names in the set
+func (s algorithmSet) Names() []string {
+    return s
+}
+
+// ValidateOptions accepts any options block
+func (s algorithmSet) ValidateOptions(string, *yaml.Node) error {
+    return nil
+}
+
+// testAlgorithms are the algorithms the validation tests may name
+var testAlgorithms = algorithmSet{"round-robin", ConsistentHashAlgorithm, P2CEWMAAlgorithm}
+
+// validTestConfig returns a configuration that passes validation
+// Tests mutate a copy to exercise individual rules
+func validTestConfig() *Config {
+    cfg := DefaultConfig()
+    cfg.LoadBalance.Backends = []BackendConfig{
+        {URL: "http://a.internal", Weight: 1},
+        {URL: "https://b.internal:8443", Weight: 2},
+    }
+    return cfg
+}
+
+// fieldPaths extracts the YAML paths from a validation error
+func fieldPaths(t *testing.T, err error) map[string]bool {
+    t.Helper()
+
+    var validationErr *ValidationError
+    if !errors.As(err, &validationErr) {
+        t.Fatalf("Expected *ValidationError, got %T: %v", err, err)
+    }
+
+    paths := make(map[string]bool)
+    for _, fieldErr := range validationErr.Errors {
+        paths[fieldErr.Path] = true
+    }
+    return paths
+}
+
+// TestValidateValidConfig verifies a well-formed configuration passes
+func TestValidateValidConfig(t *testing.T) {
+    if err := validTestConfig().Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected valid config, got %v", err)
+    }
+}
+
+// TestValidateAggregatesErrors verifies all problems are reported with YAML paths
+// Ensures CI sees every issue in one run instead of one at a time
+func TestValidateAggregatesErrors(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Server.Port = 0
+    cfg.Server.TLSCertFile = "/etc/cert.pem"
+    cfg.Cache.TTL = 0
+    cfg.LoadBalance.Algorithm = "random"
+    cfg.LoadBalance.Backends = append(cfg.LoadBalance.Backends,
+        BackendConfig{URL: "ftp://c.internal"},
+        BackendConfig{URL: "http://a.internal"},
+        BackendConfig{URL: "", Weight: -1},
+    )
+    cfg.Health.Interval = 0
+    cfg.Health.Path = "health"
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+
+    expected := []string{
+        "server.port",
+        "server.tlsKeyFile",
+        "cache.ttl",
+        "loadBalance.algorithm",
+        "loadBalance.backends[2].url",
+        "loadBalance.backends[3].url",
+        "loadBalance.backends[4].url",
+        "loadBalance.backends[4].weight",
+        "health.interval",
+        "health.path",
+    }
+    for _, path := range expected {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+    if len(paths) != len(expected) {
+        t.Errorf("Expected %d distinct paths, got %d: %v", len(expected), len(paths), paths)
+    }
+}
+
+// TestValidateSkipsDisabledSections verifies disabled features are not validated
+// A disabled health check with zero interval must not be rejected
+func TestValidateSkipsDisabledSections(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Health.Enabled = false
+    cfg.Health.Interval = 0
+    cfg.Cache.Enabled = false
+    cfg.Cache.MaxSize = 0
+    cfg.RateLimit.Enabled = false
+    cfg.RateLimit.Capacity = 0
+
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled sections to be skipped, got %v", err)
+    }
+}
+
+// TestValidateHealthTimeout verifies timeout longer than interval is rejected
+func TestValidateHealthTimeout(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Health.Interval = time.Second
+    cfg.Health.Timeout = 2 * time.Second
+
+    if paths := fieldPaths(t, cfg.Validate(testAlgorithms)); !paths["health.timeout"] {
+        t.Errorf("Expected health.timeout error, got %v", paths)
+    }
+}
+
+// TestValidateTracing verifies tracing requires an exporter and valid ratio
+func TestValidateTracing(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Tracing.Enabled = true
+    cfg.Tracing.SamplingRatio = 1.5
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    if !paths["tracing"] || !paths["tracing.samplingRatio"] {
+        t.Errorf("Expected tracing errors, got %v", paths)
+    }
+}
+
+// TestValidateTLSSettings verifies TLS version, cipher suite and ALPN checks
+func TestValidateTLSSettings(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Server.TLSMinVersion = "1.4"
+    cfg.Server.TLSCipherSuites = []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_RC4_128_SHA"}
+    cfg.Server.TLSALPNProtocols = []string{"h2", ""}
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"server.tlsMinVersion", "server.tlsCipherSuites[1]", "server.tlsAlpnProtocols[1]"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+    if paths["server.tlsCipherSuites[0]"] {
+        t.Error("Expected secure cipher suite to be accepted")
+    }
+}
+
+// TestValidateClientAuth verifies client certificate verification needs a CA bundle and TLS
+func TestValidateClientAuth(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Server.TLSClientAuth = "require-and-verify"
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    if !paths["server.tlsClientCAFile"] || !paths["server.tlsClientAuth"] {
+        t.Errorf("Expected client auth errors, got %v", paths)
+    }
+
+    cfg.Server.TLSClientAuth = "sometimes"
+    if paths := fieldPaths(t, cfg.Validate(testAlgorithms)); !paths["server.tlsClientAuth"] {
+        t.Errorf("Expected unknown client auth mode to be rejected, got %v", paths)
+    }
+}
+
+// TestValidateBackendTLS verifies backend client certificates need a key and an https URL
+func TestValidateBackendTLS(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Backends[0].TLS = BackendTLSConfig{CAFile: "/etc/ca.pem"}
+    cfg.LoadBalance.Backends[1].TLS = BackendTLSConfig{CertFile: "/etc/client.pem"}
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    if !paths["loadBalance.backends[0].tls"] || !paths["loadBalance.backends[1].tls"] {
+        t.Errorf("Expected backend tls errors, got %v", paths)
+    }
+}
+
+// TestValidateRouting verifies pool names, route references and match expressions
+func TestValidateRouting(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Routing.Pools = []PoolConfig{
+        {Name: "api", Algorithm: "round-robin", Backends: []BackendConfig{{URL: "http://api.internal", Weight: 1}}},
+        {Name: "api", Algorithm: "round-robin"},
+        {Name: DefaultPoolName, Algorithm: "round-robin", Backends: []BackendConfig{{URL: "http://x.internal", Weight: 1}}},
+    }
+    cfg.Routing.Routes = []RouteConfig{
+        {Pool: "api", PathRegex: "(unclosed"},
+        {Pool: "missing", PathPrefix: "api", Headers: []HeaderMatchConfig{{Value: "1", Regex: "1"}}},
+    }
+    cfg.Routing.DefaultPool = "nowhere"
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    expected := []string{
+        "routing.pools[1].name",
+        "routing.pools[1].backends",
+        "routing.pools[2].name",
+        "routing.routes[0].pathRegex",
+        "routing.routes[1].pool",
+        "routing.routes[1].pathPrefix",
+        "routing.routes[1].headers[0].name",
+        "routing.routes[1].headers[0]",
+        "routing.defaultPool",
+    }
+    for _, path := range expected {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateRoutingWithoutDefaultPool verifies loadBalance may be empty when pools exist
+func TestValidateRoutingWithoutDefaultPool(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Backends = nil
+    cfg.Routing.Pools = []PoolConfig{
+        {Name: "api", Algorithm: "round-robin", Backends: []BackendConfig{{URL: "http://api.internal", Weight: 1}}},
+    }
+    cfg.Routing.DefaultPool = "api"
+
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected valid config, got %v", err)
+    }
+
+    cfg.Routing.DefaultPool = DefaultPoolName
+    if paths := fieldPaths(t, cfg.Validate(testAlgorithms)); !paths["routing.defaultPool"] {
+        t.Errorf("Expected default pool error without loadBalance backends, got %v", paths)
+    }
+}
+
+// TestValidateRoutingPoolsOnly verifies a file defining only routing pools
+// loads with an empty default pool and passes validation
+func TestValidateRoutingPoolsOnly(t *testing.T) {
+    path := writeConfigFile(t, "config.yaml", `
+routing:
+  pools:
+    - name: api
+      algorithm: round-robin
+      backends:
+        - url: http://api.internal
+  routes:
+    - pathPrefix: /api/
+      pool: api
+`)
+    cfg, err := Load(path)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if cfg.Routing.DefaultPool != "" {
+        t.Errorf("Expected empty default pool without loadBalance backends, got %q", cfg.Routing.DefaultPool)
+    }
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Errorf("Expected valid config, got %v", err)
+    }
+}
+
+// TestValidateRewriteAndRedirect verifies rewrite and redirect options are checked
+func TestValidateRewriteAndRedirect(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Routing.Routes = []RouteConfig{
+        {Pool: DefaultPoolName, Rewrite: RewriteConfig{Path: "users", StripPrefix: "api"}},
+        {Redirect: RedirectConfig{URL: "https://example.com", HTTPS: true, Status: 303}},
+        {Redirect: RedirectConfig{HTTPS: true}, Rewrite: RewriteConfig{Host: "a.internal"}},
+        {Redirect: RedirectConfig{HTTPS: true}},
+    }
+
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    expected := []string{
+        "routing.routes[0].rewrite.path",
+        "routing.routes[0].rewrite.stripPrefix",
+        "routing.routes[1].redirect",
+        "routing.routes[1].redirect.status",
+        "routing.routes[2].rewrite",
+    }
+    for _, path := range expected {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+    if len(paths) != len(expected) {
+        t.Errorf("Expected %d distinct paths, got %d: %v", len(expected), len(paths), paths)
+    }
+}
+
+// TestValidateRetry verifies retry settings are checked only when enabled
+func TestValidateRetry(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Retry = RetryConfig{MaxAttempts: 0, RetryOn: []int{42}}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled retries to be skipped, got %v", err)
+    }
+
+    cfg.LoadBalance.Retry.Enabled = true
+    cfg.LoadBalance.Retry.InitialBackoff = time.Second
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"loadBalance.retry.maxAttempts", "loadBalance.retry.maxBackoff", "loadBalance.retry.retryOn[0]"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateCircuitBreaker verifies breaker thresholds are checked only when enabled
+func TestValidateCircuitBreaker(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.CircuitBreaker = CircuitBreakerConfig{ErrorRate: 2}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled circuit breaker to be skipped, got %v", err)
+    }
+
+    cfg.LoadBalance.CircuitBreaker = CircuitBreakerConfig{Enabled: true, ErrorRate: 2}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"loadBalance.circuitBreaker.errorRate", "loadBalance.circuitBreaker.window", "loadBalance.circuitBreaker.openDuration", "loadBalance.circuitBreaker.halfOpenProbes"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateOutlierDetection verifies detection settings are checked only when enabled
+func TestValidateOutlierDetection(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.OutlierDetection = OutlierDetectionConfig{MaxEjectionPercent: 200}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled outlier detection to be skipped, got %v", err)
+    }
+
+    cfg.LoadBalance.OutlierDetection = DefaultOutlierDetectionConfig()
+    cfg.LoadBalance.OutlierDetection.Enabled = true
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected default outlier detection to be valid, got %v", err)
+    }
+
+    cfg.LoadBalance.OutlierDetection.LatencyDeviation = 0.5
+    cfg.LoadBalance.OutlierDetection.MinHosts = 1
+    cfg.LoadBalance.OutlierDetection.MaxEjectionTime = time.Second
+    cfg.LoadBalance.OutlierDetection.MaxEjectionPercent = 0
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"loadBalance.outlierDetection.latencyDeviation", "loadBalance.outlierDetection.minHosts", "loadBalance.outlierDetection.maxEjectionTime", "loadBalance.outlierDetection.maxEjectionPercent"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateHealthChecks verifies check types, thresholds and expectations
+func TestValidateHealthChecks(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Health.Type = "icmp"
+    cfg.Health.Rise = 0
+    cfg.Health.Jitter = 1.5
+    cfg.LoadBalance.Backends[0].HealthPath = "ready"
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"health.type", "health.rise", "health.jitter", "loadBalance.backends[0].healthPath"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+
+    cfg = validTestConfig()
+    cfg.Health.ExpectedStatus = []string{"200-299", "404-400", "abc"}
+    cfg.Health.BodyRegex = "("
+    paths = fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"health.expectedStatus", "health.bodyRegex"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+
+    cfg = validTestConfig()
+    cfg.Health.Type = HealthCheckTCP
+    cfg.Health.Path = ""
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Errorf("Expected TCP checks to ignore the HTTP path, got %v", err)
+    }
+}
+
+// TestValidateHashAndSticky verifies hash keys are checked for consistent-hash pools
+// and sticky cookies when sticky sessions are enabled
+func TestValidateHashAndSticky(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Hash = HashConfig{Key: "header"}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected hash settings of other algorithms to be ignored, got %v", err)
+    }
+
+    cfg.LoadBalance.Algorithm = ConsistentHashAlgorithm
+    cfg.LoadBalance.Hash = HashConfig{Key: "header", Segment: -1}
+    cfg.LoadBalance.Sticky = StickyConfig{Enabled: true, Cookie: "bad name", TTL: -time.Second}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"loadBalance.hash.name", "loadBalance.hash.segment", "loadBalance.hash.virtualNodes", "loadBalance.sticky.cookie", "loadBalance.sticky.ttl"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateAlgorithmNames verifies algorithm names are matched ignoring case,
+// including for algorithm-specific settings, against the algorithms passed in
+func TestValidateAlgorithmNames(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Algorithm = "Consistent-Hash"
+    cfg.LoadBalance.Hash = HashConfig{Key: "header"}
+    cfg.LoadBalance.P2C = P2CConfig{}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    if paths["loadBalance.algorithm"] || !paths["loadBalance.hash.name"] {
+        t.Errorf("Expected hash settings of a mixed-case consistent-hash pool to be checked, got %v", paths)
+    }
+
+    cfg = validTestConfig()
+    cfg.LoadBalance.Algorithm = "P2C-EWMA"
+    cfg.LoadBalance.P2C = P2CConfig{}
+    if paths := fieldPaths(t, cfg.Validate(testAlgorithms)); !paths["loadBalance.p2c.decay"] {
+        t.Errorf("Expected p2c settings of a mixed-case p2c-ewma pool to be checked, got %v", paths)
+    }
+
+    if paths := fieldPaths(t, validTestConfig().Validate(algorithmSet{"least-connections"})); !paths["loadBalance.algorithm"] {
+        t.Errorf("Expected an algorithm missing from the set to be rejected, got %v", paths)
+    }
+}
+
+// TestValidateSlowStart verifies the ramp-up settings are checked when enabled
+func TestValidateSlowStart(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.SlowStart = SlowStartConfig{Window: -time.Second}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled slow start to be ignored, got %v", err)
+    }
+
+    cfg.LoadBalance.SlowStart = SlowStartConfig{Enabled: true, MinWeight: 1.5, Aggression: -1}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"loadBalance.slowStart.window", "loadBalance.slowStart.minWeight", "loadBalance.slowStart.aggression"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateMirror verifies an enabled mirror needs a usable shadow backend and limits
+func TestValidateMirror(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.LoadBalance.Mirror = MirrorConfig{Percent: -1}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected disabled mirror to be ignored, got %v", err)
+    }
+
+    cfg.LoadBalance.Mirror = DefaultMirrorConfig()
+    cfg.LoadBalance.Mirror.Enabled = true
+    cfg.LoadBalance.Mirror.URL = "http://shadow:8080"
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected valid mirror, got %v", err)
+    }
+
+    cfg.LoadBalance.Mirror = MirrorConfig{Enabled: true, URL: "ftp://shadow", Percent: 150, MaxBodyBytes: -1, Header: "X Bad"}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{
+        "loadBalance.mirror.url", "loadBalance.mirror.percent", "loadBalance.mirror.maxBodyBytes",
+        "loadBalance.mirror.header", "loadBalance.mirror.timeout", "loadBalance.mirror.maxInFlight",
+    } {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateWebSocket verifies upgraded connection limits reject negative values
+func TestValidateWebSocket(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.WebSocket = WebSocketConfig{IdleTimeout: -time.Second, MaxLifetime: -time.Second}
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{"websocket.idleTimeout", "websocket.maxLifetime", "websocket.closeTimeout"} {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateSplits verifies splits reference distinct pools and can be routed to
+func TestValidateSplits(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Routing.Pools = []PoolConfig{{Name: "canary", Algorithm: "round-robin", Backends: []BackendConfig{{URL: "http://canary:8080"}}}}
+    cfg.Routing.Splits = []SplitConfig{{Name: "web", Stable: DefaultPoolName, Canary: "canary", Percent: 10}}
+    cfg.Routing.Routes = []RouteConfig{{PathPrefix: "/", Pool: "web"}}
+    cfg.Routing.DefaultPool = "web"
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected valid split, got %v", err)
+    }
+
+    cfg.Routing.Splits = []SplitConfig{
+        {Name: "canary", Stable: "missing", Canary: "canary", Percent: 120,
+            Headers: []HeaderMatchConfig{{Value: "1"}},
+            Cookies: []HeaderMatchConfig{{Name: "beta", Regex: "("}},
+            Sticky:  SplitStickyConfig{Enabled: true, Cookie: "bad name"}},
+        {Name: "same", Stable: "canary", Canary: "canary"},
+    }
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{
+        "routing.splits[0].name", "routing.splits[0].stable", "routing.splits[0].percent",
+        "routing.splits[0].headers[0].name", "routing.splits[0].cookies[0].regex",
+        "routing.splits[0].sticky.cookie", "routing.splits[1].canary",
+        "routing.routes[0].pool", "routing.defaultPool",
+    } {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
+
+// TestValidateGRPC verifies h2c settings and gRPC route matches
+func TestValidateGRPC(t *testing.T) {
+    cfg := validTestConfig()
+    cfg.Server.H2C = true
+    cfg.LoadBalance.Transport.H2C = true
+    cfg.Routing.Routes = []RouteConfig{{Pool: DefaultPoolName, GRPC: GRPCMatchConfig{Service: "orders.v1.Orders", Method: "Get"}}}
+    if err := cfg.Validate(testAlgorithms); err != nil {
+        t.Fatalf("Expected valid gRPC configuration, got %v", err)
+    }
+
+    cfg.Server.TLSCertFile, cfg.Server.TLSKeyFile = "cert.pem", "key.pem"
+    cfg.LoadBalance.Transport.HTTP2 = false
+    cfg.Routing.Routes = []RouteConfig{
+        {Pool: DefaultPoolName, GRPC: GRPCMatchConfig{Method: "Get"}},
+        {Pool: DefaultPoolName, GRPC: GRPCMatchConfig{Service: "/orders.v1.Orders", Method: "Get/"}},
+    }
+    paths := fieldPaths(t, cfg.Validate(testAlgorithms))
+    for _, path := range []string{
+        "server.h2c", "loadBalance.transport.h2c",
+        "routing.routes[0].grpc.service", "routing.routes[1].grpc.service", "routing.routes[1].grpc.method",
+    } {
+        if !paths[path] {
+            t.Errorf("Expected error for %s, got %v", path, paths)
+        }
+    }
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+	"os"
+	"time"
+)
+
+// Watch polls a configuration file and calls onChange when it is modified
+// Polling follows symlinks, so atomic symlink swaps used by Kubernetes
+// ConfigMap volumes are detected as well as in-place edits
+// Blocks until the context is cancelled
+// Time Complexity: O(1) per poll - single stat call
+// Space Complexity: O(1) - stores last observed file state
+func Watch(ctx context.Context, path string, interval time.Duration, onChange func()) {
+    ticker := time.NewTicker(interval)
+    defer ticker.Stop()
+
+    last, _ := os.Stat(path)
+
+    for {
+        select {
+        case <-ticker.C:
+            current, err := os.Stat(path)
+            if err != nil {
+                // File may be mid-replacement; keep the last state and retry
+                continue
+            }
+            if last == nil || current.ModTime() != last.ModTime() || current.Size() != last.Size() {
+                last = current
+                onChange()
+            }
+        case <-ctx.Done():
+            return
+        }
+    }
+}
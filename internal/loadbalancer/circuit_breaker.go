@@ -0,0 +1,234 @@
+package loadbalancer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// BreakerState is the state of a circuit breaker
+type BreakerState int
+
+const (
+    BreakerClosed   BreakerState = iota // Requests flow normally, failures are counted
+    BreakerOpen                         // Backend is out of rotation until the open duration passes
+    BreakerHalfOpen                     // A limited number of probe requests test the backend
+)
+
+// String returns the lowercase state name used in metrics and logs
+func (s BreakerState) String() string {
+    switch s {
+    case BreakerClosed:
+        return "closed"
+    case BreakerOpen:
+        return "open"
+    case BreakerHalfOpen:
+        return "half-open"
+    default:
+        return "unknown"
+    }
+}
+
+// breakerBuckets is the number of buckets the rolling error window is split into
+const breakerBuckets = 10
+
+// breakerBucket counts outcomes within one slice of the rolling window
+type breakerBucket struct {
+    slot      int64 // Window slice this bucket currently holds
+    successes int
+    failures  int
+}
+
+// CircuitBreaker tracks request outcomes for one backend and decides whether
+// the backend may receive traffic
+// Closed breakers trip open on consecutive failures or on the error rate over a
+// rolling window; after the open duration a limited number of half-open probes
+// decide between closing again and reopening
+type CircuitBreaker struct {
+    config   config.CircuitBreakerConfig
+    onChange func(from, to BreakerState) // Called outside the lock on every transition
+    now      func() time.Time            // Clock, replaced in tests
+
+    mutex               sync.Mutex
+    state               BreakerState
+    consecutiveFailures int
+    buckets             [breakerBuckets]breakerBucket
+    openedAt            time.Time
+    probes              int // Half-open requests in flight
+    probeSuccesses      int // Successful half-open requests since entering half-open
+}
+
+// NewCircuitBreaker creates a closed breaker for cfg
+// onChange may be nil; it is used for metrics and log events
+// Time Complexity: O(1) - struct initialisation
+// Space Complexity: O(1) - fixed size window
+func NewCircuitBreaker(cfg config.CircuitBreakerConfig, onChange func(from, to BreakerState)) *CircuitBreaker {
+    return &CircuitBreaker{
+        config:   cfg,
+        onChange: onChange,
+        now:      time.Now,
+    }
+}
+
+// State returns the current breaker state
+// Time Complexity: O(1) - locked read
+// Space Complexity: O(1) - no allocations
+func (cb *CircuitBreaker) State() BreakerState {
+    cb.mutex.Lock()
+    defer cb.mutex.Unlock()
+    return cb.state
+}
+
+// Ready reports whether the backend may be selected for a new request
+// An open breaker whose open duration has passed moves to half-open here,
+// and half-open breakers only accept requests while probe slots are free
+// Ready reserves nothing: the request sent after selection claims its probe
+// slot through Acquire, which may still refuse it
+// Time Complexity: O(1) - state check under lock
+// Space Complexity: O(1) - no allocations
+func (cb *CircuitBreaker) Ready() bool {
+    cb.mutex.Lock()
+    from := cb.state
+    cb.advance()
+    ready := cb.state == BreakerClosed || (cb.state == BreakerHalfOpen && cb.probes < cb.config.HalfOpenProbes)
+    to := cb.state
+    cb.mutex.Unlock()
+
+    cb.notify(from, to)
+    return ready
+}
+
+// Acquire admits a request to the backend and returns the function that
+// reports its outcome; failed should be true for transport errors and 5xx responses
+// While half-open the probe slot is checked and claimed in one critical
+// section, so concurrent requests never exceed HalfOpenProbes. Returns false,
+// and no report function, when the breaker is open or every probe slot is taken
+// Time Complexity: O(1) - counter updates under lock
+// Space Complexity: O(1) - single closure
+func (cb *CircuitBreaker) Acquire() (func(failed bool), bool) {
+    cb.mutex.Lock()
+    from := cb.state
+    cb.advance()
+    probe := cb.state == BreakerHalfOpen
+    admitted := cb.state == BreakerClosed || (probe && cb.probes < cb.config.HalfOpenProbes)
+    if admitted && probe {
+        cb.probes++
+    }
+    to := cb.state
+    cb.mutex.Unlock()
+
+    cb.notify(from, to)
+    if !admitted {
+        return nil, false
+    }
+
+    return func(failed bool) {
+        cb.mutex.Lock()
+        from := cb.state
+        if probe {
+            cb.recordProbe(failed)
+        } else if cb.state == BreakerClosed {
+            cb.record(failed)
+        }
+        to := cb.state
+        cb.mutex.Unlock()
+
+        cb.notify(from, to)
+    }, true
+}
+
+// advance moves an open breaker to half-open once its open duration has passed
+// Caller holds the lock
+func (cb *CircuitBreaker) advance() {
+    if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.config.OpenDuration {
+        cb.transition(BreakerHalfOpen)
+    }
+}
+
+// recordProbe applies a half-open probe result, caller holds the lock
+// A failed probe reopens the breaker; enough successes close it
+func (cb *CircuitBreaker) recordProbe(failed bool) {
+    cb.probes--
+    if cb.state != BreakerHalfOpen {
+        return
+    }
+    if failed {
+        cb.transition(BreakerOpen)
+        return
+    }
+    cb.probeSuccesses++
+    if cb.probeSuccesses >= cb.config.HalfOpenProbes {
+        cb.transition(BreakerClosed)
+    }
+}
+
+// record applies a closed-state result and trips the breaker when a threshold is hit
+// Caller holds the lock
+// Time Complexity: O(b) where b is number of window buckets
+// Space Complexity: O(1) - no allocations
+func (cb *CircuitBreaker) record(failed bool) {
+    if failed {
+        cb.consecutiveFailures++
+    } else {
+        cb.consecutiveFailures = 0
+    }
+
+    if cb.config.ConsecutiveFailures > 0 && cb.consecutiveFailures >= cb.config.ConsecutiveFailures {
+        cb.transition(BreakerOpen)
+        return
+    }
+    if cb.config.ErrorRate <= 0 || cb.config.Window <= 0 {
+        return
+    }
+
+    // Place the outcome in the bucket for the current slice of the window
+    width := int64(cb.config.Window / breakerBuckets)
+    if width <= 0 {
+        width = 1
+    }
+    slot := cb.now().UnixNano() / width
+    bucket := &cb.buckets[slot%breakerBuckets]
+    if bucket.slot != slot {
+        *bucket = breakerBucket{slot: slot}
+    }
+    if failed {
+        bucket.failures++
+    } else {
+        bucket.successes++
+    }
+
+    // Sum buckets still inside the window
+    var successes, failures int
+    for _, b := range cb.buckets {
+        if b.slot > slot-breakerBuckets {
+            successes += b.successes
+            failures += b.failures
+        }
+    }
+    total := successes + failures
+    if total >= cb.config.MinRequests && float64(failures)/float64(total) >= cb.config.ErrorRate {
+        cb.transition(BreakerOpen)
+    }
+}
+
+// transition moves to state and resets the counters it depends on
+// Caller holds the lock
+func (cb *CircuitBreaker) transition(state BreakerState) {
+    cb.state = state
+    cb.consecutiveFailures = 0
+    cb.probeSuccesses = 0
+    switch state {
+    case BreakerOpen:
+        cb.openedAt = cb.now()
+    case BreakerClosed:
+        cb.buckets = [breakerBuckets]breakerBucket{}
+    }
+}
+
+// notify reports a transition to the change callback, if any
+func (cb *CircuitBreaker) notify(from, to BreakerState) {
+    if from != to && cb.onChange != nil {
+        cb.onChange(from, to)
+    }
+}
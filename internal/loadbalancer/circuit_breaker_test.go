@@ -0,0 +1,189 @@
+package loadbalancer
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newTestBreaker returns a breaker driven by a manually advanced clock
+func newTestBreaker(cfg config.CircuitBreakerConfig) (*CircuitBreaker, *time.Time, *[]BreakerState) {
+    now := time.Unix(1000, 0)
+    var transitions []BreakerState
+    cb := NewCircuitBreaker(cfg, func(from, to BreakerState) {
+        transitions = append(transitions, to)
+    })
+    cb.now = func() time.Time { return now }
+    return cb, &now, &transitions
+}
+
+// acquire admits a request through cb, failing the test when it is refused
+func acquire(t *testing.T, cb *CircuitBreaker) func(failed bool) {
+    t.Helper()
+    report, ok := cb.Acquire()
+    if !ok {
+        t.Fatalf("Expected the breaker to admit a request in state %v", cb.State())
+    }
+    return report
+}
+
+// TestCircuitBreakerConsecutiveFailures verifies the breaker opens after the
+// configured run of failures and that a success resets the run
+func TestCircuitBreakerConsecutiveFailures(t *testing.T) {
+    cfg := config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 3, OpenDuration: time.Second, HalfOpenProbes: 1}
+    cb, _, _ := newTestBreaker(cfg)
+
+    acquire(t, cb)(true)
+    acquire(t, cb)(true)
+    acquire(t, cb)(false)
+    acquire(t, cb)(true)
+    acquire(t, cb)(true)
+    if !cb.Ready() {
+        t.Fatal("Expected breaker to stay closed after interrupted failures")
+    }
+
+    acquire(t, cb)(true)
+    if cb.Ready() || cb.State() != BreakerOpen {
+        t.Errorf("Expected breaker to open after 3 consecutive failures, got %v", cb.State())
+    }
+}
+
+// TestCircuitBreakerErrorRate verifies the breaker opens on the rolling error
+// rate once the minimum request count is reached, and forgets old outcomes
+func TestCircuitBreakerErrorRate(t *testing.T) {
+    cfg := config.CircuitBreakerConfig{Enabled: true, ErrorRate: 0.5, MinRequests: 4, Window: 10 * time.Second, OpenDuration: time.Second, HalfOpenProbes: 1}
+    cb, now, _ := newTestBreaker(cfg)
+
+    acquire(t, cb)(true)
+    acquire(t, cb)(true)
+    acquire(t, cb)(true)
+    if cb.State() != BreakerClosed {
+        t.Fatal("Expected breaker to stay closed below the minimum request count")
+    }
+
+    // Failures older than the window no longer count
+    *now = now.Add(11 * time.Second)
+    acquire(t, cb)(false)
+    acquire(t, cb)(false)
+    acquire(t, cb)(true)
+    if cb.State() != BreakerClosed {
+        t.Fatal("Expected expired failures to be dropped from the window")
+    }
+
+    acquire(t, cb)(true)
+    if cb.State() != BreakerOpen {
+        t.Errorf("Expected breaker to open at 50%% errors, got %v", cb.State())
+    }
+}
+
+// TestCircuitBreakerHalfOpen verifies the open duration, the probe limit and
+// that probes close or reopen the breaker
+func TestCircuitBreakerHalfOpen(t *testing.T) {
+    cfg := config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, OpenDuration: 5 * time.Second, HalfOpenProbes: 2}
+    cb, now, transitions := newTestBreaker(cfg)
+
+    acquire(t, cb)(true)
+    *now = now.Add(4 * time.Second)
+    if cb.Ready() {
+        t.Fatal("Expected breaker to stay open before the open duration passed")
+    }
+
+    *now = now.Add(time.Second)
+    if !cb.Ready() || cb.State() != BreakerHalfOpen {
+        t.Fatalf("Expected breaker to be half-open, got %v", cb.State())
+    }
+
+    first := acquire(t, cb)
+    second := acquire(t, cb)
+    if cb.Ready() {
+        t.Error("Expected no further requests while all probe slots are in use")
+    }
+    if _, ok := cb.Acquire(); ok {
+        t.Error("Expected Acquire to refuse a request while all probe slots are in use")
+    }
+    first(false)
+    second(true)
+    if cb.State() != BreakerOpen {
+        t.Fatalf("Expected failed probe to reopen the breaker, got %v", cb.State())
+    }
+
+    *now = now.Add(5 * time.Second)
+    cb.Ready()
+    acquire(t, cb)(false)
+    acquire(t, cb)(false)
+    if cb.State() != BreakerClosed {
+        t.Fatalf("Expected successful probes to close the breaker, got %v", cb.State())
+    }
+
+    expected := []BreakerState{BreakerOpen, BreakerHalfOpen, BreakerOpen, BreakerHalfOpen, BreakerClosed}
+    if len(*transitions) != len(expected) {
+        t.Fatalf("Expected transitions %v, got %v", expected, *transitions)
+    }
+    for i, state := range expected {
+        if (*transitions)[i] != state {
+            t.Errorf("Transition %d: expected %v, got %v", i, state, (*transitions)[i])
+        }
+    }
+}
+
+// TestCircuitBreakerConcurrentProbes verifies concurrent requests that all saw
+// a free probe slot never admit more than HalfOpenProbes probes
+func TestCircuitBreakerConcurrentProbes(t *testing.T) {
+    cfg := config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, OpenDuration: 10 * time.Millisecond, HalfOpenProbes: 3}
+    cb := NewCircuitBreaker(cfg, nil)
+    acquire(t, cb)(true)
+    time.Sleep(cfg.OpenDuration)
+
+    const requests = 64
+    var admitted atomic.Int32
+    var wg sync.WaitGroup
+    start := make(chan struct{})
+    for i := 0; i < requests; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            <-start
+            if cb.Ready() {
+                if _, ok := cb.Acquire(); ok {
+                    admitted.Add(1)
+                }
+            }
+        }()
+    }
+    close(start)
+    wg.Wait()
+
+    if n := admitted.Load(); n != int32(cfg.HalfOpenProbes) {
+        t.Errorf("Expected exactly %d probes admitted, got %d", cfg.HalfOpenProbes, n)
+    }
+}
+
+// TestBalancersSkipOpenBreakers verifies every algorithm leaves backends with
+// open breakers out of selection
+func TestBalancersSkipOpenBreakers(t *testing.T) {
+    options := DefaultOptions()
+    options.CircuitBreaker = config.CircuitBreakerConfig{Enabled: true, ConsecutiveFailures: 1, OpenDuration: time.Minute, HalfOpenProbes: 1}
+    backends := []config.BackendConfig{{URL: "http://a", Weight: 1}, {URL: "http://b", Weight: 1}}
+
+    for _, algorithm := range []string{"round-robin", "least-connections", "weighted-round-robin"} {
+        lb, err := NewLoadBalancerWithOptions(algorithm, backends, options)
+        if err != nil {
+            t.Fatalf("%s: unexpected error: %v", algorithm, err)
+        }
+        tripped := lb.GetBackends()[0]
+        acquire(t, tripped.CircuitBreaker())(true)
+
+        for i := 0; i < 4; i++ {
+            backend, err := lb.SelectBackend(nil)
+            if err != nil {
+                t.Fatalf("%s: unexpected error: %v", algorithm, err)
+            }
+            if backend == tripped {
+                t.Errorf("%s: expected backend with open breaker to be skipped", algorithm)
+            }
+        }
+    }
+}
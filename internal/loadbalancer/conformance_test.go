@@ -0,0 +1,19 @@
+package loadbalancer_test
+
+import (
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer/lbtest"
+)
+
+// TestConformance runs the conformance suite against every registered algorithm
+func TestConformance(t *testing.T) {
+    for _, algorithm := range loadbalancer.GetSupportedAlgorithms() {
+        t.Run(algorithm, func(t *testing.T) {
+            lbtest.Run(t, func(backends []loadbalancer.Backend) (loadbalancer.LoadBalancer, error) {
+                return loadbalancer.New(algorithm, backends, loadbalancer.DefaultOptions())
+            })
+        })
+    }
+}
@@ -0,0 +1,237 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// ringPoint is one virtual node on the hash ring
+type ringPoint struct {
+    hash    uint64
+    backend int // Index into backends
+}
+
+// ConsistentHashBalancer maps requests to backends with a hash ring
+// Each backend owns VirtualNodes points per unit of weight, so adding or losing
+// a backend only remaps the keys next to its points. Unavailable backends are
+// skipped by walking clockwise to the next point, leaving other keys in place
+// Time Complexity: O(log v) per selection where v is number of ring points
+// Space Complexity: O(v) for the ring
+type ConsistentHashBalancer struct {
+    backends []Backend         // List of all configured backends
+    ring     []ringPoint       // Virtual nodes sorted by hash
+    key      config.HashConfig // Which part of the request is hashed
+    mutex    sync.RWMutex      // Protects backends and ring
+    healthSubscribers
+}
+
+// NewConsistentHashBalancer creates a consistent-hash balancer keyed as cfg describes
+// Backend weights are read once here to size each backend's share of the ring
+// Time Complexity: O(v log v) where v is number of ring points
+// Space Complexity: O(v) for the ring
+func NewConsistentHashBalancer(backends []Backend, cfg config.HashConfig) *ConsistentHashBalancer {
+    if cfg.VirtualNodes <= 0 {
+        cfg.VirtualNodes = config.DefaultHashConfig().VirtualNodes
+    }
+    return &ConsistentHashBalancer{
+        backends: backends,
+        ring:     buildRing(backends, cfg.VirtualNodes),
+        key:      cfg,
+    }
+}
+
+// buildRing places weight * virtualNodes points for every backend and sorts them
+// Points are derived from the backend URL so every proxy builds the same ring
+// Time Complexity: O(v log v) where v is number of ring points
+// Space Complexity: O(v) for the ring
+func buildRing(backends []Backend, virtualNodes int) []ringPoint {
+    var ring []ringPoint
+    for i, backend := range backends {
+        points := virtualNodes * backend.GetWeight()
+        for n := 0; n < points; n++ {
+            ring = append(ring, ringPoint{hash: hashString(backend.GetURL() + "#" + strconv.Itoa(n)), backend: i})
+        }
+    }
+    sort.Slice(ring, func(i, j int) bool {
+        return ring[i].hash < ring[j].hash
+    })
+    return ring
+}
+
+// SelectBackend returns the owner of the first ring point at or after the
+// request key's hash, moving clockwise past unavailable backends
+// Time Complexity: O(log v) typical, O(v) when most backends are unavailable
+// Space Complexity: O(1) - no allocations beyond key extraction
+func (ch *ConsistentHashBalancer) SelectBackend(req *http.Request) (Backend, error) {
+    ch.mutex.RLock()
+    defer ch.mutex.RUnlock()
+
+    if len(ch.ring) == 0 {
+        return nil, errors.New("no backends available")
+    }
+
+    hash := hashString(ch.requestKey(req))
+    start := sort.Search(len(ch.ring), func(i int) bool {
+        return ch.ring[i].hash >= hash
+    })
+    // Backends in slow start own a key only when its draw, derived from the
+    // key's hash so a key keeps its backend, falls within their current share
+    draw := float64(hash>>11) / (1 << 53)
+    var fallback Backend
+    for n := 0; n < len(ch.ring); n++ {
+        backend := ch.backends[ch.ring[(start+n)%len(ch.ring)].backend]
+        if !backend.IsAvailable() {
+            continue
+        }
+        if admit(backend, draw) {
+            return backend, nil
+        }
+        if fallback == nil {
+            fallback = backend
+        }
+    }
+    if fallback != nil {
+        return fallback, nil
+    }
+    return nil, errors.New("no healthy backends available")
+}
+
+// requestKey extracts the configured key, falling back to the client IP
+// Time Complexity: O(k) where k is key length
+// Space Complexity: O(1) - substrings of the request
+func (ch *ConsistentHashBalancer) requestKey(req *http.Request) string {
+    if req == nil {
+        return ""
+    }
+
+    var key string
+    switch ch.key.Key {
+    case config.HashKeyHeader:
+        key = req.Header.Get(ch.key.Name)
+    case config.HashKeyCookie:
+        if cookie, err := req.Cookie(ch.key.Name); err == nil {
+            key = cookie.Value
+        }
+    case config.HashKeyPath:
+        key = pathSegment(req.URL.Path, ch.key.Segment)
+    }
+    if key != "" {
+        return key
+    }
+    return clientIP(req)
+}
+
+// pathSegment returns the n-th segment of path (1-based), or the whole path for 0
+func pathSegment(path string, n int) string {
+    if n == 0 {
+        return path
+    }
+    for _, segment := range strings.Split(strings.Trim(path, "/"), "/") {
+        if n--; n == 0 {
+            return segment
+        }
+    }
+    return ""
+}
+
+// clientIP returns the host part of the request's remote address
+func clientIP(req *http.Request) string {
+    if host, _, err := net.SplitHostPort(req.RemoteAddr); err == nil {
+        return host
+    }
+    return req.RemoteAddr
+}
+
+// hashString hashes s with FNV-1a and a finaliser that spreads similar inputs
+// Time Complexity: O(k) where k is length of s
+// Space Complexity: O(1) - no allocations
+func hashString(s string) uint64 {
+    h := fnv.New64a()
+    h.Write([]byte(s))
+    x := h.Sum64()
+    x ^= x >> 33
+    x *= 0xff51afd7ed558ccd
+    x ^= x >> 33
+    x *= 0xc4ceb9fe1a85ec53
+    x ^= x >> 33
+    return x
+}
+
+// UpdateBackendHealth updates health status for specified backend URL
+// Unhealthy backends keep their ring points and are skipped during selection,
+// so their keys return to them once they recover
+// Time Complexity: O(n) for linear search through backend list
+// Space Complexity: O(1) - no additional allocations
+func (ch *ConsistentHashBalancer) UpdateBackendHealth(url string, healthy bool) {
+    ch.mutex.RLock()
+    event, changed := setBackendHealth(ch.backends, url, healthy)
+    ch.mutex.RUnlock()
+
+    if changed {
+        ch.publish(event)
+    }
+}
+
+// GetBackends returns copy of all backends for health checking
+// Time Complexity: O(n) for slice copy
+// Space Complexity: O(n) for copied slice
+func (ch *ConsistentHashBalancer) GetBackends() []Backend {
+    ch.mutex.RLock()
+    defer ch.mutex.RUnlock()
+
+    backends := make([]Backend, len(ch.backends))
+    copy(backends, ch.backends)
+    return backends
+}
+
+// AddBackend adds backend to the pool
+// The ring is rebuilt; only keys landing on the new backend's points move
+// Time Complexity: O(n) for the duplicate check and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (ch *ConsistentHashBalancer) AddBackend(backend Backend) error {
+    ch.mutex.Lock()
+    defer ch.mutex.Unlock()
+
+    backends, err := withBackend(ch.backends, backend)
+    if err != nil {
+        return err
+    }
+    ch.backends = backends
+    ch.ring = buildRing(ch.backends, ch.key.VirtualNodes)
+    return nil
+}
+
+// RemoveBackend removes the backend with url from the pool at once
+// The ring is rebuilt; only the removed backend's keys move
+// Requests already sent to it are unaffected; use DrainBackend to wait for them
+// Time Complexity: O(n) for the search and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (ch *ConsistentHashBalancer) RemoveBackend(url string) error {
+    ch.mutex.Lock()
+    defer ch.mutex.Unlock()
+
+    backends, _, err := withoutBackend(ch.backends, url)
+    if err != nil {
+        return err
+    }
+    ch.backends = backends
+    ch.ring = buildRing(ch.backends, ch.key.VirtualNodes)
+    return nil
+}
+
+// DrainBackend stops selecting the backend with url and removes it once its
+// in-flight requests have finished or returns the context error if ctx ends first
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (ch *ConsistentHashBalancer) DrainBackend(ctx context.Context, url string) error {
+    return drainBackend(ctx, ch, url)
+}
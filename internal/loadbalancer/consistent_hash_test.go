@@ -0,0 +1,123 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newTestHashBalancer builds a consistent-hash balancer over n equally weighted backends
+func newTestHashBalancer(t *testing.T, cfg config.HashConfig, n int) *ConsistentHashBalancer {
+    t.Helper()
+    backends := make([]Backend, n)
+    for i := range backends {
+        backend, err := NewHTTPBackend(fmt.Sprintf("http://backend-%d", i), 1)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        backends[i] = backend
+    }
+    return NewConsistentHashBalancer(backends, cfg)
+}
+
+// keyedRequest returns a request whose client IP is key
+func keyedRequest(key string) *http.Request {
+    req := httptest.NewRequest("GET", "/", nil)
+    req.RemoteAddr = key + ":1234"
+    return req
+}
+
+// TestConsistentHashStableAndSpread verifies a key always maps to the same
+// backend and keys spread over every backend
+func TestConsistentHashStableAndSpread(t *testing.T) {
+    lb := newTestHashBalancer(t, config.DefaultHashConfig(), 4)
+
+    counts := map[Backend]int{}
+    for i := 0; i < 2000; i++ {
+        key := fmt.Sprintf("10.0.%d.%d", i/256, i%256)
+        first, _ := lb.SelectBackend(keyedRequest(key))
+        second, _ := lb.SelectBackend(keyedRequest(key))
+        if first != second {
+            t.Fatalf("Expected key %s to map to one backend", key)
+        }
+        counts[first]++
+    }
+    for _, backend := range lb.GetBackends() {
+        if counts[backend] < 300 || counts[backend] > 700 {
+            t.Errorf("Expected roughly 500 keys on %s, got %d", backend.GetURL(), counts[backend])
+        }
+    }
+}
+
+// TestConsistentHashMinimalRemap verifies only keys of a failed backend move
+func TestConsistentHashMinimalRemap(t *testing.T) {
+    lb := newTestHashBalancer(t, config.DefaultHashConfig(), 5)
+
+    before := map[string]Backend{}
+    for i := 0; i < 1000; i++ {
+        key := fmt.Sprintf("client-%d", i)
+        before[key], _ = lb.SelectBackend(keyedRequest(key))
+    }
+
+    failed := lb.GetBackends()[2]
+    lb.UpdateBackendHealth(failed.GetURL(), false)
+    for key, previous := range before {
+        backend, _ := lb.SelectBackend(keyedRequest(key))
+        if backend == failed {
+            t.Fatalf("Expected failed backend to be skipped for %s", key)
+        }
+        if previous != failed && backend != previous {
+            t.Errorf("Expected %s to stay on %s, moved to %s", key, previous.GetURL(), backend.GetURL())
+        }
+    }
+}
+
+// TestConsistentHashKeys verifies header, cookie and path keys, and the client IP fallback
+func TestConsistentHashKeys(t *testing.T) {
+    tests := []struct {
+        name  string
+        cfg   config.HashConfig
+        apply func(*http.Request, string)
+    }{
+        {"header", config.HashConfig{Key: config.HashKeyHeader, Name: "X-User"}, func(r *http.Request, key string) {
+            r.Header.Set("X-User", key)
+        }},
+        {"cookie", config.HashConfig{Key: config.HashKeyCookie, Name: "user"}, func(r *http.Request, key string) {
+            r.AddCookie(&http.Cookie{Name: "user", Value: key})
+        }},
+        {"path", config.HashConfig{Key: config.HashKeyPath, Segment: 2}, func(r *http.Request, key string) {
+            r.URL.Path = "/tenants/" + key + "/orders"
+        }},
+    }
+
+    for _, tt := range tests {
+        lb := newTestHashBalancer(t, tt.cfg, 8)
+        seen := map[Backend]bool{}
+        for i := 0; i < 50; i++ {
+            key := fmt.Sprintf("key-%d", i)
+            // Requests from different clients with the same key land together
+            a, b := keyedRequest("10.0.0.1"), keyedRequest("10.0.0.2")
+            tt.apply(a, key)
+            tt.apply(b, key)
+            first, _ := lb.SelectBackend(a)
+            second, _ := lb.SelectBackend(b)
+            if first != second {
+                t.Fatalf("%s: expected key %s to map to one backend", tt.name, key)
+            }
+            seen[first] = true
+        }
+        if len(seen) < 2 {
+            t.Errorf("%s: expected keys to spread over backends", tt.name)
+        }
+
+        // Without the key the client IP decides
+        first, _ := lb.SelectBackend(keyedRequest("192.168.1.1"))
+        second, _ := lb.SelectBackend(keyedRequest("192.168.1.1"))
+        if first != second {
+            t.Errorf("%s: expected client IP fallback to be stable", tt.name)
+        }
+    }
+}
@@ -0,0 +1,71 @@
+package loadbalancer
+
+import "sync"
+
+// HealthEvent reports a backend transitioning between healthy and unhealthy
+type HealthEvent struct {
+    Backend string // Backend URL
+    Healthy bool   // New health status
+}
+
+// healthSubscribers fans health events out to subscribers
+// Embedded by every balancer to implement LoadBalancer.Subscribe; the zero value is ready to use
+type healthSubscribers struct {
+    mutex       sync.RWMutex
+    next        int
+    subscribers map[int]func(HealthEvent)
+}
+
+// Subscribe registers fn to receive every health transition of the pool's backends
+// fn is called synchronously outside balancer locks, so it may query the balancer
+// Returns the function that removes the subscription
+// Time Complexity: O(1) - map insertion
+// Space Complexity: O(1) per subscriber
+func (h *healthSubscribers) Subscribe(fn func(HealthEvent)) func() {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    if h.subscribers == nil {
+        h.subscribers = make(map[int]func(HealthEvent))
+    }
+    id := h.next
+    h.next++
+    h.subscribers[id] = fn
+
+    return func() {
+        h.mutex.Lock()
+        defer h.mutex.Unlock()
+        delete(h.subscribers, id)
+    }
+}
+
+// publish delivers event to every current subscriber
+// Time Complexity: O(s) where s is number of subscribers
+// Space Complexity: O(s) for the subscriber snapshot
+func (h *healthSubscribers) publish(event HealthEvent) {
+    h.mutex.RLock()
+    subscribers := make([]func(HealthEvent), 0, len(h.subscribers))
+    for _, fn := range h.subscribers {
+        subscribers = append(subscribers, fn)
+    }
+    h.mutex.RUnlock()
+
+    for _, fn := range subscribers {
+        fn(event)
+    }
+}
+
+// setBackendHealth updates the health of the backend with url among backends
+// Returns the resulting event and whether the status actually changed
+// Time Complexity: O(n) for linear search through backend list
+// Space Complexity: O(1) - no allocations
+func setBackendHealth(backends []Backend, url string, healthy bool) (HealthEvent, bool) {
+    for _, backend := range backends {
+        if backend.GetURL() == url {
+            changed := backend.IsHealthy() != healthy
+            backend.SetHealthy(healthy)
+            return HealthEvent{Backend: url, Healthy: healthy}, changed
+        }
+    }
+    return HealthEvent{}, false
+}
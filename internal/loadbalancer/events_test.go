@@ -0,0 +1,84 @@
+package loadbalancer
+
+import (
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// testBackendConfigs returns n backend configurations with distinct URLs
+func testBackendConfigs(n int) []config.BackendConfig {
+    backends := make([]config.BackendConfig, n)
+    for i := range backends {
+        backends[i] = config.BackendConfig{URL: "http://backend-" + string(rune('a'+i)), Weight: i + 1}
+    }
+    return backends
+}
+
+// TestHealthSubscription verifies subscribers see transitions only, and stop
+// receiving events once unsubscribed
+func TestHealthSubscription(t *testing.T) {
+    for _, algorithm := range GetSupportedAlgorithms() {
+        lb, err := NewLoadBalancer(algorithm, testBackendConfigs(2))
+        if err != nil {
+            t.Fatalf("%s: unexpected error: %v", algorithm, err)
+        }
+
+        var events []HealthEvent
+        unsubscribe := lb.Subscribe(func(event HealthEvent) {
+            events = append(events, event)
+        })
+
+        lb.UpdateBackendHealth("http://backend-a", true) // already healthy
+        lb.UpdateBackendHealth("http://backend-a", false)
+        lb.UpdateBackendHealth("http://backend-a", false)
+        lb.UpdateBackendHealth("http://backend-a", true)
+        lb.UpdateBackendHealth("http://unknown", false)
+
+        expected := []HealthEvent{{Backend: "http://backend-a", Healthy: false}, {Backend: "http://backend-a", Healthy: true}}
+        if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+            t.Errorf("%s: expected events %v, got %v", algorithm, expected, events)
+        }
+
+        unsubscribe()
+        lb.UpdateBackendHealth("http://backend-b", false)
+        if len(events) != len(expected) {
+            t.Errorf("%s: expected no events after unsubscribe, got %v", algorithm, events[len(expected):])
+        }
+    }
+}
+
+// TestConcurrentHealthAndWeightUpdates exercises selection alongside health and
+// weight updates; run with -race to detect unsynchronised state
+func TestConcurrentHealthAndWeightUpdates(t *testing.T) {
+    for _, algorithm := range GetSupportedAlgorithms() {
+        lb, err := NewLoadBalancer(algorithm, testBackendConfigs(3))
+        if err != nil {
+            t.Fatalf("%s: unexpected error: %v", algorithm, err)
+        }
+        lb.Subscribe(func(HealthEvent) { lb.GetBackends() })
+        req := httptest.NewRequest("GET", "/", nil)
+
+        var wg sync.WaitGroup
+        for worker := 0; worker < 4; worker++ {
+            wg.Add(2)
+            go func() {
+                defer wg.Done()
+                for i := 0; i < 200; i++ {
+                    lb.SelectBackend(req)
+                }
+            }()
+            go func(worker int) {
+                defer wg.Done()
+                for i := 0; i < 200; i++ {
+                    backend := lb.GetBackends()[i%3]
+                    lb.UpdateBackendHealth(backend.GetURL(), (i+worker)%2 == 0)
+                    backend.SetWeight(i%5 + 1)
+                }
+            }(worker)
+        }
+        wg.Wait()
+    }
+}
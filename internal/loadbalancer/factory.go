@@ -2,7 +2,8 @@ package loadbalancer
 
 import (
 	"fmt"
-	"strings"
+
+	"gopkg.in/yaml.v3"
 
 	"github.com/WillKirkmanM/proxy/internal/config"
 )
@@ -15,8 +16,36 @@ const (
     RoundRobin         LoadBalancerType = "round-robin"
     LeastConnections   LoadBalancerType = "least-connections"
     WeightedRoundRobin LoadBalancerType = "weighted-round-robin"
+    ConsistentHash     LoadBalancerType = config.ConsistentHashAlgorithm
+    P2CEWMA            LoadBalancerType = config.P2CEWMAAlgorithm
 )
 
+// init registers the built-in algorithms
+// Algorithms are looked up by name, so adding one only requires a Register call
+func init() {
+    Register(string(RoundRobin), func(backends []Backend, _ Options) (LoadBalancer, error) {
+        return NewRoundRobinBalancer(backends), nil
+    })
+    Register(string(LeastConnections), func(backends []Backend, _ Options) (LoadBalancer, error) {
+        return NewLeastConnectionsBalancer(backends), nil
+    })
+    Register(string(WeightedRoundRobin), func(backends []Backend, _ Options) (LoadBalancer, error) {
+        return NewWeightedRoundRobinBalancer(backends), nil
+    })
+    Register(string(ConsistentHash), WithOptions(
+        func(o Options) config.HashConfig { return o.Hash },
+        func(backends []Backend, cfg config.HashConfig) (LoadBalancer, error) {
+            return NewConsistentHashBalancer(backends, cfg), nil
+        },
+    ))
+    Register(string(P2CEWMA), WithOptions(
+        func(o Options) config.P2CConfig { return o.P2C },
+        func(backends []Backend, cfg config.P2CConfig) (LoadBalancer, error) {
+            return NewP2CEWMABalancer(backends, cfg), nil
+        },
+    ))
+}
+
 // BackendConfig represents backend server configuration
 // Includes URL and optional weight for weighted algorithms
 type BackendConfig struct {
@@ -25,14 +54,57 @@ type BackendConfig struct {
 }
 
 // NewLoadBalancer creates load balancer instance using factory pattern
-// Supports multiple algorithms through strategy pattern implementation
+// Supports every algorithm in the registry through strategy pattern implementation
 // Factory pattern encapsulates creation logic and enables runtime algorithm selection
+// Backends share a transport with the default tuning
 // Time Complexity: O(n) where n is number of backends for initialisation
 // Space Complexity: O(n) for storing backend configurations
 func NewLoadBalancer(algorithm string, backendConfigs []config.BackendConfig) (LoadBalancer, error) {
+    return NewLoadBalancerWithOptions(algorithm, backendConfigs, DefaultOptions())
+}
+
+// breakerCallback binds a backend URL to the pool's breaker transition callback
+func breakerCallback(backendURL string, onChange func(string, BreakerState, BreakerState)) func(BreakerState, BreakerState) {
+    if onChange == nil {
+        return nil
+    }
+    return func(from, to BreakerState) {
+        onChange(backendURL, from, to)
+    }
+}
+
+// Options configures the backends created for a pool
+type Options struct {
+    Transport      config.TransportConfig      // Tuning of the transport shared by the pool's backends
+    CircuitBreaker config.CircuitBreakerConfig // Per-backend breaker settings, unused when disabled
+    Hash           config.HashConfig           // Request key of the consistent-hash algorithm
+    P2C            config.P2CConfig            // Latency decay of the p2c-ewma algorithm
+    SlowStart      config.SlowStartConfig      // Ramp-up of backends returning to rotation
+    Algorithm      *yaml.Node                  // Options block of the pool's algorithm, nil when absent
+
+    // OnBreakerStateChange is called with the backend URL on every breaker transition
+    OnBreakerStateChange func(backend string, from, to BreakerState)
+}
+
+// DefaultOptions returns options with the default transport and breakers disabled
+func DefaultOptions() Options {
+    return Options{Transport: config.DefaultTransportConfig(), Hash: config.DefaultHashConfig(), P2C: config.DefaultP2CConfig()}
+}
+
+// NewLoadBalancerWithOptions creates a load balancer whose backends share one
+// transport tuned by options, so a pool keeps a single connection pool, and
+// get their own circuit breaker and slow start when enabled
+// Time Complexity: O(n) where n is number of backends for initialisation
+// Space Complexity: O(n) for storing backend configurations
+func NewLoadBalancerWithOptions(algorithm string, backendConfigs []config.BackendConfig, options Options) (LoadBalancer, error) {
     if len(backendConfigs) == 0 {
         return nil, fmt.Errorf("no backends configured")
     }
+    if _, ok := lookup(algorithm); !ok {
+        return nil, fmt.Errorf("unsupported load balancing algorithm: %s", algorithm)
+    }
+
+    shared := NewTransport(options.Transport)
 
     // Parse backend configurations and create Backend instances
     backends := make([]Backend, len(backendConfigs))
@@ -42,34 +114,22 @@ func NewLoadBalancer(algorithm string, backendConfigs []config.BackendConfig) (L
             weight = 1 // Default weight for invalid values
         }
 
-        backend, err := NewHTTPBackend(cfg.URL, weight)
+        transport, err := backendTransport(shared, cfg.TLS)
+        if err != nil {
+            return nil, fmt.Errorf("failed to configure TLS for backend %s: %w", cfg.URL, err)
+        }
+
+        backend, err := NewHTTPBackendWithTransport(cfg.URL, weight, transport)
         if err != nil {
             return nil, fmt.Errorf("failed to create backend %s: %w", cfg.URL, err)
         }
+        if options.CircuitBreaker.Enabled {
+            backend.SetCircuitBreaker(NewCircuitBreaker(options.CircuitBreaker, breakerCallback(backend.GetURL(), options.OnBreakerStateChange)))
+        }
+        backend.SetSlowStart(options.SlowStart)
         backends[i] = backend
     }
 
-    // Create load balancer based on algorithm using strategy pattern
-    switch LoadBalancerType(strings.ToLower(algorithm)) {
-    case RoundRobin:
-        return NewRoundRobinBalancer(backends), nil
-    case LeastConnections:
-        return NewLeastConnectionsBalancer(backends), nil
-    case WeightedRoundRobin:
-        return NewWeightedRoundRobinBalancer(backends), nil
-    default:
-        return nil, fmt.Errorf("unsupported load balancing algorithm: %s", algorithm)
-    }
-}
-
-// GetSupportedAlgorithms returns list of supported load balancing algorithms
-// Used for configuration validation and documentation
-// Time Complexity: O(1) - returns static slice
-// Space Complexity: O(1) - static data
-func GetSupportedAlgorithms() []string {
-    return []string{
-        string(RoundRobin),
-        string(LeastConnections),
-        string(WeightedRoundRobin),
-    }
+    // Create load balancer through the constructor registered for the algorithm
+    return New(algorithm, backends, options)
 }
\ No newline at end of file
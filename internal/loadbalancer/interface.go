@@ -1,9 +1,14 @@
 package loadbalancer
 
 import (
+	"context"
 	"net/http"
+	"net/http/httputil"
 	"net/url"
 	"sync/atomic"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
 )
 
 // Backend represents a backend server interface
@@ -19,6 +24,15 @@ type Backend interface {
     DecrementConnections()                               // Decrements active connections
     GetWeight() int                                      // Returns backend weight for weighted algorithms
     SetWeight(int)                                       // Sets backend weight
+    GetTransport() http.RoundTripper                     // Returns transport used to reach the backend
+    IsAvailable() bool                                   // Reports whether the backend may be selected
+    CircuitBreaker() *CircuitBreaker                     // Returns the backend's breaker, nil when disabled
+    IsEjected() bool                                     // Reports whether outlier detection ejected the backend
+    SetEjected(bool)                                     // Ejects the backend or returns it to rotation
+    IsDraining() bool                                    // Reports whether the backend is being drained
+    SetDraining(bool)                                    // Stops or resumes new selections of the backend
+    SlowStartFactor() float64                            // Returns the share of traffic while warming up, 1 when warm
+    BeginSlowStart()                                     // Starts warming up when slow start is enabled
 }
 
 // LoadBalancer defines interface for load balancing algorithms
@@ -28,25 +42,43 @@ type LoadBalancer interface {
     SelectBackend(*http.Request) (Backend, error) // Selects backend for request
     UpdateBackendHealth(string, bool)             // Updates backend health status
     GetBackends() []Backend                       // Returns all backends for monitoring
+    Subscribe(func(HealthEvent)) func()           // Registers a health transition callback, returns unsubscribe
+    AddBackend(Backend) error                     // Adds a backend to rotation
+    RemoveBackend(string) error                   // Removes a backend immediately, in-flight requests finish
+    DrainBackend(context.Context, string) error   // Stops new selections, removes once in-flight requests finish
 }
 
 // HTTPBackend implements Backend interface for HTTP servers
 // Provides concrete implementation for proxying HTTP requests
 // Maintains health status, connection count, and weight for load balancing decisions
 type HTTPBackend struct {
-    url         *url.URL      // Parsed backend server URL
-    healthy     bool          // Current health status
-    client      *http.Client  // HTTP client for request forwarding
-    connections int64         // Active connection count (atomic for thread safety)
-    weight      int           // Backend weight for weighted load balancing
+    url         *url.URL               // Parsed backend server URL
+    healthy     atomic.Bool            // Current health status, written by health checks while requests read it
+    transport   http.RoundTripper      // Transport shared by reverse proxies and health checks
+    proxy       *httputil.ReverseProxy // Forwards requests served directly by the backend
+    breaker     *CircuitBreaker        // Takes the backend out of rotation on failures, nil when disabled
+    ejected     atomic.Bool            // Set while outlier detection keeps the backend out of rotation
+    draining    atomic.Bool            // Set while the backend is drained before removal
+    slowStart   config.SlowStartConfig // Ramp-up applied when the backend returns to rotation
+    warmingFrom atomic.Int64           // Unix nanoseconds slow start began, 0 when warm
+    connections int64                  // Active connection count (atomic for thread safety)
+    weight      atomic.Int64           // Backend weight for weighted load balancing
 }
 
 // NewHTTPBackend creates new HTTP backend with specified URL and weight
-// Initializes with healthy status and default HTTP client
+// Initializes with healthy status and the default HTTP transport
 // Default weight of 1 provides equal distribution for weighted algorithms
 // Time Complexity: O(1) - simple struct initialisation
 // Space Complexity: O(1) - fixed size backend structure
 func NewHTTPBackend(backendURL string, weight int) (*HTTPBackend, error) {
+    return NewHTTPBackendWithTransport(backendURL, weight, http.DefaultTransport)
+}
+
+// NewHTTPBackendWithTransport creates HTTP backend reached through a custom transport
+// Used for backends needing their own TLS settings such as client certificates
+// Time Complexity: O(1) - simple struct initialisation
+// Space Complexity: O(1) - fixed size backend structure
+func NewHTTPBackendWithTransport(backendURL string, weight int, transport http.RoundTripper) (*HTTPBackend, error) {
     url, err := url.Parse(backendURL)
     if err != nil {
         return nil, err
@@ -56,13 +88,21 @@ func NewHTTPBackend(backendURL string, weight int) (*HTTPBackend, error) {
         weight = 1 // Default weight for invalid values
     }
 
-    return &HTTPBackend{
+    proxy := httputil.NewSingleHostReverseProxy(url)
+    proxy.Transport = transport
+    proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+        http.Error(w, "Backend unavailable", http.StatusBadGateway)
+    }
+
+    backend := &HTTPBackend{
         url:         url,
-        healthy:     true,
-        client:      &http.Client{},
+        transport:   transport,
+        proxy:       proxy,
         connections: 0,
-        weight:      weight,
-    }, nil
+    }
+    backend.healthy.Store(true)
+    backend.weight.Store(int64(weight))
+    return backend, nil
 }
 
 // GetURL returns backend server URL string
@@ -75,18 +115,112 @@ func (b *HTTPBackend) GetURL() string {
 
 // IsHealthy returns current backend health status
 // Used by load balancer to determine routing eligibility
-// Time Complexity: O(1) - simple boolean access
+// Time Complexity: O(1) - atomic load
 // Space Complexity: O(1) - no allocations
 func (b *HTTPBackend) IsHealthy() bool {
-    return b.healthy
+    return b.healthy.Load()
 }
 
 // SetHealthy updates backend health status
 // Called by health check system to mark backends as up/down
-// Time Complexity: O(1) - simple boolean assignment
+// A backend recovering from unhealthy begins slow start
+// Time Complexity: O(1) - atomic swap
 // Space Complexity: O(1) - no allocations
 func (b *HTTPBackend) SetHealthy(healthy bool) {
-    b.healthy = healthy
+    if !b.healthy.Swap(healthy) && healthy {
+        b.BeginSlowStart()
+    }
+}
+
+// IsAvailable reports whether load balancers may select this backend
+// Requires a passing health check, no outlier ejection or draining and a
+// circuit breaker that admits traffic
+// Time Complexity: O(1) - health flags and breaker state check
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) IsAvailable() bool {
+    return b.IsHealthy() && !b.IsEjected() && !b.IsDraining() && (b.breaker == nil || b.breaker.Ready())
+}
+
+// IsEjected reports whether outlier detection currently ejects this backend
+// Time Complexity: O(1) - atomic load
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) IsEjected() bool {
+    return b.ejected.Load()
+}
+
+// SetEjected ejects the backend from rotation or returns it
+// Called by the pool's outlier detector; independent of active health checks
+// A backend returning from ejection begins slow start
+// Time Complexity: O(1) - atomic swap
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) SetEjected(ejected bool) {
+    if b.ejected.Swap(ejected) && !ejected {
+        b.BeginSlowStart()
+    }
+}
+
+// IsDraining reports whether the backend is being drained from its pool
+// Time Complexity: O(1) - atomic load
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) IsDraining() bool {
+    return b.draining.Load()
+}
+
+// SetDraining stops new selections of the backend while in-flight requests finish
+// Time Complexity: O(1) - atomic store
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) SetDraining(draining bool) {
+    b.draining.Store(draining)
+}
+
+// SetSlowStart configures the ramp-up applied when the backend returns to rotation
+// Must be called before the backend receives traffic
+// Time Complexity: O(1) - struct assignment
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) SetSlowStart(cfg config.SlowStartConfig) {
+    b.slowStart = cfg
+}
+
+// BeginSlowStart starts the backend's warm-up window now
+// Does nothing when slow start is disabled
+// Time Complexity: O(1) - atomic store
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) BeginSlowStart() {
+    if b.slowStart.Enabled {
+        b.warmingFrom.Store(time.Now().UnixNano())
+    }
+}
+
+// SlowStartFactor returns the fraction of its share the backend currently
+// receives, ramping from MinWeight to 1 over the slow start window
+// Time Complexity: O(1) - atomic load and arithmetic
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) SlowStartFactor() float64 {
+    from := b.warmingFrom.Load()
+    if from == 0 {
+        return 1
+    }
+    factor := slowStartFactor(b.slowStart, time.Since(time.Unix(0, from)))
+    if factor >= 1 {
+        b.warmingFrom.CompareAndSwap(from, 0)
+    }
+    return factor
+}
+
+// CircuitBreaker returns the backend's circuit breaker, nil when disabled
+// The proxy path reports request outcomes to it
+// Time Complexity: O(1) - returns stored pointer
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) CircuitBreaker() *CircuitBreaker {
+    return b.breaker
+}
+
+// SetCircuitBreaker attaches a circuit breaker to the backend
+// Must be called before the backend receives traffic
+// Time Complexity: O(1) - pointer assignment
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) SetCircuitBreaker(breaker *CircuitBreaker) {
+    b.breaker = breaker
 }
 
 // GetConnections returns current active connection count
@@ -119,66 +253,40 @@ func (b *HTTPBackend) DecrementConnections() {
 // GetWeight returns backend weight for weighted load balancing
 // Higher weights receive proportionally more traffic
 // Used by weighted round-robin and weighted least connections algorithms
-// Time Complexity: O(1) - simple integer access
+// Time Complexity: O(1) - atomic load
 // Space Complexity: O(1) - no allocations
 func (b *HTTPBackend) GetWeight() int {
-    return b.weight
+    return int(b.weight.Load())
 }
 
 // SetWeight updates backend weight for weighted load balancing
 // Allows dynamic weight adjustment for traffic shaping
 // Weight changes take effect on next load balancing decision
-// Time Complexity: O(1) - simple integer assignment
+// Time Complexity: O(1) - atomic store
 // Space Complexity: O(1) - no allocations
 func (b *HTTPBackend) SetWeight(weight int) {
     if weight <= 0 {
         weight = 1 // Ensure positive weight
     }
-    b.weight = weight
+    b.weight.Store(int64(weight))
+}
+
+// GetTransport returns the transport used to reach this backend
+// Shared by the reverse proxy and health checks so TLS settings apply to both
+// Time Complexity: O(1) - returns stored transport
+// Space Complexity: O(1) - no allocations
+func (b *HTTPBackend) GetTransport() http.RoundTripper {
+    return b.transport
 }
 
 // ServeHTTP forwards request to backend server with connection tracking
-// Implements reverse proxy functionality with error handling
-// Updates request URL to point to backend server and tracks connections
+// Uses a reverse proxy over the backend's transport so pooled connections are reused
 // Time Complexity: O(1) for setup, O(n) for request/response transfer
-// Space Complexity: O(n) for request/response buffering
+// Space Complexity: O(1) - response is streamed with pooled buffers
 func (b *HTTPBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
     // Increment connection count for load balancing
     b.IncrementConnections()
     defer b.DecrementConnections()
 
-    // Create new request with backend URL
-    r.URL.Scheme = b.url.Scheme
-    r.URL.Host = b.url.Host
-    r.Host = b.url.Host
-
-    // Forward request to backend
-    resp, err := b.client.Do(r)
-    if err != nil {
-        http.Error(w, "Backend unavailable", http.StatusBadGateway)
-        return
-    }
-    defer resp.Body.Close()
-
-    // Copy response headers
-    for key, values := range resp.Header {
-        for _, value := range values {
-            w.Header().Add(key, value)
-        }
-    }
-
-    // Set status code and copy body
-    w.WriteHeader(resp.StatusCode)
-    
-    // Stream response body to client
-    buffer := make([]byte, 32*1024) // 32KB buffer for streaming
-    for {
-        n, err := resp.Body.Read(buffer)
-        if n > 0 {
-            w.Write(buffer[:n])
-        }
-        if err != nil {
-            break
-        }
-    }
+    b.proxy.ServeHTTP(w, r)
 }
\ No newline at end of file
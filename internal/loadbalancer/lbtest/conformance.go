@@ -0,0 +1,350 @@
+// Package lbtest provides a conformance suite for load balancer implementations
+// Any LoadBalancer can be checked with Run, in the spirit of testing/fstest
+package lbtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// Factory builds the balancer under test over the given backends
+type Factory func(backends []loadbalancer.Backend) (loadbalancer.LoadBalancer, error)
+
+// Run checks the properties every load balancer must have
+// Selection stays within the pool, skips unavailable backends, spreads equally
+// weighted traffic over every backend, publishes health transitions, follows
+// backends being added, removed and drained, and is safe for concurrent use;
+// run the suite with -race for the last property
+func Run(t *testing.T, factory Factory) {
+    t.Run("SelectsPoolMembers", func(t *testing.T) { testSelectsPoolMembers(t, factory) })
+    t.Run("SkipsUnhealthy", func(t *testing.T) { testSkipsUnhealthy(t, factory) })
+    t.Run("SkipsEjected", func(t *testing.T) { testSkipsEjected(t, factory) })
+    t.Run("Distribution", func(t *testing.T) { testDistribution(t, factory) })
+    t.Run("HealthEvents", func(t *testing.T) { testHealthEvents(t, factory) })
+    t.Run("Membership", func(t *testing.T) { testMembership(t, factory) })
+    t.Run("Drain", func(t *testing.T) { testDrain(t, factory) })
+    t.Run("Concurrency", func(t *testing.T) { testConcurrency(t, factory) })
+}
+
+// newBalancer builds the balancer under test over n equally weighted backends
+func newBalancer(t *testing.T, factory Factory, n int) loadbalancer.LoadBalancer {
+    t.Helper()
+    backends := make([]loadbalancer.Backend, n)
+    for i := range backends {
+        backend, err := loadbalancer.NewHTTPBackend(fmt.Sprintf("http://conformance-%d.test", i), 1)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        backends[i] = backend
+    }
+    lb, err := factory(backends)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if got := len(lb.GetBackends()); got != n {
+        t.Fatalf("Expected %d backends, got %d", n, got)
+    }
+    return lb
+}
+
+// request returns the i-th of a series of requests from distinct clients with
+// distinct paths, so key-based algorithms see a spread of keys
+func request(i int) *http.Request {
+    req := httptest.NewRequest("GET", fmt.Sprintf("/item/%d", i), nil)
+    req.RemoteAddr = fmt.Sprintf("10.%d.%d.%d:4000", i>>16&0xff, i>>8&0xff, i&0xff)
+    return req
+}
+
+// members returns the set of backends of lb
+func members(lb loadbalancer.LoadBalancer) map[loadbalancer.Backend]bool {
+    set := make(map[loadbalancer.Backend]bool)
+    for _, backend := range lb.GetBackends() {
+        set[backend] = true
+    }
+    return set
+}
+
+// testSelectsPoolMembers verifies every selection is one of the pool's backends
+func testSelectsPoolMembers(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 3)
+    pool := members(lb)
+    for i := 0; i < 100; i++ {
+        backend, err := lb.SelectBackend(request(i))
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        if !pool[backend] {
+            t.Fatalf("Expected a pool member, got %v", backend)
+        }
+    }
+}
+
+// testSkipsUnhealthy verifies unhealthy backends are never selected, that an
+// all-unhealthy pool returns an error, and that recovered backends return
+func testSkipsUnhealthy(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 3)
+    backends := lb.GetBackends()
+    lb.UpdateBackendHealth(backends[0].GetURL(), false)
+    lb.UpdateBackendHealth(backends[2].GetURL(), false)
+
+    for i := 0; i < 100; i++ {
+        backend, err := lb.SelectBackend(request(i))
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        if backend != backends[1] {
+            t.Fatalf("Expected only healthy backend %s, got %s", backends[1].GetURL(), backend.GetURL())
+        }
+    }
+
+    lb.UpdateBackendHealth(backends[1].GetURL(), false)
+    if backend, err := lb.SelectBackend(request(0)); err == nil {
+        t.Fatalf("Expected error with no healthy backends, got %s", backend.GetURL())
+    }
+
+    lb.UpdateBackendHealth(backends[0].GetURL(), true)
+    backend, err := lb.SelectBackend(request(0))
+    if err != nil || backend != backends[0] {
+        t.Errorf("Expected recovered backend %s, got %v (err %v)", backends[0].GetURL(), backend, err)
+    }
+}
+
+// testSkipsEjected verifies outlier-ejected backends are out of rotation
+func testSkipsEjected(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 2)
+    backends := lb.GetBackends()
+    backends[0].SetEjected(true)
+
+    for i := 0; i < 50; i++ {
+        if backend, _ := lb.SelectBackend(request(i)); backend != backends[1] {
+            t.Fatalf("Expected backend %s, got %v", backends[1].GetURL(), backend)
+        }
+    }
+}
+
+// testDistribution verifies equally weighted backends each receive a fair
+// share of traffic; requests stay in flight until the end, as concurrent
+// requests would, so load-aware algorithms see the load build up
+func testDistribution(t *testing.T, factory Factory) {
+    const n, requests = 4, 2000
+    lb := newBalancer(t, factory, n)
+
+    counts := make(map[loadbalancer.Backend]int)
+    for i := 0; i < requests; i++ {
+        backend, err := lb.SelectBackend(request(i))
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        backend.IncrementConnections()
+        counts[backend]++
+    }
+
+    for _, backend := range lb.GetBackends() {
+        for i := 0; i < counts[backend]; i++ {
+            backend.DecrementConnections()
+        }
+        // Allow generous skew for randomised and hash-based algorithms
+        if counts[backend] < requests/n/2 {
+            t.Errorf("Expected a fair share of %d requests on %s, got %d", requests/n, backend.GetURL(), counts[backend])
+        }
+    }
+}
+
+// testHealthEvents verifies subscribers see health transitions only
+func testHealthEvents(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 2)
+    url := lb.GetBackends()[0].GetURL()
+
+    var mutex sync.Mutex
+    var events []loadbalancer.HealthEvent
+    unsubscribe := lb.Subscribe(func(event loadbalancer.HealthEvent) {
+        mutex.Lock()
+        defer mutex.Unlock()
+        events = append(events, event)
+    })
+    defer unsubscribe()
+
+    lb.UpdateBackendHealth(url, true)
+    lb.UpdateBackendHealth(url, false)
+    lb.UpdateBackendHealth(url, false)
+    lb.UpdateBackendHealth(url, true)
+
+    mutex.Lock()
+    defer mutex.Unlock()
+    expected := []loadbalancer.HealthEvent{{Backend: url, Healthy: false}, {Backend: url, Healthy: true}}
+    if len(events) != len(expected) || events[0] != expected[0] || events[1] != expected[1] {
+        t.Errorf("Expected events %v, got %v", expected, events)
+    }
+}
+
+// testMembership verifies added backends join the rotation, removed ones leave
+// it, and duplicate or unknown backends are rejected
+func testMembership(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 2)
+    added, err := loadbalancer.NewHTTPBackend("http://conformance-added.test", 1)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if err := lb.AddBackend(added); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if err := lb.AddBackend(added); !errors.Is(err, loadbalancer.ErrBackendExists) {
+        t.Errorf("Expected ErrBackendExists, got %v", err)
+    }
+    if !members(lb)[added] {
+        t.Fatal("Expected added backend in GetBackends")
+    }
+    if !selected(lb, added, 500) {
+        t.Error("Expected added backend to be selected")
+    }
+
+    removed := lb.GetBackends()[0]
+    if err := lb.RemoveBackend(removed.GetURL()); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if err := lb.RemoveBackend(removed.GetURL()); !errors.Is(err, loadbalancer.ErrBackendNotFound) {
+        t.Errorf("Expected ErrBackendNotFound, got %v", err)
+    }
+    if members(lb)[removed] || len(lb.GetBackends()) != 2 {
+        t.Fatalf("Expected removed backend gone, got %d backends", len(lb.GetBackends()))
+    }
+    if selected(lb, removed, 500) {
+        t.Error("Expected removed backend never to be selected")
+    }
+}
+
+// testDrain verifies a draining backend receives no new requests and is only
+// removed once its in-flight requests finish
+func testDrain(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 2)
+    draining := lb.GetBackends()[0]
+    draining.IncrementConnections()
+
+    done := make(chan error, 1)
+    go func() {
+        done <- lb.DrainBackend(context.Background(), draining.GetURL())
+    }()
+
+    // Wait until the drain has taken effect
+    for deadline := time.Now().Add(time.Second); !draining.IsDraining(); {
+        if time.Now().After(deadline) {
+            t.Fatal("Expected backend to be marked draining")
+        }
+        time.Sleep(time.Millisecond)
+    }
+    if selected(lb, draining, 200) {
+        t.Error("Expected draining backend not to be selected")
+    }
+    select {
+    case err := <-done:
+        t.Fatalf("Expected drain to wait for in-flight requests, returned %v", err)
+    case <-time.After(100 * time.Millisecond):
+    }
+    if !members(lb)[draining] {
+        t.Error("Expected draining backend to stay in the pool while busy")
+    }
+
+    draining.DecrementConnections()
+    select {
+    case err := <-done:
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+    case <-time.After(time.Second):
+        t.Fatal("Expected drain to finish once requests completed")
+    }
+    if members(lb)[draining] {
+        t.Error("Expected drained backend to be removed")
+    }
+
+    // A drain that runs out of time leaves the backend in the pool
+    busy := lb.GetBackends()[0]
+    busy.IncrementConnections()
+    defer busy.DecrementConnections()
+    ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+    defer cancel()
+    if err := lb.DrainBackend(ctx, busy.GetURL()); !errors.Is(err, context.DeadlineExceeded) {
+        t.Errorf("Expected deadline error, got %v", err)
+    }
+    if !members(lb)[busy] {
+        t.Error("Expected backend to stay in the pool after a cancelled drain")
+    }
+}
+
+// selected reports whether lb picks backend within the given number of requests
+// Requests are held in flight until the end, as in testDistribution
+func selected(lb loadbalancer.LoadBalancer, backend loadbalancer.Backend, requests int) bool {
+    var inFlight []loadbalancer.Backend
+    defer func() {
+        for _, b := range inFlight {
+            b.DecrementConnections()
+        }
+    }()
+
+    for i := 0; i < requests; i++ {
+        got, err := lb.SelectBackend(request(i))
+        if err != nil {
+            continue
+        }
+        if got == backend {
+            return true
+        }
+        got.IncrementConnections()
+        inFlight = append(inFlight, got)
+    }
+    return false
+}
+
+// testConcurrency runs selection alongside health, weight, connection and
+// membership updates
+func testConcurrency(t *testing.T, factory Factory) {
+    lb := newBalancer(t, factory, 3)
+    backends := lb.GetBackends()
+
+    var wg sync.WaitGroup
+    for worker := 0; worker < 4; worker++ {
+        wg.Add(2)
+        go func(worker int) {
+            defer wg.Done()
+            for i := 0; i < 200; i++ {
+                if backend, err := lb.SelectBackend(request(worker*200 + i)); err == nil {
+                    backend.IncrementConnections()
+                    backend.DecrementConnections()
+                }
+            }
+        }(worker)
+        go func(worker int) {
+            defer wg.Done()
+            for i := 0; i < 200; i++ {
+                backend := backends[(worker+i)%len(backends)]
+                lb.UpdateBackendHealth(backend.GetURL(), (worker+i)%2 == 0)
+                backend.SetWeight(i%5 + 1)
+                lb.GetBackends()
+            }
+        }(worker)
+    }
+
+    // Membership changes alongside selection
+    wg.Add(1)
+    go func() {
+        defer wg.Done()
+        for i := 0; i < 50; i++ {
+            added, err := loadbalancer.NewHTTPBackend(fmt.Sprintf("http://conformance-extra-%d.test", i), 1)
+            if err != nil {
+                continue
+            }
+            lb.AddBackend(added)
+            lb.RemoveBackend(added.GetURL())
+        }
+    }()
+    wg.Wait()
+}
@@ -1,7 +1,9 @@
 package loadbalancer
 
 import (
+	"context"
 	"errors"
+	"math/rand/v2"
 	"net/http"
 	"sync"
 )
@@ -14,6 +16,7 @@ import (
 type LeastConnectionsBalancer struct {
     backends []Backend    // List of all configured backends
     mutex    sync.RWMutex // Protects backends slice during updates
+    healthSubscribers
 }
 
 // NewLeastConnectionsBalancer creates new least connections load balancer
@@ -39,14 +42,21 @@ func (lc *LeastConnectionsBalancer) SelectBackend(req *http.Request) (Backend, e
         return nil, errors.New("no backends available")
     }
 
-    var selectedBackend Backend
+    var selectedBackend, fallback Backend
     minConnections := int64(-1) // Use -1 to handle first backend selection
 
     // Find healthy backend with minimum active connections
     // Linear scan is acceptable for typical backend counts (< 100)
     for _, backend := range lc.backends {
-        if !backend.IsHealthy() {
-            continue // Skip unhealthy backends
+        if !backend.IsAvailable() {
+            continue // Skip unhealthy or circuit-broken backends
+        }
+        if !admit(backend, rand.Float64()) {
+            // Passed over for this request while in slow start
+            if fallback == nil {
+                fallback = backend
+            }
+            continue
         }
 
         connections := backend.GetConnections()
@@ -58,6 +68,9 @@ func (lc *LeastConnectionsBalancer) SelectBackend(req *http.Request) (Backend, e
         }
     }
 
+    if selectedBackend == nil {
+        selectedBackend = fallback
+    }
     if selectedBackend == nil {
         return nil, errors.New("no healthy backends available")
     }
@@ -66,19 +79,18 @@ func (lc *LeastConnectionsBalancer) SelectBackend(req *http.Request) (Backend, e
 }
 
 // UpdateBackendHealth updates health status for specified backend URL
-// Uses linear search to find backend by URL
-// Write lock ensures thread safety during health status updates
+// Uses linear search to find backend by URL; health itself is atomic, so a read
+// lock is enough. Subscribers are notified after the lock is released when the
+// status actually changed
 // Time Complexity: O(n) for linear search through backend list
 // Space Complexity: O(1) - no additional allocations
 func (lc *LeastConnectionsBalancer) UpdateBackendHealth(url string, healthy bool) {
-    lc.mutex.Lock()
-    defer lc.mutex.Unlock()
+    lc.mutex.RLock()
+    event, changed := setBackendHealth(lc.backends, url, healthy)
+    lc.mutex.RUnlock()
 
-    for _, backend := range lc.backends {
-        if backend.GetURL() == url {
-            backend.SetHealthy(healthy)
-            return
-        }
+    if changed {
+        lc.publish(event)
     }
 }
 
@@ -94,4 +106,43 @@ func (lc *LeastConnectionsBalancer) GetBackends() []Backend {
     backends := make([]Backend, len(lc.backends))
     copy(backends, lc.backends)
     return backends
+}
+
+// AddBackend adds backend to the pool
+// Time Complexity: O(n) for the duplicate check and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (lc *LeastConnectionsBalancer) AddBackend(backend Backend) error {
+    lc.mutex.Lock()
+    defer lc.mutex.Unlock()
+
+    backends, err := withBackend(lc.backends, backend)
+    if err != nil {
+        return err
+    }
+    lc.backends = backends
+    return nil
+}
+
+// RemoveBackend removes the backend with url from the pool at once
+// Requests already sent to it are unaffected; use DrainBackend to wait for them
+// Time Complexity: O(n) for the search and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (lc *LeastConnectionsBalancer) RemoveBackend(url string) error {
+    lc.mutex.Lock()
+    defer lc.mutex.Unlock()
+
+    backends, _, err := withoutBackend(lc.backends, url)
+    if err != nil {
+        return err
+    }
+    lc.backends = backends
+    return nil
+}
+
+// DrainBackend stops selecting the backend with url and removes it once its
+// in-flight requests have finished or returns the context error if ctx ends first
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (lc *LeastConnectionsBalancer) DrainBackend(ctx context.Context, url string) error {
+    return drainBackend(ctx, lc, url)
 }
\ No newline at end of file
@@ -0,0 +1,84 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned by AddBackend, RemoveBackend and DrainBackend
+var (
+    ErrBackendExists   = errors.New("backend already in pool")
+    ErrBackendNotFound = errors.New("backend not in pool")
+)
+
+// drainPollInterval is how often a draining backend's in-flight count is checked
+const drainPollInterval = 25 * time.Millisecond
+
+// withBackend returns a copy of backends with backend appended and starts its
+// slow start, so a new backend is ramped up like a recovered one
+// Balancers replace their slice rather than growing it in place, so slices
+// handed out earlier are never written to
+// Time Complexity: O(n) for the duplicate check and copy
+// Space Complexity: O(n) for the new slice
+func withBackend(backends []Backend, backend Backend) ([]Backend, error) {
+    if indexOf(backends, backend.GetURL()) >= 0 {
+        return nil, fmt.Errorf("%w: %s", ErrBackendExists, backend.GetURL())
+    }
+    updated := make([]Backend, len(backends), len(backends)+1)
+    copy(updated, backends)
+    backend.BeginSlowStart()
+    return append(updated, backend), nil
+}
+
+// withoutBackend returns a copy of backends without the backend with url,
+// along with the index it had
+// Time Complexity: O(n) for the search and copy
+// Space Complexity: O(n) for the new slice
+func withoutBackend(backends []Backend, url string) ([]Backend, int, error) {
+    index := indexOf(backends, url)
+    if index < 0 {
+        return nil, -1, fmt.Errorf("%w: %s", ErrBackendNotFound, url)
+    }
+    updated := make([]Backend, 0, len(backends)-1)
+    updated = append(updated, backends[:index]...)
+    return append(updated, backends[index+1:]...), index, nil
+}
+
+// indexOf returns the position of the backend with url, or -1
+func indexOf(backends []Backend, url string) int {
+    for i, backend := range backends {
+        if backend.GetURL() == url {
+            return i
+        }
+    }
+    return -1
+}
+
+// drainBackend takes the backend with url out of rotation, waits for its
+// in-flight requests to finish and then removes it from lb
+// If ctx ends first the backend stays drained but in the pool and the context
+// error is returned; RemoveBackend then removes it regardless of traffic
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func drainBackend(ctx context.Context, lb LoadBalancer, url string) error {
+    backends := lb.GetBackends()
+    index := indexOf(backends, url)
+    if index < 0 {
+        return fmt.Errorf("%w: %s", ErrBackendNotFound, url)
+    }
+    backend := backends[index]
+    backend.SetDraining(true)
+
+    ticker := time.NewTicker(drainPollInterval)
+    defer ticker.Stop()
+    for backend.GetConnections() > 0 {
+        select {
+        case <-ticker.C:
+        case <-ctx.Done():
+            return fmt.Errorf("failed to drain backend %s: %w", url, ctx.Err())
+        }
+    }
+    return lb.RemoveBackend(url)
+}
@@ -0,0 +1,304 @@
+package loadbalancer
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// Outlier ejection reasons reported in events, metrics and logs
+const (
+    EjectConsecutive5xx           = "consecutive-5xx"
+    EjectConsecutiveConnectErrors = "consecutive-connect-errors"
+    EjectErrorRate                = "error-rate"
+    EjectLatency                  = "latency"
+)
+
+// Outcome describes one request forwarded to a backend
+type Outcome struct {
+    Status       int           // Response status, 0 when none was received
+    ConnectError bool          // Transport failure: refused, reset or timed out before a response
+    Latency      time.Duration // Time until response headers arrived, 0 when none did
+}
+
+// OutlierEvent reports a backend being ejected or returned to rotation
+type OutlierEvent struct {
+    Backend  string        // Backend URL
+    Ejected  bool          // True on ejection, false when the backend returns
+    Reason   string        // Ejection reason, empty when the backend returns
+    Duration time.Duration // Length of the ejection, zero when the backend returns
+}
+
+// outlierHost holds the outcome counters of one backend
+type outlierHost struct {
+    requests           int           // Requests in the current interval
+    errors             int           // 5xx responses and transport failures in the current interval
+    latency            time.Duration // Summed latency of responses in the current interval
+    responses          int           // Responses contributing to latency
+    consecutive5xx     int
+    consecutiveConnect int
+    ejections          int       // Recent ejections, multiplies the ejection time and decays while healthy
+    ejectedUntil       time.Time // Zero when the backend is not ejected
+}
+
+// OutlierDetector ejects backends of a pool based on live traffic
+// Outcomes reported by the proxy path trigger immediate ejection on consecutive
+// failures; Evaluate compares backends against the pool once per interval and
+// returns backends whose ejection time has passed
+type OutlierDetector struct {
+    config   config.OutlierDetectionConfig
+    backends []Backend
+    onEvent  func(OutlierEvent) // Called outside the lock for every ejection and return
+    now      func() time.Time   // Clock, replaced in tests
+
+    mutex sync.Mutex
+    hosts map[Backend]*outlierHost
+}
+
+// NewOutlierDetector creates a detector for the backends of one pool
+// onEvent may be nil; it is used for metrics and log events
+// Time Complexity: O(n) where n is number of backends
+// Space Complexity: O(n) for per-backend counters
+func NewOutlierDetector(cfg config.OutlierDetectionConfig, backends []Backend, onEvent func(OutlierEvent)) *OutlierDetector {
+    d := &OutlierDetector{
+        config:   cfg,
+        backends: backends,
+        onEvent:  onEvent,
+        now:      time.Now,
+        hosts:    make(map[Backend]*outlierHost, len(backends)),
+    }
+    for _, backend := range backends {
+        d.hosts[backend] = &outlierHost{}
+    }
+    return d
+}
+
+// Add starts tracking a backend added to the pool at runtime
+// Its counters start empty, so it is compared from the next full interval
+// Time Complexity: O(n) where n is number of backends, for the slice copy
+// Space Complexity: O(n) for the new backend slice
+func (d *OutlierDetector) Add(backend Backend) {
+    d.mutex.Lock()
+    defer d.mutex.Unlock()
+
+    if _, ok := d.hosts[backend]; ok {
+        return
+    }
+    d.hosts[backend] = &outlierHost{}
+    d.backends = append(d.backends[:len(d.backends):len(d.backends)], backend)
+}
+
+// Remove stops tracking a backend removed from the pool
+// Later outcomes of requests still in flight to it are ignored
+// Time Complexity: O(n) where n is number of backends
+// Space Complexity: O(n) for the new backend slice
+func (d *OutlierDetector) Remove(backend Backend) {
+    d.mutex.Lock()
+    defer d.mutex.Unlock()
+
+    if _, ok := d.hosts[backend]; !ok {
+        return
+    }
+    delete(d.hosts, backend)
+    backends := make([]Backend, 0, len(d.backends)-1)
+    for _, b := range d.backends {
+        if b != backend {
+            backends = append(backends, b)
+        }
+    }
+    d.backends = backends
+}
+
+// Record adds the outcome of a request to backend
+// Ejects the backend when it reaches a consecutive failure threshold
+// Time Complexity: O(n) where n is number of backends, only when ejecting
+// Space Complexity: O(1) - counter updates
+func (d *OutlierDetector) Record(backend Backend, outcome Outcome) {
+    d.mutex.Lock()
+    host, ok := d.hosts[backend]
+    if !ok {
+        d.mutex.Unlock()
+        return
+    }
+
+    host.requests++
+    switch {
+    case outcome.ConnectError:
+        host.errors++
+        host.consecutiveConnect++
+    case outcome.Status >= 500:
+        host.errors++
+        host.consecutive5xx++
+        host.consecutiveConnect = 0
+    default:
+        host.consecutive5xx = 0
+        host.consecutiveConnect = 0
+    }
+    if outcome.Latency > 0 {
+        host.latency += outcome.Latency
+        host.responses++
+    }
+
+    var events []OutlierEvent
+    now := d.now()
+    if d.config.Consecutive5xx > 0 && host.consecutive5xx >= d.config.Consecutive5xx {
+        events = d.eject(backend, host, EjectConsecutive5xx, now, events)
+    } else if d.config.ConsecutiveConnectErrors > 0 && host.consecutiveConnect >= d.config.ConsecutiveConnectErrors {
+        events = d.eject(backend, host, EjectConsecutiveConnectErrors, now, events)
+    }
+    d.mutex.Unlock()
+
+    d.notify(events)
+}
+
+// Evaluate runs one detection interval
+// Expired ejections end, ejection counts of backends that stayed in rotation
+// decay, and backends deviating from the pool median are ejected; the
+// interval counters are then reset
+// Time Complexity: O(n log n) where n is number of backends, for the medians
+// Space Complexity: O(n) for the compared rates and latencies
+func (d *OutlierDetector) Evaluate() {
+    d.mutex.Lock()
+    now := d.now()
+    var events []OutlierEvent
+
+    for _, backend := range d.backends {
+        host := d.hosts[backend]
+        switch {
+        case !host.ejectedUntil.IsZero() && !now.Before(host.ejectedUntil):
+            host.ejectedUntil = time.Time{}
+            backend.SetEjected(false)
+            events = append(events, OutlierEvent{Backend: backend.GetURL()})
+        case host.ejectedUntil.IsZero() && host.ejections > 0:
+            host.ejections--
+        }
+    }
+
+    // Only backends in rotation with enough traffic are compared
+    var candidates []Backend
+    for _, backend := range d.backends {
+        host := d.hosts[backend]
+        if host.ejectedUntil.IsZero() && host.requests >= d.config.MinRequests && host.requests > 0 {
+            candidates = append(candidates, backend)
+        }
+    }
+    if len(candidates) >= d.config.MinHosts {
+        if d.config.ErrorRateDeviation > 0 {
+            rates := make([]float64, len(candidates))
+            for i, backend := range candidates {
+                host := d.hosts[backend]
+                rates[i] = float64(host.errors) / float64(host.requests)
+            }
+            limit := median(rates) + d.config.ErrorRateDeviation
+            for i, backend := range candidates {
+                if rates[i] >= limit {
+                    events = d.eject(backend, d.hosts[backend], EjectErrorRate, now, events)
+                }
+            }
+        }
+
+        if d.config.LatencyDeviation > 0 {
+            var latencies []float64
+            var measured []Backend
+            for _, backend := range candidates {
+                if host := d.hosts[backend]; host.responses > 0 && host.ejectedUntil.IsZero() {
+                    latencies = append(latencies, float64(host.latency)/float64(host.responses))
+                    measured = append(measured, backend)
+                }
+            }
+            if len(measured) >= d.config.MinHosts {
+                limit := median(latencies) * d.config.LatencyDeviation
+                for i, backend := range measured {
+                    if latencies[i] > limit {
+                        events = d.eject(backend, d.hosts[backend], EjectLatency, now, events)
+                    }
+                }
+            }
+        }
+    }
+
+    for _, host := range d.hosts {
+        host.requests = 0
+        host.errors = 0
+        host.latency = 0
+        host.responses = 0
+    }
+    d.mutex.Unlock()
+
+    d.notify(events)
+}
+
+// Run evaluates the pool once per interval until ctx is cancelled
+// Time Complexity: O(n log n) per interval where n is number of backends
+// Space Complexity: O(n) per evaluation
+func (d *OutlierDetector) Run(ctx context.Context) {
+    ticker := time.NewTicker(d.config.Interval)
+    defer ticker.Stop()
+
+    for {
+        select {
+        case <-ticker.C:
+            d.Evaluate()
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// eject takes backend out of rotation unless it already is or the pool has
+// reached its ejection limit; caller holds the lock
+// The ejection time grows with the number of recent ejections
+func (d *OutlierDetector) eject(backend Backend, host *outlierHost, reason string, now time.Time, events []OutlierEvent) []OutlierEvent {
+    if !host.ejectedUntil.IsZero() {
+        return events
+    }
+
+    // At least one backend may be ejected, but never the whole pool
+    ejected := 0
+    for _, h := range d.hosts {
+        if !h.ejectedUntil.IsZero() {
+            ejected++
+        }
+    }
+    limit := max(len(d.backends)*d.config.MaxEjectionPercent/100, 1)
+    limit = min(limit, len(d.backends)-1)
+    if ejected >= limit {
+        return events
+    }
+
+    host.ejections++
+    duration := d.config.BaseEjectionTime * time.Duration(host.ejections)
+    if d.config.MaxEjectionTime > 0 {
+        duration = min(duration, d.config.MaxEjectionTime)
+    }
+    host.ejectedUntil = now.Add(duration)
+    host.consecutive5xx = 0
+    host.consecutiveConnect = 0
+    backend.SetEjected(true)
+
+    return append(events, OutlierEvent{Backend: backend.GetURL(), Ejected: true, Reason: reason, Duration: duration})
+}
+
+// notify reports events to the event callback, if any
+func (d *OutlierDetector) notify(events []OutlierEvent) {
+    if d.onEvent == nil {
+        return
+    }
+    for _, event := range events {
+        d.onEvent(event)
+    }
+}
+
+// median returns the median of values, sorting a copy
+func median(values []float64) float64 {
+    sorted := append([]float64(nil), values...)
+    sort.Float64s(sorted)
+    mid := len(sorted) / 2
+    if len(sorted)%2 == 0 {
+        return (sorted[mid-1] + sorted[mid]) / 2
+    }
+    return sorted[mid]
+}
@@ -0,0 +1,149 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newTestDetector returns a detector over n backends driven by a manual clock
+func newTestDetector(t *testing.T, cfg config.OutlierDetectionConfig, n int) (*OutlierDetector, []Backend, *time.Time, *[]OutlierEvent) {
+    t.Helper()
+    backends := make([]Backend, n)
+    for i := range backends {
+        backend, err := NewHTTPBackend(fmt.Sprintf("http://backend-%d", i), 1)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        backends[i] = backend
+    }
+
+    now := time.Unix(1000, 0)
+    var events []OutlierEvent
+    d := NewOutlierDetector(cfg, backends, func(event OutlierEvent) {
+        events = append(events, event)
+    })
+    d.now = func() time.Time { return now }
+    return d, backends, &now, &events
+}
+
+// testOutlierConfig enables detection with every check and a 50% ejection cap
+func testOutlierConfig() config.OutlierDetectionConfig {
+    cfg := config.DefaultOutlierDetectionConfig()
+    cfg.Enabled = true
+    cfg.Consecutive5xx = 3
+    cfg.ConsecutiveConnectErrors = 2
+    cfg.MinRequests = 4
+    cfg.MaxEjectionPercent = 50
+    return cfg
+}
+
+// TestOutlierConsecutiveFailures verifies consecutive 5xx and connect errors eject
+// immediately and that ejection time grows with repeated ejections
+func TestOutlierConsecutiveFailures(t *testing.T) {
+    d, backends, now, events := newTestDetector(t, testOutlierConfig(), 4)
+
+    for i := 0; i < 3; i++ {
+        d.Record(backends[0], Outcome{Status: 503})
+    }
+    if !backends[0].IsEjected() || backends[0].IsAvailable() {
+        t.Fatal("Expected backend to be ejected after 3 consecutive 5xx responses")
+    }
+
+    d.Record(backends[1], Outcome{ConnectError: true})
+    d.Record(backends[1], Outcome{Status: 200})
+    d.Record(backends[1], Outcome{ConnectError: true})
+    if backends[1].IsEjected() {
+        t.Fatal("Expected a success to reset consecutive connect errors")
+    }
+
+    // Returns after the base ejection time, then a second ejection lasts twice as long
+    *now = now.Add(30 * time.Second)
+    d.Evaluate()
+    if backends[0].IsEjected() {
+        t.Fatal("Expected backend to return after the base ejection time")
+    }
+    for i := 0; i < 3; i++ {
+        d.Record(backends[0], Outcome{Status: 500})
+    }
+    last := (*events)[len(*events)-1]
+    if !last.Ejected || last.Reason != EjectConsecutive5xx || last.Duration != 60*time.Second {
+        t.Errorf("Expected second ejection for 60s, got %+v", last)
+    }
+}
+
+// TestOutlierMaxEjectionPercent verifies the ejection cap and that the whole
+// pool is never ejected
+func TestOutlierMaxEjectionPercent(t *testing.T) {
+    d, backends, _, _ := newTestDetector(t, testOutlierConfig(), 4)
+    for _, backend := range backends {
+        for i := 0; i < 3; i++ {
+            d.Record(backend, Outcome{ConnectError: true})
+        }
+    }
+
+    ejected := 0
+    for _, backend := range backends {
+        if backend.IsEjected() {
+            ejected++
+        }
+    }
+    if ejected != 2 {
+        t.Errorf("Expected 50%% of 4 backends to be ejected, got %d", ejected)
+    }
+
+    cfg := testOutlierConfig()
+    cfg.MaxEjectionPercent = 100
+    d, backends, _, _ = newTestDetector(t, cfg, 2)
+    for _, backend := range backends {
+        for i := 0; i < 3; i++ {
+            d.Record(backend, Outcome{Status: 502})
+        }
+    }
+    if backends[0].IsEjected() == backends[1].IsEjected() {
+        t.Error("Expected exactly one of two backends to stay in rotation")
+    }
+}
+
+// TestOutlierDeviation verifies backends whose error rate or latency deviates
+// from the pool median are ejected at the end of an interval
+func TestOutlierDeviation(t *testing.T) {
+    cfg := testOutlierConfig()
+    cfg.Consecutive5xx = 0
+    d, backends, _, events := newTestDetector(t, cfg, 4)
+
+    for i := 0; i < 10; i++ {
+        d.Record(backends[0], Outcome{Status: 200, Latency: 10 * time.Millisecond})
+        d.Record(backends[1], Outcome{Status: 200, Latency: 12 * time.Millisecond})
+        d.Record(backends[2], Outcome{Status: 200, Latency: 100 * time.Millisecond})
+        status := 200
+        if i%2 == 0 {
+            status = 500
+        }
+        d.Record(backends[3], Outcome{Status: status, Latency: 11 * time.Millisecond})
+    }
+    d.Evaluate()
+
+    reasons := map[string]string{}
+    for _, event := range *events {
+        reasons[event.Backend] = event.Reason
+    }
+    if reasons[backends[2].GetURL()] != EjectLatency {
+        t.Errorf("Expected slow backend ejected for latency, got %v", reasons)
+    }
+    if reasons[backends[3].GetURL()] != EjectErrorRate {
+        t.Errorf("Expected failing backend ejected for error rate, got %v", reasons)
+    }
+    if backends[0].IsEjected() || backends[1].IsEjected() {
+        t.Error("Expected backends in line with the pool to stay in rotation")
+    }
+
+    // Too little traffic in the next interval means nothing is compared
+    d.Record(backends[0], Outcome{Status: 500})
+    d.Evaluate()
+    if backends[0].IsEjected() {
+        t.Error("Expected backends below minRequests to be left alone")
+    }
+}
@@ -0,0 +1,248 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// LatencyObserver is implemented by balancers that learn from response times
+// The proxy reports the latency of every attempt to a backend of such a pool,
+// through ObserveFailure when the attempt failed
+type LatencyObserver interface {
+    ObserveLatency(backend Backend, latency time.Duration)
+    ObserveFailure(backend Backend, latency time.Duration)
+}
+
+// ewma is the exponentially weighted moving average of one backend's latency
+type ewma struct {
+    mutex   sync.Mutex
+    value   float64   // Average latency in nanoseconds, 0 until the first observation
+    updated time.Time // Time of the last observation
+}
+
+// P2CEWMABalancer implements power-of-two-choices over latency-weighted load
+// Two available backends are sampled at random and the one with the lower
+// score wins, where the score is the latency average times in-flight requests
+// plus one. Sampling avoids the herding of always picking the global minimum,
+// while the score steers traffic away from slow or busy backends
+// Time Complexity: O(1) per selection when the samples are available, O(n) otherwise
+// Space Complexity: O(n) for per-backend latency averages
+type P2CEWMABalancer struct {
+    backends []Backend
+    latency  map[Backend]*ewma // Per-backend averages, values lock themselves
+    decay    time.Duration     // Time constant of the moving average
+    penalty  time.Duration     // Least latency recorded for a failed request
+    now      func() time.Time  // Clock, replaced in tests
+    mutex    sync.RWMutex      // Protects backends and the latency map
+    healthSubscribers
+}
+
+// NewP2CEWMABalancer creates a p2c-ewma balancer with the decay of cfg
+// Time Complexity: O(n) where n is number of backends
+// Space Complexity: O(n) for per-backend latency averages
+func NewP2CEWMABalancer(backends []Backend, cfg config.P2CConfig) *P2CEWMABalancer {
+    if cfg.Decay <= 0 {
+        cfg.Decay = config.DefaultP2CConfig().Decay
+    }
+    if cfg.FailurePenalty <= 0 {
+        cfg.FailurePenalty = config.DefaultP2CConfig().FailurePenalty
+    }
+    latency := make(map[Backend]*ewma, len(backends))
+    for _, backend := range backends {
+        latency[backend] = &ewma{}
+    }
+    return &P2CEWMABalancer{
+        backends: backends,
+        latency:  latency,
+        decay:    cfg.Decay,
+        penalty:  cfg.FailurePenalty,
+        now:      time.Now,
+    }
+}
+
+// SelectBackend samples two distinct available backends and returns the one
+// with the lower score; ties are broken by the first sample, itself random
+// Time Complexity: O(1) typical, O(n) when a sample is unavailable
+// Space Complexity: O(n) only when falling back to the available list
+func (p *P2CEWMABalancer) SelectBackend(req *http.Request) (Backend, error) {
+    p.mutex.RLock()
+    defer p.mutex.RUnlock()
+
+    n := len(p.backends)
+    if n == 0 {
+        return nil, errors.New("no backends available")
+    }
+
+    var first, second Backend
+    if n > 1 {
+        i := rand.IntN(n)
+        j := rand.IntN(n - 1)
+        if j >= i {
+            j++
+        }
+        first, second = p.backends[i], p.backends[j]
+    } else {
+        first = p.backends[0]
+    }
+
+    // Resample among available backends when either pick is out of rotation
+    if !first.IsAvailable() || (second != nil && !second.IsAvailable()) {
+        available := make([]Backend, 0, n)
+        for _, backend := range p.backends {
+            if backend.IsAvailable() {
+                available = append(available, backend)
+            }
+        }
+        switch len(available) {
+        case 0:
+            return nil, errors.New("no healthy backends available")
+        case 1:
+            return available[0], nil
+        }
+        i := rand.IntN(len(available))
+        j := rand.IntN(len(available) - 1)
+        if j >= i {
+            j++
+        }
+        first, second = available[i], available[j]
+    }
+
+    if second == nil {
+        return first, nil
+    }
+
+    // A sample in slow start only competes for its share of draws
+    firstAdmitted, secondAdmitted := admit(first, rand.Float64()), admit(second, rand.Float64())
+    if firstAdmitted != secondAdmitted {
+        if firstAdmitted {
+            return first, nil
+        }
+        return second, nil
+    }
+    if p.score(first) <= p.score(second) {
+        return first, nil
+    }
+    return second, nil
+}
+
+// score weighs a backend's latency average by its in-flight requests
+// Backends without observations score zero so new backends are tried first
+func (p *P2CEWMABalancer) score(backend Backend) float64 {
+    return p.latency[backend].get() * float64(backend.GetConnections()+1)
+}
+
+// ObserveLatency folds a response time into the backend's moving average
+// The previous average keeps weight exp(-elapsed/decay), so the average adapts
+// at the same pace however much traffic the backend receives
+// Time Complexity: O(1) - single update under the backend's lock
+// Space Complexity: O(1) - no allocations
+func (p *P2CEWMABalancer) ObserveLatency(backend Backend, latency time.Duration) {
+    p.mutex.RLock()
+    average, ok := p.latency[backend]
+    p.mutex.RUnlock()
+    if !ok || latency < 0 {
+        return
+    }
+
+    now := p.now()
+    average.mutex.Lock()
+    defer average.mutex.Unlock()
+
+    if average.updated.IsZero() {
+        average.value = float64(latency)
+    } else {
+        elapsed := max(now.Sub(average.updated), 0)
+        weight := math.Exp(-float64(elapsed) / float64(p.decay))
+        average.value = average.value*weight + float64(latency)*(1-weight)
+    }
+    average.updated = now
+}
+
+// ObserveFailure folds a failed request into the backend's moving average
+// The failure counts as at least the configured penalty: a backend refusing
+// connections fails in microseconds and must not outscore ones that answer
+// Time Complexity: O(1) - single update under the backend's lock
+// Space Complexity: O(1) - no allocations
+func (p *P2CEWMABalancer) ObserveFailure(backend Backend, latency time.Duration) {
+    p.ObserveLatency(backend, max(latency, p.penalty))
+}
+
+// get returns the current average in nanoseconds
+func (e *ewma) get() float64 {
+    e.mutex.Lock()
+    defer e.mutex.Unlock()
+    return e.value
+}
+
+// UpdateBackendHealth updates health status for specified backend URL
+// Time Complexity: O(n) for linear search through backend list
+// Space Complexity: O(1) - no additional allocations
+func (p *P2CEWMABalancer) UpdateBackendHealth(url string, healthy bool) {
+    p.mutex.RLock()
+    event, changed := setBackendHealth(p.backends, url, healthy)
+    p.mutex.RUnlock()
+
+    if changed {
+        p.publish(event)
+    }
+}
+
+// GetBackends returns copy of all backends for health checking
+// Time Complexity: O(n) for slice copy
+// Space Complexity: O(n) for copied slice
+func (p *P2CEWMABalancer) GetBackends() []Backend {
+    p.mutex.RLock()
+    defer p.mutex.RUnlock()
+
+    backends := make([]Backend, len(p.backends))
+    copy(backends, p.backends)
+    return backends
+}
+
+// AddBackend adds backend to the pool
+// Time Complexity: O(n) for the duplicate check and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (p *P2CEWMABalancer) AddBackend(backend Backend) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    backends, err := withBackend(p.backends, backend)
+    if err != nil {
+        return err
+    }
+    p.backends = backends
+    p.latency[backend] = &ewma{}
+    return nil
+}
+
+// RemoveBackend removes the backend with url from the pool at once
+// Requests already sent to it are unaffected; use DrainBackend to wait for them
+// Time Complexity: O(n) for the search and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (p *P2CEWMABalancer) RemoveBackend(url string) error {
+    p.mutex.Lock()
+    defer p.mutex.Unlock()
+
+    backends, index, err := withoutBackend(p.backends, url)
+    if err != nil {
+        return err
+    }
+    delete(p.latency, p.backends[index])
+    p.backends = backends
+    return nil
+}
+
+// DrainBackend stops selecting the backend with url and removes it once its
+// in-flight requests have finished or returns the context error if ctx ends first
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (p *P2CEWMABalancer) DrainBackend(ctx context.Context, url string) error {
+    return drainBackend(ctx, p, url)
+}
@@ -0,0 +1,101 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newTestP2CBalancer builds a p2c-ewma balancer over n backends with a manual clock
+func newTestP2CBalancer(t *testing.T, n int) (*P2CEWMABalancer, *time.Time) {
+    t.Helper()
+    backends := make([]Backend, n)
+    for i := range backends {
+        backend, err := NewHTTPBackend(fmt.Sprintf("http://backend-%d", i), 1)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        backends[i] = backend
+    }
+    lb := NewP2CEWMABalancer(backends, config.DefaultP2CConfig())
+    now := time.Unix(0, 0)
+    lb.now = func() time.Time { return now }
+    return lb, &now
+}
+
+// TestP2CEWMAPrefersFastBackend verifies traffic shifts to the backend with
+// the lower observed latency
+func TestP2CEWMAPrefersFastBackend(t *testing.T) {
+    lb, _ := newTestP2CBalancer(t, 2)
+    backends := lb.GetBackends()
+    lb.ObserveLatency(backends[0], 10*time.Millisecond)
+    lb.ObserveLatency(backends[1], 200*time.Millisecond)
+
+    for i := 0; i < 100; i++ {
+        backend, err := lb.SelectBackend(nil)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        if backend != backends[0] {
+            t.Fatalf("Expected fast backend, got %s", backend.GetURL())
+        }
+    }
+}
+
+// TestP2CEWMAWeighsInflight verifies a fast backend loses to a slower one
+// once it carries enough in-flight requests
+func TestP2CEWMAWeighsInflight(t *testing.T) {
+    lb, _ := newTestP2CBalancer(t, 2)
+    backends := lb.GetBackends()
+    lb.ObserveLatency(backends[0], 10*time.Millisecond)
+    lb.ObserveLatency(backends[1], 30*time.Millisecond)
+    for i := 0; i < 5; i++ {
+        backends[0].IncrementConnections()
+    }
+
+    backend, _ := lb.SelectBackend(nil)
+    if backend != backends[1] {
+        t.Errorf("Expected idle backend, got %s", backend.GetURL())
+    }
+}
+
+// TestP2CEWMASkipsUnavailable verifies unhealthy backends are never sampled
+func TestP2CEWMASkipsUnavailable(t *testing.T) {
+    lb, _ := newTestP2CBalancer(t, 3)
+    backends := lb.GetBackends()
+    lb.UpdateBackendHealth(backends[0].GetURL(), false)
+    lb.UpdateBackendHealth(backends[1].GetURL(), false)
+
+    for i := 0; i < 50; i++ {
+        backend, err := lb.SelectBackend(nil)
+        if err != nil {
+            t.Fatalf("unexpected error: %v", err)
+        }
+        if backend != backends[2] {
+            t.Fatalf("Expected only healthy backend, got %s", backend.GetURL())
+        }
+    }
+
+    lb.UpdateBackendHealth(backends[2].GetURL(), false)
+    if _, err := lb.SelectBackend(nil); err == nil {
+        t.Error("Expected error when no backend is healthy")
+    }
+}
+
+// TestP2CEWMADecay verifies older observations lose weight with elapsed time
+func TestP2CEWMADecay(t *testing.T) {
+    lb, now := newTestP2CBalancer(t, 1)
+    backend := lb.GetBackends()[0]
+
+    lb.ObserveLatency(backend, 100*time.Millisecond)
+    *now = now.Add(lb.decay)
+    lb.ObserveLatency(backend, 0)
+
+    want := float64(100*time.Millisecond) * math.Exp(-1)
+    if got := lb.latency[backend].get(); math.Abs(got-want) > 1 {
+        t.Errorf("Expected average %.0f after one decay period, got %.0f", want, got)
+    }
+}
@@ -0,0 +1,170 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// Constructor builds a load balancer over the backends of one pool
+type Constructor func(backends []Backend, options Options) (LoadBalancer, error)
+
+// algorithm is a registry entry
+type algorithm struct {
+    constructor Constructor
+    validate    func(options *yaml.Node) error // Checks the options block, nil when the algorithm takes none
+}
+
+var (
+    // registry maps lower-case algorithm names to their entries
+    registry      = make(map[string]algorithm)
+    registryMutex sync.RWMutex
+)
+
+// Register makes an algorithm available to NewLoadBalancer under name
+// Configuration validation sees it through Algorithms
+// Names are case-insensitive; registering a name twice panics, as a silently
+// replaced algorithm would change routing behind the configuration's back
+// Time Complexity: O(1) - map insertion
+// Space Complexity: O(1) per registered algorithm
+func Register(name string, constructor Constructor) {
+    if constructor == nil {
+        panic("loadbalancer: Register constructor is nil for " + name)
+    }
+    register(name, algorithm{constructor: constructor})
+}
+
+// RegisterWithOptions makes an algorithm with its own settings available under name
+// The settings are read from the pool's options block for the algorithm, e.g.
+// loadBalance.options.<name>, into T with config.Decode, so omitted fields take
+// their default tags. validate, which may be nil, checks the decoded settings;
+// configuration validation reports its errors before any pool is built
+// Time Complexity: O(1) - map insertion
+// Space Complexity: O(1) per registered algorithm
+func RegisterWithOptions[T any](name string, validate func(T) error, constructor func(backends []Backend, options Options, settings T) (LoadBalancer, error)) {
+    if constructor == nil {
+        panic("loadbalancer: RegisterWithOptions constructor is nil for " + name)
+    }
+    decode := func(node *yaml.Node) (T, error) {
+        var settings T
+        if err := config.Decode(node, &settings); err != nil {
+            return settings, fmt.Errorf("failed to decode %s options: %w", name, err)
+        }
+        if validate != nil {
+            if err := validate(settings); err != nil {
+                return settings, err
+            }
+        }
+        return settings, nil
+    }
+    register(name, algorithm{
+        constructor: func(backends []Backend, options Options) (LoadBalancer, error) {
+            settings, err := decode(options.Algorithm)
+            if err != nil {
+                return nil, err
+            }
+            return constructor(backends, options, settings)
+        },
+        validate: func(node *yaml.Node) error {
+            _, err := decode(node)
+            return err
+        },
+    })
+}
+
+// register adds entry under the case-insensitive name, panicking on duplicates
+func register(name string, entry algorithm) {
+    key := strings.ToLower(name)
+
+    registryMutex.Lock()
+    defer registryMutex.Unlock()
+    if _, exists := registry[key]; exists {
+        panic("loadbalancer: Register called twice for " + name)
+    }
+    registry[key] = entry
+}
+
+// WithOptions adapts a constructor taking typed options to a Constructor
+// options selects the algorithm's own settings from the pool options, so each
+// algorithm sees only the configuration block it defines
+func WithOptions[T any](options func(Options) T, constructor func(backends []Backend, options T) (LoadBalancer, error)) Constructor {
+    return func(backends []Backend, o Options) (LoadBalancer, error) {
+        return constructor(backends, options(o))
+    }
+}
+
+// lookup returns the algorithm registered under name
+func lookup(name string) (algorithm, bool) {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+    entry, ok := registry[strings.ToLower(name)]
+    return entry, ok
+}
+
+// GetSupportedAlgorithms returns the registered algorithm names in sorted order
+// Used for configuration validation and documentation
+// Time Complexity: O(a log a) where a is number of registered algorithms
+// Space Complexity: O(a) for the returned slice
+func GetSupportedAlgorithms() []string {
+    registryMutex.RLock()
+    defer registryMutex.RUnlock()
+
+    names := make([]string, 0, len(registry))
+    for name := range registry {
+        names = append(names, name)
+    }
+    sort.Strings(names)
+    return names
+}
+
+// Algorithms returns the registered algorithms for config.Config.Validate
+// The set is read at validation time, so algorithms registered later are included
+func Algorithms() config.Algorithms {
+    return registeredAlgorithms{}
+}
+
+// registeredAlgorithms implements config.Algorithms over the registry
+type registeredAlgorithms struct{}
+
+// Supported reports whether name is registered, ignoring case
+func (registeredAlgorithms) Supported(name string) bool {
+    _, ok := lookup(name)
+    return ok
+}
+
+// Names returns the registered algorithm names in sorted order
+func (registeredAlgorithms) Names() []string {
+    return GetSupportedAlgorithms()
+}
+
+// ValidateOptions checks options with the algorithm registered under name
+// Algorithms registered without options accept no options block
+func (registeredAlgorithms) ValidateOptions(name string, options *yaml.Node) error {
+    entry, ok := lookup(name)
+    switch {
+    case !ok:
+        return fmt.Errorf("unsupported load balancing algorithm: %s", name)
+    case entry.validate != nil:
+        return entry.validate(options)
+    case options != nil:
+        return fmt.Errorf("%s takes no options", name)
+    }
+    return nil
+}
+
+// New builds the balancer registered under algorithm over existing backends
+// NewLoadBalancerWithOptions uses it after creating backends from configuration
+// Time Complexity: O(c) where c is the algorithm's construction cost
+// Space Complexity: O(c) for the algorithm's state
+func New(algorithm string, backends []Backend, options Options) (LoadBalancer, error) {
+    entry, ok := lookup(algorithm)
+    if !ok {
+        return nil, fmt.Errorf("unsupported load balancing algorithm: %s", algorithm)
+    }
+    return entry.constructor(backends, options)
+}
@@ -0,0 +1,133 @@
+package loadbalancer
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// testAlgorithmOptions is the options block of the algorithm registered by
+// TestRegisterAlgorithmOptions
+type testAlgorithmOptions struct {
+    Spread int    `yaml:"spread" default:"3"`
+    Label  string `yaml:"label" default:"none"`
+}
+
+// TestRegisterCustomAlgorithm verifies a registered algorithm is built by the
+// factory with its typed options and listed as supported
+func TestRegisterCustomAlgorithm(t *testing.T) {
+    var received config.HashConfig
+    Register("Test-Custom", WithOptions(
+        func(o Options) config.HashConfig { return o.Hash },
+        func(backends []Backend, cfg config.HashConfig) (LoadBalancer, error) {
+            received = cfg
+            return NewRoundRobinBalancer(backends), nil
+        },
+    ))
+
+    options := DefaultOptions()
+    options.Hash.Key = config.HashKeyPath
+    lb, err := NewLoadBalancerWithOptions("test-custom", testBackendConfigs(2), options)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if len(lb.GetBackends()) != 2 {
+        t.Errorf("Expected 2 backends, got %d", len(lb.GetBackends()))
+    }
+    if received.Key != config.HashKeyPath {
+        t.Errorf("Expected typed options to reach the constructor, got %+v", received)
+    }
+    if !slices.Contains(GetSupportedAlgorithms(), "test-custom") {
+        t.Errorf("Expected test-custom in %v", GetSupportedAlgorithms())
+    }
+
+    cfg := config.DefaultConfig()
+    cfg.LoadBalance.Algorithm = "test-custom"
+    cfg.LoadBalance.Backends = testBackendConfigs(1)
+    if err := cfg.Validate(Algorithms()); err != nil {
+        t.Errorf("Expected registered algorithm to validate, got %v", err)
+    }
+}
+
+// TestRegisterAlgorithmOptions verifies an algorithm decodes its own options
+// block with defaults and that configuration validation runs its checks
+func TestRegisterAlgorithmOptions(t *testing.T) {
+    var received testAlgorithmOptions
+    RegisterWithOptions("Test-Options",
+        func(o testAlgorithmOptions) error {
+            if o.Spread < 1 {
+                return errors.New("spread must be at least 1")
+            }
+            return nil
+        },
+        func(backends []Backend, _ Options, settings testAlgorithmOptions) (LoadBalancer, error) {
+            received = settings
+            return NewRoundRobinBalancer(backends), nil
+        },
+    )
+
+    var cfg config.Config
+    if err := yaml.Unmarshal([]byte(`
+loadBalance:
+  algorithm: test-options
+  options:
+    test-options:
+      spread: 5
+`), &cfg); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    options := DefaultOptions()
+    options.Algorithm = cfg.LoadBalance.Options.For(cfg.LoadBalance.Algorithm)
+    if _, err := NewLoadBalancerWithOptions("test-options", testBackendConfigs(2), options); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if received != (testAlgorithmOptions{Spread: 5, Label: "none"}) {
+        t.Errorf("Expected decoded options with defaults, got %+v", received)
+    }
+
+    valid := config.DefaultConfig()
+    valid.LoadBalance.Algorithm = "test-options"
+    valid.LoadBalance.Backends = testBackendConfigs(1)
+    valid.LoadBalance.Options = cfg.LoadBalance.Options
+    if err := valid.Validate(Algorithms()); err != nil {
+        t.Errorf("Expected valid options to pass, got %v", err)
+    }
+
+    invalid := *valid
+    if err := yaml.Unmarshal([]byte("test-options: {spread: 0}\nround-robin: {}\n"), &invalid.LoadBalance.Options); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    err := invalid.Validate(Algorithms())
+    for _, want := range []string{"loadBalance.options.test-options: spread must be at least 1", "loadBalance.options.round-robin: round-robin takes no options"} {
+        if err == nil || !strings.Contains(err.Error(), want) {
+            t.Errorf("Expected error containing %q, got %v", want, err)
+        }
+    }
+    if _, err := NewLoadBalancerWithOptions("test-options", testBackendConfigs(1), Options{Algorithm: invalid.LoadBalance.Options.For("test-options")}); err == nil {
+        t.Error("Expected constructor to reject invalid options")
+    }
+}
+
+// TestRegisterDuplicatePanics verifies an algorithm name cannot be registered twice
+func TestRegisterDuplicatePanics(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("Expected panic on duplicate registration")
+        }
+    }()
+    Register(string(RoundRobin), func(backends []Backend, _ Options) (LoadBalancer, error) {
+        return NewRoundRobinBalancer(backends), nil
+    })
+}
+
+// TestUnsupportedAlgorithm verifies unknown names are rejected
+func TestUnsupportedAlgorithm(t *testing.T) {
+    if _, err := NewLoadBalancer("no-such-algorithm", testBackendConfigs(1)); err == nil {
+        t.Error("Expected error for unsupported algorithm")
+    }
+}
@@ -1,7 +1,9 @@
 package loadbalancer
 
 import (
+	"context"
 	"errors"
+	"math/rand/v2"
 	"net/http"
 	"sync"
 )
@@ -15,6 +17,7 @@ type RoundRobinBalancer struct {
     backends []Backend    // List of all configured backends
     current  int          // Current position in round-robin cycle
     mutex    sync.RWMutex // Protects current counter and backends slice
+    healthSubscribers
 }
 
 // NewRoundRobinBalancer creates a new round-robin load balancer
@@ -44,7 +47,10 @@ func (rb *RoundRobinBalancer) SelectBackend(req *http.Request) (Backend, error)
 
     // Try each backend starting from current position
     // This ensures even distribution and handles unhealthy backends gracefully
+    // Backends in slow start take their turn only for their share of draws;
+    // the first one passed over is used if no other backend is available
     start := rb.current
+    var fallback Backend
     for {
         backend := rb.backends[rb.current]
         
@@ -52,35 +58,40 @@ func (rb *RoundRobinBalancer) SelectBackend(req *http.Request) (Backend, error)
         // Modulo operation ensures wrap-around at end of list
         rb.current = (rb.current + 1) % len(rb.backends)
 
-        // Return backend if healthy, otherwise continue searching
-        if backend.IsHealthy() {
-            return backend, nil
+        // Return backend if available, otherwise continue searching
+        if backend.IsAvailable() {
+            if admit(backend, rand.Float64()) {
+                return backend, nil
+            }
+            if fallback == nil {
+                fallback = backend
+            }
         }
 
         // If we've checked all backends without finding healthy one
         // This prevents infinite loop when all backends are unhealthy
         if rb.current == start {
+            if fallback != nil {
+                return fallback, nil
+            }
             return nil, errors.New("no healthy backends available")
         }
     }
 }
 
 // UpdateBackendHealth updates health status for specified backend URL
-// Uses linear search to find backend by URL (could be optimized with map)
-// Write lock ensures thread safety during health status updates
+// Uses linear search to find backend by URL; health itself is atomic, so a read
+// lock is enough. Subscribers are notified after the lock is released when the
+// status actually changed
 // Time Complexity: O(n) for linear search through backend list
 // Space Complexity: O(1) - no additional allocations
 func (rb *RoundRobinBalancer) UpdateBackendHealth(url string, healthy bool) {
-    rb.mutex.Lock()
-    defer rb.mutex.Unlock()
+    rb.mutex.RLock()
+    event, changed := setBackendHealth(rb.backends, url, healthy)
+    rb.mutex.RUnlock()
 
-    // Linear search for backend with matching URL
-    // Could be optimized with URL->Backend map for O(1) lookup
-    for _, backend := range rb.backends {
-        if backend.GetURL() == url {
-            backend.SetHealthy(healthy)
-            return
-        }
+    if changed {
+        rb.publish(event)
     }
 }
 
@@ -97,4 +108,51 @@ func (rb *RoundRobinBalancer) GetBackends() []Backend {
     backends := make([]Backend, len(rb.backends))
     copy(backends, rb.backends)
     return backends
+}
+
+// AddBackend adds backend to the pool at the end of the rotation
+// Time Complexity: O(n) for the duplicate check and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (rb *RoundRobinBalancer) AddBackend(backend Backend) error {
+    rb.mutex.Lock()
+    defer rb.mutex.Unlock()
+
+    backends, err := withBackend(rb.backends, backend)
+    if err != nil {
+        return err
+    }
+    rb.backends = backends
+    return nil
+}
+
+// RemoveBackend removes the backend with url from the pool at once
+// Requests already sent to it are unaffected; use DrainBackend to wait for them
+// Time Complexity: O(n) for the search and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (rb *RoundRobinBalancer) RemoveBackend(url string) error {
+    rb.mutex.Lock()
+    defer rb.mutex.Unlock()
+
+    backends, index, err := withoutBackend(rb.backends, url)
+    if err != nil {
+        return err
+    }
+    rb.backends = backends
+
+    // Keep the rotation position on the backend that was due next
+    if index < rb.current {
+        rb.current--
+    }
+    if rb.current >= len(rb.backends) {
+        rb.current = 0
+    }
+    return nil
+}
+
+// DrainBackend stops selecting the backend with url and removes it once its
+// in-flight requests have finished or returns the context error if ctx ends first
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (rb *RoundRobinBalancer) DrainBackend(ctx context.Context, url string) error {
+    return drainBackend(ctx, rb, url)
 }
\ No newline at end of file
@@ -0,0 +1,33 @@
+package loadbalancer
+
+import (
+	"math"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// slowStartFactor returns the share of its traffic a backend receives after
+// warming up for elapsed: MinWeight at first, reaching 1 at the end of Window
+// The ramp is (elapsed/window)^(1/aggression), so aggression 1 is linear
+// Time Complexity: O(1) - arithmetic only
+// Space Complexity: O(1) - no allocations
+func slowStartFactor(cfg config.SlowStartConfig, elapsed time.Duration) float64 {
+    if !cfg.Enabled || cfg.Window <= 0 || elapsed >= cfg.Window {
+        return 1
+    }
+    progress := max(float64(elapsed), 0) / float64(cfg.Window)
+    aggression := cfg.Aggression
+    if aggression <= 0 {
+        aggression = 1
+    }
+    return max(math.Pow(progress, 1/aggression), cfg.MinWeight)
+}
+
+// admit reports whether a selection of backend stands, given a draw in [0, 1)
+// Backends in slow start are admitted for the fraction of draws equal to their
+// slow start factor, so algorithms without weights ramp them up the same way
+// weighted round-robin does with effective weights
+func admit(backend Backend, draw float64) bool {
+    return draw < backend.SlowStartFactor()
+}
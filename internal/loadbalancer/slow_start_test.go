@@ -0,0 +1,152 @@
+package loadbalancer
+
+import (
+	"fmt"
+	"math"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// testSlowStartConfig returns slow start settings with a long window
+func testSlowStartConfig() config.SlowStartConfig {
+    cfg := config.DefaultSlowStartConfig()
+    cfg.Enabled = true
+    cfg.Window = time.Hour
+    return cfg
+}
+
+// TestSlowStartFactor verifies the ramp starts at the minimum weight, follows
+// the aggression curve and ends at the full share
+func TestSlowStartFactor(t *testing.T) {
+    cfg := config.SlowStartConfig{Enabled: true, Window: 100 * time.Second, MinWeight: 0.1, Aggression: 1}
+    cases := []struct {
+        aggression float64
+        elapsed    time.Duration
+        expected   float64
+    }{
+        {1, 0, 0.1},
+        {1, 5 * time.Second, 0.1},
+        {1, 50 * time.Second, 0.5},
+        {2, 25 * time.Second, 0.5},
+        {1, 100 * time.Second, 1},
+        {1, time.Hour, 1},
+    }
+    for _, c := range cases {
+        cfg.Aggression = c.aggression
+        if got := slowStartFactor(cfg, c.elapsed); math.Abs(got-c.expected) > 1e-9 {
+            t.Errorf("Expected factor %.2f after %v with aggression %g, got %.2f", c.expected, c.elapsed, c.aggression, got)
+        }
+    }
+
+    cfg.Enabled = false
+    if got := slowStartFactor(cfg, 0); got != 1 {
+        t.Errorf("Expected factor 1 when disabled, got %.2f", got)
+    }
+}
+
+// TestSlowStartTriggers verifies recovery, return from ejection and being
+// added start slow start, while backends configured at startup begin warm
+func TestSlowStartTriggers(t *testing.T) {
+    backend, _ := NewHTTPBackend("http://backend-a", 1)
+    backend.SetSlowStart(testSlowStartConfig())
+    if got := backend.SlowStartFactor(); got != 1 {
+        t.Fatalf("Expected new backend to be warm, got factor %.2f", got)
+    }
+
+    backend.SetHealthy(false)
+    backend.SetHealthy(true)
+    if got := backend.SlowStartFactor(); got >= 0.2 {
+        t.Errorf("Expected recovered backend to warm up, got factor %.2f", got)
+    }
+
+    backend.warmingFrom.Store(0)
+    backend.SetEjected(true)
+    backend.SetEjected(false)
+    if got := backend.SlowStartFactor(); got >= 0.2 {
+        t.Errorf("Expected returning backend to warm up, got factor %.2f", got)
+    }
+
+    added, _ := NewHTTPBackend("http://backend-b", 1)
+    added.SetSlowStart(testSlowStartConfig())
+    lb := NewRoundRobinBalancer([]Backend{backend})
+    if err := lb.AddBackend(added); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if got := added.SlowStartFactor(); got >= 0.2 {
+        t.Errorf("Expected added backend to warm up, got factor %.2f", got)
+    }
+
+    // The window ending clears the warm-up
+    added.warmingFrom.Store(time.Now().Add(-2 * time.Hour).UnixNano())
+    if got := added.SlowStartFactor(); got != 1 || added.warmingFrom.Load() != 0 {
+        t.Errorf("Expected warm backend after the window, got factor %.2f", got)
+    }
+}
+
+// TestSlowStartAcrossAlgorithms verifies every algorithm sends a recovering
+// backend only a small share of traffic at the start of its window
+func TestSlowStartAcrossAlgorithms(t *testing.T) {
+    options := DefaultOptions()
+    options.SlowStart = testSlowStartConfig()
+
+    for _, algorithm := range GetSupportedAlgorithms() {
+        lb, err := NewLoadBalancerWithOptions(algorithm, []config.BackendConfig{{URL: "http://backend-a"}, {URL: "http://backend-b"}}, options)
+        if err != nil {
+            t.Fatalf("%s: unexpected error: %v", algorithm, err)
+        }
+        warming := lb.GetBackends()[0]
+        lb.UpdateBackendHealth(warming.GetURL(), false)
+        lb.UpdateBackendHealth(warming.GetURL(), true)
+
+        const requests = 2000
+        count := 0
+        var inFlight []Backend
+        for i := 0; i < requests; i++ {
+            req := httptest.NewRequest("GET", "/", nil)
+            req.RemoteAddr = fmt.Sprintf("10.0.%d.%d:1234", i/256, i%256)
+            backend, err := lb.SelectBackend(req)
+            if err != nil {
+                t.Fatalf("%s: unexpected error: %v", algorithm, err)
+            }
+            if backend == warming {
+                count++
+            }
+            backend.IncrementConnections()
+            inFlight = append(inFlight, backend)
+        }
+        for _, backend := range inFlight {
+            backend.DecrementConnections()
+        }
+
+        if count == 0 || count > requests/4 {
+            t.Errorf("%s: expected a small share for the warming backend, got %d of %d", algorithm, count, requests)
+        }
+    }
+}
+
+// TestSlowStartFallback verifies a warming backend still serves when it is
+// the only one available
+func TestSlowStartFallback(t *testing.T) {
+    options := DefaultOptions()
+    options.SlowStart = testSlowStartConfig()
+
+    for _, algorithm := range GetSupportedAlgorithms() {
+        lb, err := NewLoadBalancerWithOptions(algorithm, testBackendConfigs(2), options)
+        if err != nil {
+            t.Fatalf("%s: unexpected error: %v", algorithm, err)
+        }
+        backends := lb.GetBackends()
+        lb.UpdateBackendHealth(backends[0].GetURL(), false)
+        lb.UpdateBackendHealth(backends[1].GetURL(), false)
+        lb.UpdateBackendHealth(backends[0].GetURL(), true)
+
+        for i := 0; i < 50; i++ {
+            if backend, err := lb.SelectBackend(httptest.NewRequest("GET", "/", nil)); err != nil || backend != backends[0] {
+                t.Fatalf("%s: expected warming backend, got %v (err %v)", algorithm, backend, err)
+            }
+        }
+    }
+}
@@ -0,0 +1,255 @@
+package loadbalancer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// Sides of a split, also used as sticky cookie values
+const (
+    SplitStable = "stable"
+    SplitCanary = "canary"
+)
+
+// ErrSplitMembership is returned when adding a backend to a split directly
+var ErrSplitMembership = errors.New("backends are added to the stable or canary pool, not the split")
+
+// SplitTarget is the side of a split chosen for a request
+type SplitTarget struct {
+    Side      string       // SplitStable or SplitCanary
+    Pool      string       // Name of the pool on that side
+    Balancer  LoadBalancer // Load balancer of that pool
+    ByPercent bool         // Chosen by the percentage, the only choice a sticky cookie pins
+}
+
+// requestMatcher tests a header or cookie of a request
+type requestMatcher struct {
+    name  string
+    value string
+    regex *regexp.Regexp
+}
+
+// SplitBalancer divides traffic between a stable and a canary load balancer
+// Requests matching a header or cookie condition go to the canary, and of the
+// rest a configured percentage does too. Each side keeps its own
+// algorithm, health and backend state; the split only chooses between them
+// Time Complexity: O(m) per selection where m is number of match conditions,
+// plus the chosen balancer's selection
+// Space Complexity: O(m) for compiled match conditions
+type SplitBalancer struct {
+    config  config.SplitConfig
+    stable  SplitTarget
+    canary  SplitTarget
+    headers []requestMatcher
+    cookies []requestMatcher
+    percent atomic.Uint64 // math.Float64bits of the canary percentage
+}
+
+// NewSplitBalancer composes the stable and canary balancers as cfg describes
+// Time Complexity: O(m) where m is number of match conditions
+// Space Complexity: O(m) for compiled match conditions
+func NewSplitBalancer(cfg config.SplitConfig, stable, canary LoadBalancer) (*SplitBalancer, error) {
+    s := &SplitBalancer{
+        config: cfg,
+        stable: SplitTarget{Side: SplitStable, Pool: cfg.Stable, Balancer: stable},
+        canary: SplitTarget{Side: SplitCanary, Pool: cfg.Canary, Balancer: canary},
+    }
+    var err error
+    if s.headers, err = compileMatchers(cfg.Headers); err != nil {
+        return nil, err
+    }
+    if s.cookies, err = compileMatchers(cfg.Cookies); err != nil {
+        return nil, err
+    }
+    if err := s.SetPercent(cfg.Percent); err != nil {
+        return nil, err
+    }
+    return s, nil
+}
+
+// compileMatchers compiles name/value/regex conditions
+func compileMatchers(matches []config.HeaderMatchConfig) ([]requestMatcher, error) {
+    matchers := make([]requestMatcher, 0, len(matches))
+    for _, match := range matches {
+        matcher := requestMatcher{name: match.Name, value: match.Value}
+        if match.Regex != "" {
+            regex, err := regexp.Compile(match.Regex)
+            if err != nil {
+                return nil, fmt.Errorf("invalid regex for %s: %w", match.Name, err)
+            }
+            matcher.regex = regex
+        }
+        matchers = append(matchers, matcher)
+    }
+    return matchers, nil
+}
+
+// matches reports whether value satisfies the condition; present is false when
+// the header or cookie is missing, and an empty condition only requires presence
+func (m requestMatcher) matches(value string, present bool) bool {
+    switch {
+    case !present:
+        return false
+    case m.regex != nil:
+        return m.regex.MatchString(value)
+    case m.value != "":
+        return value == m.value
+    default:
+        return true
+    }
+}
+
+// Percent returns the share of unmatched traffic sent to the canary
+func (s *SplitBalancer) Percent() float64 {
+    return math.Float64frombits(s.percent.Load())
+}
+
+// SetPercent changes the share of unmatched traffic sent to the canary
+// Takes effect for the next request; safe to call while traffic flows
+// Time Complexity: O(1) - atomic store
+// Space Complexity: O(1) - no allocations
+func (s *SplitBalancer) SetPercent(percent float64) error {
+    if percent < 0 || percent > 100 || math.IsNaN(percent) {
+        return fmt.Errorf("canary percent must be between 0 and 100, got %g", percent)
+    }
+    s.percent.Store(math.Float64bits(percent))
+    return nil
+}
+
+// Choose picks the side of the split for req
+// Header and cookie conditions send a request to the canary first; a sticky
+// cookie then keeps the client's side while it still receives traffic; the
+// rest is divided by the current percentage
+// Time Complexity: O(m) where m is number of match conditions
+// Space Complexity: O(1) - no allocations beyond cookie parsing
+func (s *SplitBalancer) Choose(req *http.Request) SplitTarget {
+    for _, matcher := range s.headers {
+        values, present := req.Header[http.CanonicalHeaderKey(matcher.name)]
+        value := ""
+        if present && len(values) > 0 {
+            value = values[0]
+        }
+        if matcher.matches(value, present) {
+            return s.canary
+        }
+    }
+    for _, matcher := range s.cookies {
+        cookie, err := req.Cookie(matcher.name)
+        if err == nil && matcher.matches(cookie.Value, true) {
+            return s.canary
+        }
+    }
+
+    percent := s.Percent()
+    if s.config.Sticky.Enabled {
+        if cookie, err := req.Cookie(s.config.Sticky.Cookie); err == nil {
+            switch {
+            case cookie.Value == SplitCanary && percent > 0:
+                return s.canary
+            case cookie.Value == SplitStable && percent < 100:
+                return s.stable
+            }
+        }
+    }
+
+    target := s.stable
+    if rand.Float64()*100 < percent {
+        target = s.canary
+    }
+    target.ByPercent = true
+    return target
+}
+
+// StickyCookie returns the cookie pinning the client of req to target, or nil
+// when stickiness is disabled, the request already carries that pin or target
+// was not chosen by the percentage; a one-off header or cookie match never pins
+// Time Complexity: O(1) - single cookie lookup
+// Space Complexity: O(1) - single cookie
+func (s *SplitBalancer) StickyCookie(req *http.Request, target SplitTarget) *http.Cookie {
+    sticky := s.config.Sticky
+    if !sticky.Enabled || !target.ByPercent {
+        return nil
+    }
+    if cookie, err := req.Cookie(sticky.Cookie); err == nil && cookie.Value == target.Side {
+        return nil
+    }
+    return &http.Cookie{
+        Name:     sticky.Cookie,
+        Value:    target.Side,
+        Path:     "/",
+        MaxAge:   int(sticky.TTL.Seconds()),
+        Secure:   sticky.Secure,
+        HttpOnly: sticky.HTTPOnly,
+        SameSite: http.SameSiteLaxMode,
+    }
+}
+
+// SelectBackend chooses a side for req and selects a backend from its pool
+// Time Complexity: O(m) plus the chosen balancer's selection
+// Space Complexity: O(1) - no additional allocations
+func (s *SplitBalancer) SelectBackend(req *http.Request) (Backend, error) {
+    return s.Choose(req).Balancer.SelectBackend(req)
+}
+
+// UpdateBackendHealth forwards the health update to both sides
+// Time Complexity: O(n) across both pools
+// Space Complexity: O(1) - no additional allocations
+func (s *SplitBalancer) UpdateBackendHealth(url string, healthy bool) {
+    s.stable.Balancer.UpdateBackendHealth(url, healthy)
+    s.canary.Balancer.UpdateBackendHealth(url, healthy)
+}
+
+// GetBackends returns the backends of both sides, stable first
+// Time Complexity: O(n) for the copies
+// Space Complexity: O(n) for the combined slice
+func (s *SplitBalancer) GetBackends() []Backend {
+    return append(s.stable.Balancer.GetBackends(), s.canary.Balancer.GetBackends()...)
+}
+
+// Subscribe registers fn with both sides and returns the function removing both
+// Time Complexity: O(1) - two subscriptions
+// Space Complexity: O(1) per subscriber
+func (s *SplitBalancer) Subscribe(fn func(HealthEvent)) func() {
+    stable := s.stable.Balancer.Subscribe(fn)
+    canary := s.canary.Balancer.Subscribe(fn)
+    return func() {
+        stable()
+        canary()
+    }
+}
+
+// AddBackend rejects the backend: membership belongs to the pools of a split
+func (s *SplitBalancer) AddBackend(backend Backend) error {
+    return fmt.Errorf("%w: %s", ErrSplitMembership, backend.GetURL())
+}
+
+// RemoveBackend removes the backend with url from whichever side holds it
+// Time Complexity: O(n) across both pools
+// Space Complexity: O(n) for the side's new backend slice
+func (s *SplitBalancer) RemoveBackend(url string) error {
+    return s.sideOf(url).RemoveBackend(url)
+}
+
+// DrainBackend drains the backend with url from whichever side holds it
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (s *SplitBalancer) DrainBackend(ctx context.Context, url string) error {
+    return s.sideOf(url).DrainBackend(ctx, url)
+}
+
+// sideOf returns the balancer holding url, the stable one when neither does
+// so the caller receives its ErrBackendNotFound
+func (s *SplitBalancer) sideOf(url string) LoadBalancer {
+    if indexOf(s.canary.Balancer.GetBackends(), url) >= 0 {
+        return s.canary.Balancer
+    }
+    return s.stable.Balancer
+}
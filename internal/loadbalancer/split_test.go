@@ -0,0 +1,155 @@
+package loadbalancer
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newTestSplit composes two single-backend pools into a split
+func newTestSplit(t *testing.T, cfg config.SplitConfig) *SplitBalancer {
+    t.Helper()
+    stable, _ := NewLoadBalancer(string(RoundRobin), []config.BackendConfig{{URL: "http://stable"}})
+    canary, _ := NewLoadBalancer(string(RoundRobin), []config.BackendConfig{{URL: "http://canary"}})
+    cfg.Stable, cfg.Canary = "stable-pool", "canary-pool"
+    split, err := NewSplitBalancer(cfg, stable, canary)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return split
+}
+
+// TestSplitPercent verifies unmatched traffic is divided by the percentage
+func TestSplitPercent(t *testing.T) {
+    split := newTestSplit(t, config.SplitConfig{Percent: 20})
+
+    canary := 0
+    for i := 0; i < 5000; i++ {
+        target := split.Choose(httptest.NewRequest("GET", "/", nil))
+        if target.Side == SplitCanary {
+            canary++
+            if target.Pool != "canary-pool" {
+                t.Fatalf("Expected canary pool, got %s", target.Pool)
+            }
+        }
+    }
+    if canary < 800 || canary > 1200 {
+        t.Errorf("Expected about 1000 canary requests at 20%%, got %d", canary)
+    }
+
+    if err := split.SetPercent(101); err == nil {
+        t.Error("Expected error for percentage above 100")
+    }
+    if err := split.SetPercent(0); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    for i := 0; i < 100; i++ {
+        if backend, _ := split.SelectBackend(httptest.NewRequest("GET", "/", nil)); backend.GetURL() != "http://stable" {
+            t.Fatalf("Expected stable backend at 0%%, got %s", backend.GetURL())
+        }
+    }
+}
+
+// TestSplitMatches verifies header and cookie conditions force the canary
+func TestSplitMatches(t *testing.T) {
+    split := newTestSplit(t, config.SplitConfig{
+        Headers: []config.HeaderMatchConfig{{Name: "X-Canary", Value: "1"}},
+        Cookies: []config.HeaderMatchConfig{{Name: "beta", Regex: "^(yes|true)$"}},
+    })
+
+    cases := []struct {
+        header, cookie string
+        expected       string
+    }{
+        {"", "", SplitStable},
+        {"1", "", SplitCanary},
+        {"0", "", SplitStable},
+        {"", "true", SplitCanary},
+        {"", "no", SplitStable},
+    }
+    for _, c := range cases {
+        req := httptest.NewRequest("GET", "/", nil)
+        if c.header != "" {
+            req.Header.Set("X-Canary", c.header)
+        }
+        if c.cookie != "" {
+            req.AddCookie(&http.Cookie{Name: "beta", Value: c.cookie})
+        }
+        if got := split.Choose(req).Side; got != c.expected {
+            t.Errorf("Header %q cookie %q: expected %s, got %s", c.header, c.cookie, c.expected, got)
+        }
+    }
+}
+
+// TestSplitSticky verifies pins hold while their side receives traffic and are
+// only set for choices made by the percentage
+func TestSplitSticky(t *testing.T) {
+    split := newTestSplit(t, config.SplitConfig{
+        Percent: 50,
+        Headers: []config.HeaderMatchConfig{{Name: "X-Canary", Value: "1"}},
+        Sticky:  config.SplitStickyConfig{Enabled: true, Cookie: "variant"},
+    })
+
+    pinned := func(side string) *http.Request {
+        req := httptest.NewRequest("GET", "/", nil)
+        req.AddCookie(&http.Cookie{Name: "variant", Value: side})
+        return req
+    }
+    for i := 0; i < 50; i++ {
+        if got := split.Choose(pinned(SplitCanary)).Side; got != SplitCanary {
+            t.Fatalf("Expected canary pin to hold, got %s", got)
+        }
+    }
+    if cookie := split.StickyCookie(pinned(SplitCanary), split.canary); cookie != nil {
+        t.Errorf("Expected no cookie for a request already pinned, got %v", cookie)
+    }
+    unpinned := httptest.NewRequest("GET", "/", nil)
+    if target := split.Choose(unpinned); !target.ByPercent {
+        t.Errorf("Expected an unmatched request to be chosen by the percentage")
+    } else if cookie := split.StickyCookie(unpinned, target); cookie == nil || cookie.Value != target.Side {
+        t.Errorf("Expected %s pin cookie, got %v", target.Side, cookie)
+    }
+
+    matched := pinned(SplitStable)
+    matched.Header.Set("X-Canary", "1")
+    if target := split.Choose(matched); target.Side != SplitCanary {
+        t.Errorf("Expected header match to reach the canary, got %s", target.Side)
+    } else if cookie := split.StickyCookie(matched, target); cookie != nil {
+        t.Errorf("Expected no pin for a header match, got %v", cookie)
+    }
+
+    split.SetPercent(0)
+    if got := split.Choose(pinned(SplitCanary)).Side; got != SplitStable {
+        t.Errorf("Expected canary pin to be ignored at 0%%, got %s", got)
+    }
+}
+
+// TestSplitMembership verifies health and removal reach the owning pool
+func TestSplitMembership(t *testing.T) {
+    split := newTestSplit(t, config.SplitConfig{Percent: 100})
+
+    if len(split.GetBackends()) != 2 {
+        t.Fatalf("Expected backends of both pools, got %d", len(split.GetBackends()))
+    }
+    split.UpdateBackendHealth("http://canary", false)
+    if _, err := split.SelectBackend(httptest.NewRequest("GET", "/", nil)); err == nil {
+        t.Error("Expected error when the chosen side has no healthy backend")
+    }
+
+    backend, _ := NewHTTPBackend("http://extra", 1)
+    if err := split.AddBackend(backend); !errors.Is(err, ErrSplitMembership) {
+        t.Errorf("Expected ErrSplitMembership, got %v", err)
+    }
+    if err := split.RemoveBackend("http://canary"); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if len(split.canary.Balancer.GetBackends()) != 0 {
+        t.Error("Expected backend removed from the canary pool")
+    }
+    if err := split.RemoveBackend("http://unknown"); !errors.Is(err, ErrBackendNotFound) {
+        t.Errorf("Expected ErrBackendNotFound, got %v", err)
+    }
+}
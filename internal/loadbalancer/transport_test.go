@@ -0,0 +1,78 @@
+package loadbalancer
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// TestNewTransportSettings verifies pool tuning is applied to the transport
+func TestNewTransportSettings(t *testing.T) {
+    cfg := config.DefaultTransportConfig()
+    cfg.MaxIdleConnsPerHost = 64
+    cfg.ResponseHeaderTimeout = 5 * time.Second
+    cfg.HTTP2 = false
+
+    transport := NewTransport(cfg)
+    if transport.MaxIdleConnsPerHost != 64 {
+        t.Errorf("Expected 64 idle connections per host, got %d", transport.MaxIdleConnsPerHost)
+    }
+    if transport.ResponseHeaderTimeout != 5*time.Second {
+        t.Errorf("Expected response header timeout 5s, got %v", transport.ResponseHeaderTimeout)
+    }
+    if transport.ForceAttemptHTTP2 || transport.TLSNextProto == nil {
+        t.Error("Expected HTTP/2 to be disabled")
+    }
+}
+
+// TestNewTransportH2C verifies plain http:// backends are reached over HTTP/2
+func TestNewTransportH2C(t *testing.T) {
+    backend := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.WriteString(w, r.Proto)
+    }))
+    backend.Config.Protocols = &http.Protocols{}
+    backend.Config.Protocols.SetHTTP1(true)
+    backend.Config.Protocols.SetUnencryptedHTTP2(true)
+    backend.Start()
+    defer backend.Close()
+
+    cfg := config.DefaultTransportConfig()
+    cfg.H2C = true
+    client := &http.Client{Transport: NewTransport(cfg)}
+    resp, err := client.Get(backend.URL)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    defer resp.Body.Close()
+    if proto, _ := io.ReadAll(resp.Body); string(proto) != "HTTP/2.0" {
+        t.Errorf("Expected the backend to see HTTP/2.0, got %q", proto)
+    }
+}
+
+// TestBackendsShareTransport verifies backends of a pool reuse one connection pool
+// while a backend with its own TLS settings gets a separate transport
+func TestBackendsShareTransport(t *testing.T) {
+    lb, err := NewLoadBalancerWithOptions("round-robin", []config.BackendConfig{
+        {URL: "http://a.internal", Weight: 1},
+        {URL: "http://b.internal", Weight: 1},
+        {URL: "https://c.internal", Weight: 1, TLS: config.BackendTLSConfig{ServerName: "c.example.com"}},
+    }, DefaultOptions())
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    backends := lb.GetBackends()
+    if backends[0].GetTransport() != backends[1].GetTransport() {
+        t.Error("Expected backends without TLS settings to share a transport")
+    }
+    if backends[2].GetTransport() == backends[0].GetTransport() {
+        t.Error("Expected backend with TLS settings to have its own transport")
+    }
+    if transport := backends[2].GetTransport().(*http.Transport); transport.TLSClientConfig.ServerName != "c.example.com" {
+        t.Errorf("Expected server name c.example.com, got %q", transport.TLSClientConfig.ServerName)
+    }
+}
@@ -1,6 +1,7 @@
 package loadbalancer
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"sync"
@@ -12,9 +13,10 @@ import (
 // Time Complexity: O(n) for finding next backend with highest current weight
 // Space Complexity: O(n) for storing backend references and current weights
 type WeightedRoundRobinBalancer struct {
-    backends       []Backend // List of all configured backends
-    currentWeights []int     // Current weights for smooth distribution
+    backends       []Backend    // List of all configured backends
+    currentWeights []float64    // Current weights for smooth distribution
     mutex          sync.RWMutex // Protects backends and weights during updates
+    healthSubscribers
 }
 
 // NewWeightedRoundRobinBalancer creates new weighted round-robin load balancer
@@ -24,7 +26,7 @@ type WeightedRoundRobinBalancer struct {
 func NewWeightedRoundRobinBalancer(backends []Backend) *WeightedRoundRobinBalancer {
     return &WeightedRoundRobinBalancer{
         backends:       backends,
-        currentWeights: make([]int, len(backends)),
+        currentWeights: make([]float64, len(backends)),
     }
 }
 
@@ -41,22 +43,26 @@ func (wrr *WeightedRoundRobinBalancer) SelectBackend(req *http.Request) (Backend
         return nil, errors.New("no backends available")
     }
 
-    // Find healthy backend with highest current weight
+    // Find available backend with highest current weight
+    // Total weight of available backends is summed in the same pass so both
+    // use one consistent view of availability
+    // Backends in slow start count with their effective, ramped-up weight
     selectedIndex := -1
-    maxCurrentWeight := -1
+    totalWeight := 0.0
 
     for i, backend := range wrr.backends {
-        if !backend.IsHealthy() {
-            continue // Skip unhealthy backends
+        if !backend.IsAvailable() {
+            continue // Skip unhealthy or circuit-broken backends
         }
 
         // Add backend weight to current weight for smooth distribution
-        wrr.currentWeights[i] += backend.GetWeight()
+        weight := float64(backend.GetWeight()) * backend.SlowStartFactor()
+        wrr.currentWeights[i] += weight
+        totalWeight += weight
 
         // Select backend with highest current weight
-        if wrr.currentWeights[i] > maxCurrentWeight {
+        if selectedIndex == -1 || wrr.currentWeights[i] > wrr.currentWeights[selectedIndex] {
             selectedIndex = i
-            maxCurrentWeight = wrr.currentWeights[i]
         }
     }
 
@@ -64,14 +70,6 @@ func (wrr *WeightedRoundRobinBalancer) SelectBackend(req *http.Request) (Backend
         return nil, errors.New("no healthy backends available")
     }
 
-    // Calculate total weight of all healthy backends
-    totalWeight := 0
-    for _, backend := range wrr.backends {
-        if backend.IsHealthy() {
-            totalWeight += backend.GetWeight()
-        }
-    }
-
     // Subtract total weight from selected backend's current weight
     // This ensures smooth distribution over time
     wrr.currentWeights[selectedIndex] -= totalWeight
@@ -80,19 +78,18 @@ func (wrr *WeightedRoundRobinBalancer) SelectBackend(req *http.Request) (Backend
 }
 
 // UpdateBackendHealth updates health status for specified backend URL
-// Uses linear search to find backend by URL
-// Write lock ensures thread safety during health status updates
+// Uses linear search to find backend by URL; health itself is atomic, so a read
+// lock is enough. Subscribers are notified after the lock is released when the
+// status actually changed
 // Time Complexity: O(n) for linear search through backend list
 // Space Complexity: O(1) - no additional allocations
 func (wrr *WeightedRoundRobinBalancer) UpdateBackendHealth(url string, healthy bool) {
-    wrr.mutex.Lock()
-    defer wrr.mutex.Unlock()
+    wrr.mutex.RLock()
+    event, changed := setBackendHealth(wrr.backends, url, healthy)
+    wrr.mutex.RUnlock()
 
-    for _, backend := range wrr.backends {
-        if backend.GetURL() == url {
-            backend.SetHealthy(healthy)
-            return
-        }
+    if changed {
+        wrr.publish(event)
     }
 }
 
@@ -110,6 +107,11 @@ func (wrr *WeightedRoundRobinBalancer) GetBackends() []Backend {
     return backends
 }
 
+// resetWeights rebuilds current weights for the backend set; caller holds the lock
+func (wrr *WeightedRoundRobinBalancer) resetWeights() {
+    wrr.currentWeights = make([]float64, len(wrr.backends))
+}
+
 // UpdateBackendWeight updates weight for specified backend URL
 // Allows dynamic weight adjustment for traffic shaping
 // Write lock ensures thread safety during weight updates
@@ -125,4 +127,46 @@ func (wrr *WeightedRoundRobinBalancer) UpdateBackendWeight(url string, weight in
             return
         }
     }
+}
+
+// AddBackend adds backend to the pool
+// Current weights are rebuilt so the smooth rotation restarts over the new set
+// Time Complexity: O(n) for the duplicate check and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (wrr *WeightedRoundRobinBalancer) AddBackend(backend Backend) error {
+    wrr.mutex.Lock()
+    defer wrr.mutex.Unlock()
+
+    backends, err := withBackend(wrr.backends, backend)
+    if err != nil {
+        return err
+    }
+    wrr.backends = backends
+    wrr.resetWeights()
+    return nil
+}
+
+// RemoveBackend removes the backend with url from the pool at once
+// Requests already sent to it are unaffected; use DrainBackend to wait for them
+// Time Complexity: O(n) for the search and slice copy
+// Space Complexity: O(n) for the new backend slice
+func (wrr *WeightedRoundRobinBalancer) RemoveBackend(url string) error {
+    wrr.mutex.Lock()
+    defer wrr.mutex.Unlock()
+
+    backends, _, err := withoutBackend(wrr.backends, url)
+    if err != nil {
+        return err
+    }
+    wrr.backends = backends
+    wrr.resetWeights()
+    return nil
+}
+
+// DrainBackend stops selecting the backend with url and removes it once its
+// in-flight requests have finished or returns the context error if ctx ends first
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the backend snapshot
+func (wrr *WeightedRoundRobinBalancer) DrainBackend(ctx context.Context, url string) error {
+    return drainBackend(ctx, wrr, url)
 }
\ No newline at end of file
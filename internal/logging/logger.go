@@ -8,6 +8,7 @@ import (
 	"os"
 	"time"
 
+	"github.com/WillKirkmanM/proxy/internal/response"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
@@ -194,7 +195,7 @@ func (l *Logger) HTTPRequestLogger() func(next http.Handler) http.Handler {
             defer span.End()
             
             // Create response writer wrapper to capture status
-            wrapper := &responseWriter{ResponseWriter: w, statusCode: 200}
+            wrapper := response.NewWriter(w)
             
             // Process request with tracing context
             next.ServeHTTP(wrapper, r.WithContext(ctx))
@@ -205,7 +206,8 @@ func (l *Logger) HTTPRequestLogger() func(next http.Handler) http.Handler {
             l.Info(ctx, "HTTP request completed",
                 slog.String("method", r.Method),
                 slog.String("path", r.URL.Path),
-                slog.Int("status", wrapper.statusCode),
+                slog.Int("status", wrapper.Status()),
+                slog.Int64("bytes", wrapper.BytesWritten()),
                 slog.Duration("duration", duration),
                 slog.String("user_agent", r.UserAgent()),
                 slog.String("remote_addr", r.RemoteAddr),
@@ -213,29 +215,20 @@ func (l *Logger) HTTPRequestLogger() func(next http.Handler) http.Handler {
             
             // Add span attributes for tracing
             span.SetAttributes(
-                attribute.Int("http.status_code", wrapper.statusCode),
+                attribute.Int("http.status_code", wrapper.Status()),
+                attribute.Int64("http.response_content_length", wrapper.BytesWritten()),
                 attribute.String("http.response.duration", duration.String()),
             )
             
+            // gRPC failures hide behind 200 responses; their code is in grpc-status
+            if response.IsGRPC(r) {
+                span.SetAttributes(attribute.String("rpc.grpc.status_code", wrapper.GRPCStatus()))
+            }
+
             // Mark span as error for 4xx/5xx responses
-            if wrapper.statusCode >= 400 {
-                span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", wrapper.statusCode))
+            if wrapper.Status() >= 400 {
+                span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", wrapper.Status()))
             }
         })
     }
-}
-
-// responseWriter wraps http.ResponseWriter to capture response status
-// Used by HTTP logging middleware to record response codes
-// Implements ResponseWriter interface transparently
-type responseWriter struct {
-    http.ResponseWriter
-    statusCode int
-}
-
-// WriteHeader captures status code for logging
-// Preserves original ResponseWriter behavior while recording status
-func (w *responseWriter) WriteHeader(code int) {
-    w.statusCode = code
-    w.ResponseWriter.WriteHeader(code)
 }
\ No newline at end of file
@@ -1,10 +1,13 @@
 package metrics
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
     "time"
 
+    "github.com/WillKirkmanM/proxy/internal/certs"
+    "github.com/WillKirkmanM/proxy/internal/response"
     "github.com/prometheus/client_golang/prometheus"
     "github.com/prometheus/client_golang/prometheus/promhttp"
 )
@@ -12,11 +15,25 @@ import (
 // Metrics provides Prometheus metrics collection for proxy server
 // Tracks request counts, durations, and backend health for monitoring
 // Enables observability and performance analysis through metrics
+// Every per-backend series is labelled with pool and backend_url alike
 type Metrics struct {
-    requestsTotal    *prometheus.CounterVec   // Total requests by method and status
+    requestsTotal    *prometheus.CounterVec   // Total requests by method and status, grpc-status for gRPC calls
     requestDuration  *prometheus.HistogramVec // Request duration distribution
     backendHealth    *prometheus.GaugeVec     // Backend health status (0/1)
     activeConnections prometheus.Gauge         // Current active connections
+    backendConnections *prometheus.GaugeVec    // In-flight requests per backend, including upgraded connections
+    backendWebSockets *prometheus.GaugeVec     // Open WebSocket connections per backend
+    certificateExpiry *prometheus.GaugeVec     // TLS certificate expiry as Unix timestamp
+    retriesTotal      *prometheus.CounterVec   // Retried backend attempts by pool and reason
+    breakerState      *prometheus.GaugeVec     // Circuit breaker state per backend (0 closed, 1 open, 2 half-open)
+    breakerTransitions *prometheus.CounterVec  // Circuit breaker transitions per backend and target state
+    ejectionsTotal    *prometheus.CounterVec   // Outlier ejections by backend and reason
+    backendEjected    *prometheus.GaugeVec     // Backend ejection status (0/1)
+    splitRequests     *prometheus.CounterVec   // Requests by split and side
+    splitPercent      *prometheus.GaugeVec     // Current canary percentage by split
+    mirrorRequests    *prometheus.CounterVec   // Mirrored requests by pool and result
+    mirrorMismatches  *prometheus.CounterVec   // Shadow responses whose status differed from the pool's
+    mirrorLatencyDiff *prometheus.HistogramVec // Shadow minus pool response time
 }
 
 // NewMetrics creates new metrics collector with Prometheus instruments
@@ -28,7 +45,7 @@ func NewMetrics() *Metrics {
         requestsTotal: prometheus.NewCounterVec(
             prometheus.CounterOpts{
                 Name: "proxy_requests_total",
-                Help: "Total number of HTTP requests processed",
+                Help: "Total number of HTTP requests processed, labelled with grpc-status instead of the HTTP status for gRPC calls",
             },
             []string{"method", "status_code", "backend"},
         ),
@@ -45,7 +62,7 @@ func NewMetrics() *Metrics {
                 Name: "proxy_backend_health",
                 Help: "Backend health status (1=healthy, 0=unhealthy)",
             },
-            []string{"backend_url"},
+            []string{"pool", "backend_url"},
         ),
         activeConnections: prometheus.NewGauge(
             prometheus.GaugeOpts{
@@ -53,17 +70,141 @@ func NewMetrics() *Metrics {
                 Help: "Number of active connections",
             },
         ),
+        backendConnections: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_backend_active_connections",
+                Help: "Number of in-flight requests and upgraded connections per backend",
+            },
+            []string{"pool", "backend_url"},
+        ),
+        backendWebSockets: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_backend_open_websockets",
+                Help: "Number of open WebSocket connections per backend",
+            },
+            []string{"pool", "backend_url"},
+        ),
+        certificateExpiry: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_tls_certificate_expiry_timestamp_seconds",
+                Help: "Expiry time of served TLS certificates as Unix timestamp",
+            },
+            []string{"file", "common_name"},
+        ),
+        splitRequests: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_split_requests_total",
+                Help: "Total number of requests sent to each side of a traffic split",
+            },
+            []string{"split", "side"},
+        ),
+        splitPercent: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_split_canary_percent",
+                Help: "Percentage of unmatched traffic a split sends to its canary",
+            },
+            []string{"split"},
+        ),
+        mirrorRequests: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_mirror_requests_total",
+                Help: "Total number of sampled requests mirrored to a shadow backend by result",
+            },
+            []string{"pool", "result"},
+        ),
+        mirrorMismatches: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_mirror_status_mismatches_total",
+                Help: "Total number of shadow responses whose status differed from the pool's response",
+            },
+            []string{"pool", "status_code", "shadow_status_code"},
+        ),
+        mirrorLatencyDiff: prometheus.NewHistogramVec(
+            prometheus.HistogramOpts{
+                Name:    "proxy_mirror_latency_difference_seconds",
+                Help:    "Shadow backend response time minus the pool's response time in seconds",
+                Buckets: []float64{-2.5, -1, -0.5, -0.25, -0.1, -0.05, -0.01, 0, 0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5},
+            },
+            []string{"pool"},
+        ),
+        retriesTotal: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_retries_total",
+                Help: "Total number of failed backend attempts that were retried",
+            },
+            []string{"pool", "reason"},
+        ),
+        breakerState: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_circuit_breaker_state",
+                Help: "Circuit breaker state per backend (0=closed, 1=open, 2=half-open)",
+            },
+            []string{"pool", "backend_url"},
+        ),
+        breakerTransitions: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_circuit_breaker_transitions_total",
+                Help: "Total number of circuit breaker state transitions",
+            },
+            []string{"pool", "backend_url", "from", "to"},
+        ),
+        ejectionsTotal: prometheus.NewCounterVec(
+            prometheus.CounterOpts{
+                Name: "proxy_outlier_ejections_total",
+                Help: "Total number of backends ejected by outlier detection",
+            },
+            []string{"pool", "backend_url", "reason"},
+        ),
+        backendEjected: prometheus.NewGaugeVec(
+            prometheus.GaugeOpts{
+                Name: "proxy_backend_ejected",
+                Help: "Backend ejection status by outlier detection (1=ejected, 0=in rotation)",
+            },
+            []string{"pool", "backend_url"},
+        ),
     }
 
     // Register metrics with Prometheus
-    prometheus.MustRegister(m.requestsTotal)
-    prometheus.MustRegister(m.requestDuration)
-    prometheus.MustRegister(m.backendHealth)
-    prometheus.MustRegister(m.activeConnections)
+    // Reuses already registered collectors so multiple collectors can coexist
+    m.requestsTotal = register(m.requestsTotal)
+    m.requestDuration = register(m.requestDuration)
+    m.backendHealth = register(m.backendHealth)
+    m.activeConnections = register(m.activeConnections)
+    m.backendConnections = register(m.backendConnections)
+    m.backendWebSockets = register(m.backendWebSockets)
+    m.certificateExpiry = register(m.certificateExpiry)
+    m.retriesTotal = register(m.retriesTotal)
+    m.breakerState = register(m.breakerState)
+    m.breakerTransitions = register(m.breakerTransitions)
+    m.ejectionsTotal = register(m.ejectionsTotal)
+    m.backendEjected = register(m.backendEjected)
+    m.splitRequests = register(m.splitRequests)
+    m.splitPercent = register(m.splitPercent)
+    m.mirrorRequests = register(m.mirrorRequests)
+    m.mirrorMismatches = register(m.mirrorMismatches)
+    m.mirrorLatencyDiff = register(m.mirrorLatencyDiff)
 
     return m
 }
 
+// register adds collector to the default Prometheus registry
+// Returns the existing collector when an identical one is already registered,
+// which happens when several servers are created in one process (e.g. tests)
+// Time Complexity: O(1) - registry lookup
+// Space Complexity: O(1) - no additional allocations
+func register[T prometheus.Collector](collector T) T {
+    if err := prometheus.Register(collector); err != nil {
+        var alreadyRegistered prometheus.AlreadyRegisteredError
+        if errors.As(err, &alreadyRegistered) {
+            if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+                return existing
+            }
+        }
+        panic(err)
+    }
+    return collector
+}
+
 // RecordRequest records HTTP request metrics including duration and status
 // Called by middleware to track request statistics
 // Time Complexity: O(1) - metric recording
@@ -73,16 +214,27 @@ func (m *Metrics) RecordRequest(method, statusCode, backend string, duration tim
     m.requestDuration.WithLabelValues(method, backend).Observe(duration.Seconds())
 }
 
-// UpdateBackendHealth updates health metric for specified backend
+// UpdateBackendHealth updates health metric for specified backend of pool
 // Called by health check system to track backend availability
 // Time Complexity: O(1) - metric update
 // Space Complexity: O(1) - no additional allocations
-func (m *Metrics) UpdateBackendHealth(backendURL string, healthy bool) {
+func (m *Metrics) UpdateBackendHealth(pool, backendURL string, healthy bool) {
     value := 0.0
     if healthy {
         value = 1.0
     }
-    m.backendHealth.WithLabelValues(backendURL).Set(value)
+    m.backendHealth.WithLabelValues(pool, backendURL).Set(value)
+}
+
+// SetCertificateExpiry replaces expiry metrics with the given certificates
+// Resetting first drops series for certificates that are no longer served
+// Time Complexity: O(c) where c is number of certificates
+// Space Complexity: O(c) for metric series
+func (m *Metrics) SetCertificateExpiry(certificates []certs.CertificateInfo) {
+    m.certificateExpiry.Reset()
+    for _, cert := range certificates {
+        m.certificateExpiry.WithLabelValues(cert.File, cert.CommonName).Set(float64(cert.NotAfter.Unix()))
+    }
 }
 
 // IncrementConnections increments active connection count
@@ -101,6 +253,114 @@ func (m *Metrics) DecrementConnections() {
     m.activeConnections.Dec()
 }
 
+// RecordRetry counts a failed attempt that will be retried on another backend
+// Reason is "error" for transport failures or the retried status code
+// Time Complexity: O(1) - label lookup and atomic increment
+// Space Complexity: O(1) per pool and reason label
+func (m *Metrics) RecordRetry(pool, reason string) {
+    m.retriesTotal.WithLabelValues(pool, reason).Inc()
+}
+
+// RecordSplit counts a request sent to one side of a traffic split
+// Time Complexity: O(1) - label lookup and atomic increment
+// Space Complexity: O(1) per split and side label
+func (m *Metrics) RecordSplit(split, side string) {
+    m.splitRequests.WithLabelValues(split, side).Inc()
+}
+
+// SetSplitPercent records the canary percentage of a split
+// Time Complexity: O(1) - label lookup and atomic store
+// Space Complexity: O(1) per split label
+func (m *Metrics) SetSplitPercent(split string, percent float64) {
+    m.splitPercent.WithLabelValues(split).Set(percent)
+}
+
+// RecordMirror counts a sampled request of a pool by mirroring result
+// Result is "completed", "error", "dropped" or "body_too_large"
+// Time Complexity: O(1) - label lookup and atomic increment
+// Space Complexity: O(1) per pool and result label
+func (m *Metrics) RecordMirror(pool, result string) {
+    m.mirrorRequests.WithLabelValues(pool, result).Inc()
+}
+
+// RecordMirrorComparison records how a shadow response differed from the pool's
+// A status mismatch is counted by both codes; the latency difference is
+// positive when the shadow was slower
+// Time Complexity: O(1) - label lookups and atomic updates
+// Space Complexity: O(1) per pool and status code pair
+func (m *Metrics) RecordMirrorComparison(pool string, status, shadowStatus int, latencyDifference time.Duration) {
+    if status != shadowStatus {
+        m.mirrorMismatches.WithLabelValues(pool, strconv.Itoa(status), strconv.Itoa(shadowStatus)).Inc()
+    }
+    m.mirrorLatencyDiff.WithLabelValues(pool).Observe(latencyDifference.Seconds())
+}
+
+// RecordBreakerTransition records a circuit breaker moving between states
+// state is the numeric value of the new state for the state gauge
+// Time Complexity: O(1) - label lookups and atomic updates
+// Space Complexity: O(1) per backend and transition label
+func (m *Metrics) RecordBreakerTransition(pool, backend, from, to string, state int) {
+    m.breakerState.WithLabelValues(pool, backend).Set(float64(state))
+    m.breakerTransitions.WithLabelValues(pool, backend, from, to).Inc()
+}
+
+// RecordEjection records outlier detection taking a backend out of rotation
+// Time Complexity: O(1) - label lookups and atomic updates
+// Space Complexity: O(1) per backend and reason label
+func (m *Metrics) RecordEjection(pool, backend, reason string) {
+    m.ejectionsTotal.WithLabelValues(pool, backend, reason).Inc()
+    m.backendEjected.WithLabelValues(pool, backend).Set(1)
+}
+
+// RecordEjectionEnd records an ejected backend returning to rotation
+// Time Complexity: O(1) - label lookup and atomic update
+// Space Complexity: O(1) - no allocations
+func (m *Metrics) RecordEjectionEnd(pool, backend string) {
+    m.backendEjected.WithLabelValues(pool, backend).Set(0)
+}
+
+// IncrementBackendConnections increments the in-flight count of a backend
+// Called by the proxy path when a request is forwarded to the backend
+// Time Complexity: O(1) - label lookup and atomic increment
+// Space Complexity: O(1) per backend label
+func (m *Metrics) IncrementBackendConnections(pool, backend string) {
+    m.backendConnections.WithLabelValues(pool, backend).Inc()
+}
+
+// DecrementBackendConnections decrements the in-flight count of a backend
+// Called once the response, or the upgraded connection, has finished
+// Time Complexity: O(1) - label lookup and atomic decrement
+// Space Complexity: O(1) - no allocations
+func (m *Metrics) DecrementBackendConnections(pool, backend string) {
+    m.backendConnections.WithLabelValues(pool, backend).Dec()
+}
+
+// IncrementWebSockets increments the open WebSocket count of a backend
+// Called once the backend has accepted the upgrade
+// Time Complexity: O(1) - label lookup and atomic increment
+// Space Complexity: O(1) per backend label
+func (m *Metrics) IncrementWebSockets(pool, backend string) {
+    m.backendWebSockets.WithLabelValues(pool, backend).Inc()
+}
+
+// DecrementWebSockets decrements the open WebSocket count of a backend
+// Called when the upgraded connection is closed
+// Time Complexity: O(1) - label lookup and atomic decrement
+// Space Complexity: O(1) - no allocations
+func (m *Metrics) DecrementWebSockets(pool, backend string) {
+    m.backendWebSockets.WithLabelValues(pool, backend).Dec()
+}
+
+// ForgetBackend deletes the gauge series of a backend removed from pool
+// Counters are kept so rates over the time before the removal stay correct
+// Time Complexity: O(1) - one label lookup per gauge
+// Space Complexity: O(1) - no allocations
+func (m *Metrics) ForgetBackend(pool, backend string) {
+    for _, gauge := range []*prometheus.GaugeVec{m.backendHealth, m.backendConnections, m.backendWebSockets, m.breakerState, m.backendEjected} {
+        gauge.DeleteLabelValues(pool, backend)
+    }
+}
+
 // Handler returns HTTP handler for Prometheus metrics exposition
 // Enables metrics scraping by monitoring systems
 // Time Complexity: O(1) - returns existing handler
@@ -122,33 +382,26 @@ func (m *Metrics) MetricsMiddleware(backend string) func(http.Handler) http.Hand
             m.IncrementConnections()
             defer m.DecrementConnections()
 
-            // Wrap response writer to capture status code
-            wrapper := &statusRecorder{ResponseWriter: w, statusCode: 200}
+            // Wrap response writer to capture status code and trailers
+            wrapper := response.NewWriter(w)
             
             // Process request
             next.ServeHTTP(wrapper, r)
             
             // Record metrics
+            // gRPC calls answer 200 even when they fail, so their status is
+            // the grpc-status code instead
             duration := time.Since(start)
+            status := strconv.Itoa(wrapper.Status())
+            if response.IsGRPC(r) {
+                status = wrapper.GRPCStatus()
+            }
             m.RecordRequest(
                 r.Method,
-                strconv.Itoa(wrapper.statusCode),
+                status,
                 backend,
                 duration,
             )
         })
     }
-}
-
-// statusRecorder wraps ResponseWriter to capture HTTP status codes
-// Used by metrics middleware to record response status
-type statusRecorder struct {
-    http.ResponseWriter
-    statusCode int
-}
-
-// WriteHeader captures status code for metrics
-func (sr *statusRecorder) WriteHeader(code int) {
-    sr.statusCode = code
-    sr.ResponseWriter.WriteHeader(code)
 }
\ No newline at end of file
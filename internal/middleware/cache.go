@@ -5,10 +5,12 @@ import (
 	"crypto/md5"
 	"fmt"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/response"
 )
 
 // CacheEntry represents a cached HTTP response with metadata
@@ -77,63 +79,130 @@ func NewCache(config config.CacheConfig) *Cache {
 }
 
 // Wrap decorates handler with response caching functionality
-// Checks cache before forwarding request, stores response after processing
-// Only caches successful GET requests to avoid caching errors or side effects
+// Responses are keyed by host, URL and content negotiation headers only;
+// a proxy that dispatches on more than that uses Serve with a scope instead
 // Time Complexity: O(1) for cache hit, O(n) for cache miss where n is response size
 // Space Complexity: O(1) for cache operations, O(n) for response buffering
 func (c *Cache) Wrap(next http.Handler) http.Handler {
     return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-        // Only cache GET requests as they should be idempotent
-        // POST, PUT, DELETE may have side effects and shouldn't be cached
-        if r.Method != http.MethodGet {
-            next.ServeHTTP(w, r)
+        c.Serve(w, r, "", next)
+    })
+}
+
+// Serve answers r from the cache or from next, storing the response after processing
+// scope separates requests that reach different upstreams with the same host and URL,
+// such as the route that matched them; requests only share entries within one scope
+// Only caches successful GET requests to avoid caching errors or side effects
+// Time Complexity: O(1) for cache hit, O(n) for cache miss where n is response size
+// Space Complexity: O(1) for cache operations, O(n) for response buffering
+func (c *Cache) Serve(w http.ResponseWriter, r *http.Request, scope string, next http.Handler) {
+    // Only cache GET requests as they should be idempotent
+    // POST, PUT, DELETE may have side effects and shouldn't be cached
+    // Upgrade requests such as WebSockets need the connection itself,
+    // and event streams never end
+    if r.Method != http.MethodGet || r.Header.Get("Upgrade") != "" || isEventStream(r.Header.Get("Accept")) {
+        next.ServeHTTP(w, r)
+        return
+    }
+
+    // Generate cache key from scope, request host and URL and relevant headers
+    // Key includes everything that selects the upstream and affects response content
+    cacheKey := c.generateCacheKey(r, scope)
+
+    // Check cache for existing entry
+    if entry := c.get(cacheKey); entry != nil {
+        // Cache hit - serve response from cache
+        c.serveFromCache(w, entry)
+        return
+    }
+
+    // Cache miss - capture the response while it streams to the client
+    // The header snapshot is taken as the status is sent; responses that
+    // cannot be replayed from a snapshot stop being captured right there
+    body := &bytes.Buffer{}
+    var headers http.Header
+    wrapper := response.NewWriter(w)
+    wrapper.Body = body
+    wrapper.OnHeader = func(status int) {
+        if !replayable(w.Header()) {
+            wrapper.Body = nil
             return
         }
+        headers = cacheableHeaders(w.Header())
+    }
 
-        // Generate cache key from request URL and relevant headers
-        // Key includes URL and headers that affect response content
-        cacheKey := c.generateCacheKey(r)
+    // Process request with wrapped response writer
+    next.ServeHTTP(wrapper, r)
+
+    // Cache successful responses (2xx status codes)
+    // Error responses are not cached to avoid serving stale errors
+    status := wrapper.Status()
+    if headers != nil && status >= 200 && status < 300 && !wrapper.Hijacked() && !hasTrailers(w.Header()) {
+        entry := &CacheEntry{
+            Body:       body.Bytes(),
+            Headers:    headers,
+            StatusCode: status,
+            ExpiresAt:  time.Now().Add(c.ttl),
+        }
+        c.set(cacheKey, entry)
+    }
+}
 
-        // Check cache for existing entry
-        if entry := c.get(cacheKey); entry != nil {
-            // Cache hit - serve response from cache
-            c.serveFromCache(w, entry)
-            return
+// isEventStream reports whether a Content-Type or Accept value names server-sent events
+func isEventStream(value string) bool {
+    for _, part := range strings.Split(value, ",") {
+        mediaType, _, _ := strings.Cut(part, ";")
+        if strings.EqualFold(strings.TrimSpace(mediaType), "text/event-stream") {
+            return true
         }
+    }
+    return false
+}
 
-        // Cache miss - create response writer wrapper to capture response
-        wrapper := &responseWriter{
-            ResponseWriter: w,
-            body:           &bytes.Buffer{},
-            headers:        make(http.Header),
+// replayable reports whether a response with header can be served from a snapshot
+// Event streams never end and announced trailers are only known after the body
+func replayable(header http.Header) bool {
+    return !isEventStream(header.Get("Content-Type")) && header.Get("Trailer") == ""
+}
+
+// hasTrailers reports whether trailers were set without being announced
+func hasTrailers(header http.Header) bool {
+    for key := range header {
+        if strings.HasPrefix(key, http.TrailerPrefix) {
+            return true
         }
+    }
+    return false
+}
 
-        // Process request with wrapped response writer
-        next.ServeHTTP(wrapper, r)
-
-        // Cache successful responses (2xx status codes)
-        // Error responses are not cached to avoid serving stale errors
-        if wrapper.statusCode >= 200 && wrapper.statusCode < 300 {
-            entry := &CacheEntry{
-                Body:       wrapper.body.Bytes(),
-                Headers:    wrapper.headers,
-                StatusCode: wrapper.statusCode,
-                ExpiresAt:  time.Now().Add(c.ttl),
-            }
-            c.set(cacheKey, entry)
+// cacheableHeaders copies the response headers stored with a cache entry
+// Set-Cookie is per client (sticky sessions, backend sessions) and never replayed
+// Time Complexity: O(h) where h is number of headers
+// Space Complexity: O(h) for the copy
+func cacheableHeaders(header http.Header) http.Header {
+    headers := make(http.Header, len(header))
+    for key, values := range header {
+        if key == "Set-Cookie" {
+            continue
         }
-    })
+        headers[key] = make([]string, len(values))
+        copy(headers[key], values)
+    }
+    return headers
 }
 
 // generateCacheKey creates unique key for request caching
-// Includes URL and headers that affect response content (Accept, Accept-Encoding)
+// Includes scope, host, URL and headers that affect response content (Accept, Accept-Encoding)
+// The host is needed because server-side request URLs carry only the path
 // MD5 hash ensures consistent key length regardless of URL complexity
 // Time Complexity: O(n) where n is URL length plus relevant headers
 // Space Complexity: O(1) - fixed size hash output
-func (c *Cache) generateCacheKey(r *http.Request) string {
-    // Include URL and relevant headers in cache key
+func (c *Cache) generateCacheKey(r *http.Request, scope string) string {
+    // Include scope, host, URL and relevant headers in cache key
     // Headers like Accept and Accept-Encoding affect response content
-    keyData := fmt.Sprintf("%s|%s|%s", 
+    keyData := fmt.Sprintf("%s|%s|%s|%s|%s", 
+        scope,
+        r.Host,
         r.URL.String(),
         r.Header.Get("Accept"),
         r.Header.Get("Accept-Encoding"),
@@ -263,51 +332,4 @@ func (c *Cache) serveFromCache(w http.ResponseWriter, entry *CacheEntry) {
     // Set status code and write response body
     w.WriteHeader(entry.StatusCode)
     w.Write(entry.Body)
-}
-
-// responseWriter wraps http.ResponseWriter to capture response data
-// Implements decorator pattern to intercept response writes
-// Buffers response body and headers for caching while preserving original behavior
-type responseWriter struct {
-    http.ResponseWriter
-    body       *bytes.Buffer
-    headers    http.Header
-    statusCode int
-}
-
-// Write captures response body data while passing through to original writer
-// Implements io.Writer interface for HTTP response writing
-// Time Complexity: O(n) where n is data length
-// Space Complexity: O(n) for buffering response data
-func (rw *responseWriter) Write(data []byte) (int, error) {
-    // Buffer data for caching
-    rw.body.Write(data)
-    
-    // Pass through to original writer
-    return rw.ResponseWriter.Write(data)
-}
-
-// WriteHeader captures status code and headers while passing through
-// Must be called before Write() as per HTTP specification
-// Time Complexity: O(h) where h is number of headers
-// Space Complexity: O(h) for header storage
-func (rw *responseWriter) WriteHeader(statusCode int) {
-    rw.statusCode = statusCode
-    
-    // Copy headers for caching
-    for key, values := range rw.ResponseWriter.Header() {
-        rw.headers[key] = make([]string, len(values))
-        copy(rw.headers[key], values)
-    }
-    
-    // Pass through to original writer
-    rw.ResponseWriter.WriteHeader(statusCode)
-}
-
-// Header returns the header map for the response
-// Preserves original ResponseWriter interface
-// Time Complexity: O(1) - returns reference
-// Space Complexity: O(1) - no additional allocations
-func (rw *responseWriter) Header() http.Header {
-    return rw.ResponseWriter.Header()
 }
\ No newline at end of file
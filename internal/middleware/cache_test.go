@@ -117,7 +117,7 @@ func TestCacheLRUEviction(t *testing.T) {
     }
 
     // First entry should no longer be cached
-    cacheKey1 := cache.generateCacheKey(req1)
+    cacheKey1 := cache.generateCacheKey(req1, "")
     if cache.get(cacheKey1) != nil {
         t.Error("Expected first entry to be evicted")
     }
@@ -152,4 +152,55 @@ func TestCacheOnlyGET(t *testing.T) {
     if callCount != 2 {
         t.Errorf("Expected 2 backend calls for POST requests, got %d", callCount)
     }
+}
+
+// TestCacheDropsSetCookie verifies per-client cookies are not replayed from cache
+func TestCacheDropsSetCookie(t *testing.T) {
+    cache := NewCache(config.CacheConfig{MaxSize: 10, TTL: time.Minute})
+    handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.SetCookie(w, &http.Cookie{Name: "session", Value: "first-client"})
+        w.Header().Set("Content-Type", "text/plain")
+        w.WriteHeader(http.StatusOK)
+    }))
+
+    first := httptest.NewRecorder()
+    handler.ServeHTTP(first, httptest.NewRequest("GET", "/", nil))
+    if first.Header().Get("Set-Cookie") == "" {
+        t.Fatal("Expected the first response to set its cookie")
+    }
+
+    second := httptest.NewRecorder()
+    handler.ServeHTTP(second, httptest.NewRequest("GET", "/", nil))
+    if second.Header().Get("X-Cache-Status") != "HIT" {
+        t.Fatal("Expected second response from cache")
+    }
+    if cookie := second.Header().Get("Set-Cookie"); cookie != "" {
+        t.Errorf("Expected cached response without Set-Cookie, got %q", cookie)
+    }
+    if second.Header().Get("Content-Type") != "text/plain" {
+        t.Error("Expected other headers to be cached")
+    }
+}
+
+// TestCacheSkipsEventStream verifies server-sent events are flushed through and never cached
+func TestCacheSkipsEventStream(t *testing.T) {
+    cache := NewCache(config.CacheConfig{MaxSize: 10, TTL: time.Minute})
+    callCount := 0
+    handler := cache.Wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        callCount++
+        w.Header().Set("Content-Type", "text/event-stream; charset=utf-8")
+        w.Write([]byte("data: tick\n\n"))
+        http.NewResponseController(w).Flush()
+    }))
+
+    for i := 0; i < 2; i++ {
+        w := httptest.NewRecorder()
+        handler.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+        if !w.Flushed {
+            t.Error("Expected the event stream to be flushed through the cache")
+        }
+    }
+    if callCount != 2 {
+        t.Errorf("Expected every event stream request to reach the handler, got %d", callCount)
+    }
 }
\ No newline at end of file
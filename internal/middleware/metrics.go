@@ -13,7 +13,13 @@ type metricsMiddleware struct {
 
 // NewMetrics constructs the metrics middleware
 func NewMetrics() Middleware {
-    return &metricsMiddleware{m: metrics.NewMetrics()}
+    return NewMetricsWith(metrics.NewMetrics())
+}
+
+// NewMetricsWith constructs the metrics middleware around an existing collector
+// Lets the server share one collector between middleware and other components
+func NewMetricsWith(m *metrics.Metrics) Middleware {
+    return &metricsMiddleware{m: m}
 }
 
 // Wrap instruments each request with Prometheus metrics
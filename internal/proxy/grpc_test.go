@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCBackend starts a cleartext gRPC server whose health service reports
+// serving for the whole server, and returns its URL and health server
+func newGRPCBackend(t *testing.T, serving healthpb.HealthCheckResponse_ServingStatus) (string, *health.Server) {
+    t.Helper()
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    healthServer := health.NewServer()
+    healthServer.SetServingStatus("", serving)
+    grpcServer := grpc.NewServer()
+    healthpb.RegisterHealthServer(grpcServer, healthServer)
+    go grpcServer.Serve(listener)
+    t.Cleanup(grpcServer.Stop)
+    return "http://" + listener.Addr().String(), healthServer
+}
+
+// grpcRequests returns proxy_requests_total for POST requests with the given status label
+func grpcRequests(t *testing.T, statusCode string) float64 {
+    t.Helper()
+    families, err := prometheus.DefaultGatherer.Gather()
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    total := 0.0
+    for _, family := range families {
+        if family.GetName() != "proxy_requests_total" {
+            continue
+        }
+        for _, metric := range family.GetMetric() {
+            labels := map[string]string{}
+            for _, label := range metric.GetLabel() {
+                labels[label.GetName()] = label.GetValue()
+            }
+            if labels["method"] == http.MethodPost && labels["status_code"] == statusCode {
+                total += metric.GetCounter().GetValue()
+            }
+        }
+    }
+    return total
+}
+
+// TestGRPCOverH2C verifies unary and streaming gRPC calls pass through an h2c
+// listener to h2c backends, route by method and are counted by grpc-status
+func TestGRPCOverH2C(t *testing.T) {
+    serving, _ := newGRPCBackend(t, healthpb.HealthCheckResponse_SERVING)
+    streaming, streamingHealth := newGRPCBackend(t, healthpb.HealthCheckResponse_NOT_SERVING)
+
+    cfg := newTestConfig(serving)
+    cfg.Server.H2C = true
+    cfg.LoadBalance.Transport.H2C = true
+    watchPool := config.PoolConfig{
+        Name:      "watch",
+        Algorithm: "round-robin",
+        Backends:  []config.BackendConfig{{URL: streaming, Weight: 1}},
+        Transport: cfg.LoadBalance.Transport,
+    }
+    cfg.Routing.Pools = []config.PoolConfig{watchPool}
+    cfg.Routing.Routes = []config.RouteConfig{
+        {Pool: "watch", GRPC: config.GRPCMatchConfig{Service: "grpc.health.v1.Health", Method: "Watch"}},
+    }
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    frontend := httptest.NewUnstartedServer(server)
+    frontend.Config.Protocols = server.httpServer.Protocols
+    frontend.Start()
+    t.Cleanup(frontend.Close)
+
+    conn, err := grpc.NewClient(frontend.Listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    defer conn.Close()
+    client := healthpb.NewHealthClient(conn)
+    ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+    defer cancel()
+
+    notFound := grpcRequests(t, "5")
+    resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+    if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+        t.Fatalf("Expected SERVING from the default pool, got %v (err %v)", resp, err)
+    }
+    if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: "missing"}); status.Code(err) != codes.NotFound {
+        t.Errorf("Expected NotFound carried in trailers, got %v", err)
+    }
+    if grpcRequests(t, "5") != notFound+1 {
+        t.Error("Expected the failed call to be counted with grpc-status 5")
+    }
+
+    // Watch goes to the streaming pool and receives updates while the call is open
+    stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{})
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if update, err := stream.Recv(); err != nil || update.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+        t.Fatalf("Expected NOT_SERVING from the watch pool, got %v (err %v)", update, err)
+    }
+    streamingHealth.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+    if update, err := stream.Recv(); err != nil || update.Status != healthpb.HealthCheckResponse_SERVING {
+        t.Errorf("Expected streamed SERVING update, got %v (err %v)", update, err)
+    }
+}
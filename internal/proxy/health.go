@@ -0,0 +1,410 @@
+package proxy
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// maxHealthBody bounds how much of a health response body is read for matching
+const maxHealthBody = 64 << 10
+
+// healthChecker probes backends as configured by the health section
+// It keeps per-backend rise/fall counters so a single result never flips health
+type healthChecker struct {
+    config    config.HealthConfig
+    statuses  []config.StatusRange
+    bodyRegex *regexp.Regexp
+    paths     map[loadbalancer.Backend]string // Per-backend health path overrides
+
+    mutex  sync.Mutex
+    states map[loadbalancer.Backend]*healthState
+    conns  map[loadbalancer.Backend]*grpc.ClientConn // gRPC connections reused across checks
+    closed bool
+}
+
+// healthState counts consecutive check results of one backend
+type healthState struct {
+    successes int
+    failures  int
+    known     bool // Health was established by a check or carried over from a reload
+}
+
+// newHealthChecker compiles the health settings of cfg for the backends of pools
+// Per-backend health paths are matched to backends by their position in the pool
+// Time Complexity: O(n) where n is total number of backends
+// Space Complexity: O(n) for path overrides and check state
+func newHealthChecker(cfg *config.Config, pools map[string]loadbalancer.LoadBalancer) (*healthChecker, error) {
+    statuses, err := config.ParseStatusRanges(cfg.Health.ExpectedStatus)
+    if err != nil {
+        return nil, fmt.Errorf("failed to parse expected health status: %w", err)
+    }
+
+    h := &healthChecker{
+        config:   cfg.Health,
+        statuses: statuses,
+        paths:    make(map[loadbalancer.Backend]string),
+        states:   make(map[loadbalancer.Backend]*healthState),
+        conns:    make(map[loadbalancer.Backend]*grpc.ClientConn),
+    }
+    if cfg.Health.BodyRegex != "" {
+        if h.bodyRegex, err = regexp.Compile(cfg.Health.BodyRegex); err != nil {
+            return nil, fmt.Errorf("failed to compile health body regex: %w", err)
+        }
+    }
+
+    backendConfigs := map[string][]config.BackendConfig{config.DefaultPoolName: cfg.LoadBalance.Backends}
+    for _, pool := range cfg.Routing.Pools {
+        backendConfigs[pool.Name] = pool.Backends
+    }
+    for name, lb := range pools {
+        configs := backendConfigs[name]
+        for i, backend := range lb.GetBackends() {
+            if i < len(configs) && configs[i].HealthPath != "" {
+                h.paths[backend] = configs[i].HealthPath
+            }
+        }
+    }
+
+    return h, nil
+}
+
+// startHealthChecks begins background health monitoring for this generation
+// Active health checks run on jittered intervals to detect backend failures,
+// and each pool's outlier detector evaluates passive results on its own interval
+// Does nothing when neither is enabled in the generation's config
+// Time Complexity: O(p) - spawns one goroutine plus one per outlier detector
+// Space Complexity: O(1) for cancellation state
+func (g *generation) startHealthChecks(parent context.Context) {
+    if (!g.config.Health.Enabled && len(g.outliers) == 0) || g.stopHealth != nil {
+        return
+    }
+
+    ctx, cancel := context.WithCancel(parent)
+    g.stopHealth = cancel
+    if g.config.Health.Enabled {
+        go g.runHealthChecks(ctx)
+    }
+    for _, detector := range g.outliers {
+        go detector.Run(ctx)
+    }
+}
+
+// stopHealthChecks cancels this generation's health monitoring if running
+// and removes its health observers, so pools it shares stop reporting through it
+// Called when the generation is replaced by a reload or on shutdown
+// Time Complexity: O(n + p) where n is number of open gRPC health connections and p number of pools
+// Space Complexity: O(1) - no allocations
+func (g *generation) stopHealthChecks() {
+    if g.stopHealth != nil {
+        g.stopHealth()
+    }
+    g.health.close()
+    for _, unsubscribe := range g.unsubscribe {
+        unsubscribe()
+    }
+}
+
+// runHealthChecks performs periodic health checks until the context is cancelled
+// Uses observer pattern to notify load balancer of backend status changes
+// Time Complexity: O(n) per check interval where n is number of backends
+// Space Complexity: O(1) for health check state per backend
+func (g *generation) runHealthChecks(ctx context.Context) {
+    // Perform initial health check before starting periodic checks
+    // This ensures backend status is known at startup
+    g.performHealthChecks(ctx)
+
+    // A timer rather than a ticker, so every interval gets its own jitter
+    timer := time.NewTimer(g.health.nextInterval())
+    defer timer.Stop()
+
+    for {
+        select {
+        case <-timer.C:
+            g.performHealthChecks(ctx)
+            timer.Reset(g.health.nextInterval())
+        case <-ctx.Done():
+            return
+        }
+    }
+}
+
+// performHealthChecks executes health checks for all configured backends
+// Each backend of every pool is checked concurrently to minimize total check time
+// Health changes are reported to the load balancer once a rise/fall threshold is met
+// Time Complexity: O(n) where n is number of backends (concurrent execution)
+// Space Complexity: O(n) for goroutine stacks during concurrent health checks
+func (g *generation) performHealthChecks(ctx context.Context) {
+    for _, lb := range g.pools {
+        for _, backend := range lb.GetBackends() {
+            go func(lb loadbalancer.LoadBalancer, b loadbalancer.Backend) {
+                passed := g.checkBackendHealth(ctx, b)
+                if ctx.Err() != nil {
+                    return
+                }
+                if healthy, changed := g.health.observe(b, passed); changed {
+                    lb.UpdateBackendHealth(b.GetURL(), healthy)
+                }
+            }(lb, backend)
+        }
+    }
+}
+
+// checkBackendHealth runs one check of the configured type against backend
+// Time Complexity: O(1) - single request or connection with bounded timeout
+// Space Complexity: O(b) where b is response body read for matching, bounded by maxHealthBody
+func (g *generation) checkBackendHealth(ctx context.Context, backend loadbalancer.Backend) bool {
+    ctx, cancel := context.WithTimeout(ctx, g.health.config.Timeout)
+    defer cancel()
+
+    switch g.health.config.Type {
+    case config.HealthCheckTCP:
+        return g.health.checkTCP(ctx, backend)
+    case config.HealthCheckGRPC:
+        return g.health.checkGRPC(ctx, backend)
+    default:
+        return g.health.checkHTTP(ctx, backend)
+    }
+}
+
+// checkHTTP requests the backend's health path over its own transport
+// The transport is reused so probes present the same client certificate as traffic;
+// redirects are not followed, list 3xx codes in expectedStatus to accept them
+func (h *healthChecker) checkHTTP(ctx context.Context, backend loadbalancer.Backend) bool {
+    path := h.config.Path
+    if override, ok := h.paths[backend]; ok {
+        path = override
+    }
+
+    req, err := http.NewRequestWithContext(ctx, http.MethodGet, backend.GetURL()+path, nil)
+    if err != nil {
+        return false
+    }
+    if h.config.Host != "" {
+        req.Host = h.config.Host
+    }
+    for name, value := range h.config.Headers {
+        req.Header.Set(name, value)
+    }
+
+    resp, err := backend.GetTransport().RoundTrip(req)
+    if err != nil {
+        return false
+    }
+    defer resp.Body.Close()
+
+    if !h.expectedStatus(resp.StatusCode) {
+        return false
+    }
+    if h.config.BodyContains == "" && h.bodyRegex == nil {
+        return true
+    }
+
+    body, err := io.ReadAll(io.LimitReader(resp.Body, maxHealthBody))
+    if err != nil {
+        return false
+    }
+    if h.config.BodyContains != "" && !strings.Contains(string(body), h.config.BodyContains) {
+        return false
+    }
+    return h.bodyRegex == nil || h.bodyRegex.Match(body)
+}
+
+// expectedStatus reports whether code lies in one of the expected ranges
+func (h *healthChecker) expectedStatus(code int) bool {
+    for _, r := range h.statuses {
+        if r.Contains(code) {
+            return true
+        }
+    }
+    return false
+}
+
+// checkTCP reports whether a TCP connection to the backend can be opened
+func (h *healthChecker) checkTCP(ctx context.Context, backend loadbalancer.Backend) bool {
+    address, err := backendAddress(backend)
+    if err != nil {
+        return false
+    }
+    var dialer net.Dialer
+    conn, err := dialer.DialContext(ctx, "tcp", address)
+    if err != nil {
+        return false
+    }
+    conn.Close()
+    return true
+}
+
+// checkGRPC calls grpc.health.v1.Health/Check and expects SERVING
+func (h *healthChecker) checkGRPC(ctx context.Context, backend loadbalancer.Backend) bool {
+    conn, err := h.grpcConn(backend)
+    if err != nil {
+        return false
+    }
+    for name, value := range h.config.Headers {
+        ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(name), value)
+    }
+
+    resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: h.config.GRPCService})
+    return err == nil && resp.GetStatus() == healthpb.HealthCheckResponse_SERVING
+}
+
+// grpcConn returns the gRPC connection used to check backend, creating it on first use
+// https backends are reached over TLS with the backend's client TLS settings
+func (h *healthChecker) grpcConn(backend loadbalancer.Backend) (*grpc.ClientConn, error) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+    if h.closed {
+        return nil, fmt.Errorf("health checker stopped")
+    }
+    if conn, ok := h.conns[backend]; ok {
+        return conn, nil
+    }
+
+    address, err := backendAddress(backend)
+    if err != nil {
+        return nil, err
+    }
+    creds := insecure.NewCredentials()
+    if strings.HasPrefix(backend.GetURL(), "https://") {
+        tlsConfig := &tls.Config{}
+        if transport, ok := backend.GetTransport().(*http.Transport); ok && transport.TLSClientConfig != nil {
+            tlsConfig = transport.TLSClientConfig.Clone()
+        }
+        tlsConfig.NextProtos = []string{"h2"}
+        creds = credentials.NewTLS(tlsConfig)
+    }
+    options := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+    if h.config.Host != "" {
+        options = append(options, grpc.WithAuthority(h.config.Host))
+    }
+
+    conn, err := grpc.NewClient(address, options...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create gRPC client: %w", err)
+    }
+    h.conns[backend] = conn
+    return conn, nil
+}
+
+// observe records a check result and applies the rise/fall thresholds
+// The first result of a backend whose health is not known yet decides it at once,
+// so a backend that is down at startup or when added never waits out fall checks
+// Returns the backend's health and whether it changed
+// Time Complexity: O(1) - counter updates under lock
+// Space Complexity: O(1) per backend
+func (h *healthChecker) observe(backend loadbalancer.Backend, passed bool) (bool, bool) {
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+
+    state, ok := h.states[backend]
+    if !ok {
+        state = &healthState{}
+        h.states[backend] = state
+    }
+    if !state.known {
+        state.known = true
+        return passed, passed != backend.IsHealthy()
+    }
+    if passed {
+        state.successes++
+        state.failures = 0
+    } else {
+        state.failures++
+        state.successes = 0
+    }
+
+    healthy := backend.IsHealthy()
+    switch {
+    case healthy && state.failures >= h.config.Fall:
+        return false, true
+    case !healthy && state.successes >= h.config.Rise:
+        return true, true
+    default:
+        return healthy, false
+    }
+}
+
+// nextInterval returns the check interval randomised by up to the jitter fraction
+func (h *healthChecker) nextInterval() time.Duration {
+    interval := h.config.Interval
+    if h.config.Jitter <= 0 {
+        return interval
+    }
+    spread := float64(interval) * h.config.Jitter
+    return interval + time.Duration((rand.Float64()*2-1)*spread)
+}
+
+// carry marks the health of backend as known from a previous generation,
+// so its next checks go through the rise/fall thresholds
+// Safe to call on a nil checker
+func (h *healthChecker) carry(backend loadbalancer.Backend) {
+    if h == nil {
+        return
+    }
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+    h.states[backend] = &healthState{known: true}
+}
+
+// forget drops the check state and gRPC connection of a removed backend
+// Safe to call on a nil checker
+func (h *healthChecker) forget(backend loadbalancer.Backend) {
+    if h == nil {
+        return
+    }
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+    delete(h.states, backend)
+    if conn, ok := h.conns[backend]; ok {
+        conn.Close()
+        delete(h.conns, backend)
+    }
+}
+
+// close releases gRPC connections; safe to call on a nil checker
+func (h *healthChecker) close() {
+    if h == nil {
+        return
+    }
+    h.mutex.Lock()
+    defer h.mutex.Unlock()
+    h.closed = true
+    for backend, conn := range h.conns {
+        conn.Close()
+        delete(h.conns, backend)
+    }
+}
+
+// backendAddress returns host:port of backend, defaulting the port by scheme
+func backendAddress(backend loadbalancer.Backend) (string, error) {
+    u, err := url.Parse(backend.GetURL())
+    if err != nil {
+        return "", err
+    }
+    if u.Port() != "" {
+        return u.Host, nil
+    }
+    if u.Scheme == "https" {
+        return net.JoinHostPort(u.Hostname(), "443"), nil
+    }
+    return net.JoinHostPort(u.Hostname(), "80"), nil
+}
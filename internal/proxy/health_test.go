@@ -0,0 +1,231 @@
+package proxy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newHealthTestGeneration builds a generation over urls with the given health settings
+func newHealthTestGeneration(t *testing.T, healthConfig config.HealthConfig, urls ...string) *generation {
+    t.Helper()
+    cfg := newTestConfig(urls...)
+    cfg.Health = healthConfig
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return server.current.Load()
+}
+
+// enabledHealth returns the default health settings, enabled
+func enabledHealth() config.HealthConfig {
+    health := config.DefaultConfig().Health
+    health.Enabled = true
+    return health
+}
+
+// TestHealthRiseFall verifies health only changes after the configured number of
+// consecutive results
+func TestHealthRiseFall(t *testing.T) {
+    gen := newHealthTestGeneration(t, enabledHealth(), "http://backend")
+    backend := gen.pools[config.DefaultPoolName].GetBackends()[0]
+
+    results := []struct {
+        passed  bool
+        healthy bool
+    }{
+        {true, true},                               // first check establishes health
+        {false, true}, {false, true}, {true, true}, // success resets the failure run
+        {false, true}, {false, true}, {false, false}, // fall = 3
+        {true, false}, {true, true}, // rise = 2
+    }
+    for i, result := range results {
+        healthy, changed := gen.health.observe(backend, result.passed)
+        if changed {
+            backend.SetHealthy(healthy)
+        }
+        if backend.IsHealthy() != result.healthy {
+            t.Fatalf("Check %d: expected healthy=%v, got %v", i, result.healthy, backend.IsHealthy())
+        }
+    }
+}
+
+// TestHealthFirstCheckDecides verifies a backend failing its first check is marked
+// down at once, while one carried over by a reload still needs fall failures
+func TestHealthFirstCheckDecides(t *testing.T) {
+    cfg := newTestConfig("http://dead", "http://kept")
+    cfg.Health = enabledHealth()
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    gen := server.current.Load()
+    for _, backend := range gen.pools[config.DefaultPoolName].GetBackends() {
+        if healthy, changed := gen.health.observe(backend, backend.GetURL() == "http://kept"); changed {
+            backend.SetHealthy(healthy)
+        }
+    }
+    backends := gen.pools[config.DefaultPoolName].GetBackends()
+    if backends[0].IsHealthy() || !backends[1].IsHealthy() {
+        t.Fatal("Expected the first check to decide health")
+    }
+
+    next := newTestConfig("http://dead", "http://kept", "http://added")
+    next.Health = enabledHealth()
+    if err := server.Reload(next); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+    gen = server.current.Load()
+    for _, backend := range gen.pools[config.DefaultPoolName].GetBackends() {
+        if healthy, changed := gen.health.observe(backend, false); changed {
+            backend.SetHealthy(healthy)
+        }
+    }
+    backends = gen.pools[config.DefaultPoolName].GetBackends()
+    if !backends[1].IsHealthy() {
+        t.Error("Expected a carried-over backend to survive one failed check")
+    }
+    if backends[2].IsHealthy() {
+        t.Error("Expected a backend added by reload to be marked down by its first check")
+    }
+}
+
+// TestHealthHTTPExpectations verifies status ranges, body matching, custom
+// headers and Host, and per-backend paths
+func TestHealthHTTPExpectations(t *testing.T) {
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if r.Header.Get("X-Probe") != "yes" || r.Host != "health.internal" {
+            w.WriteHeader(http.StatusForbidden)
+            return
+        }
+        switch r.URL.Path {
+        case "/ready":
+            w.WriteHeader(http.StatusNoContent)
+        case "/status":
+            w.Write([]byte(`{"status":"ok","version":"1.2"}`))
+        default:
+            w.WriteHeader(http.StatusNotFound)
+        }
+    }))
+    defer backend.Close()
+
+    health := enabledHealth()
+    health.Host = "health.internal"
+    health.Headers = map[string]string{"X-Probe": "yes"}
+
+    tests := []struct {
+        name     string
+        path     string
+        statuses []string
+        contains string
+        regex    string
+        expected bool
+    }{
+        {"default range", "/ready", []string{"200-299"}, "", "", true},
+        {"status outside range", "/missing", []string{"200-299"}, "", "", false},
+        {"single status", "/missing", []string{"200", "404"}, "", "", true},
+        {"body contains", "/status", []string{"200"}, `"status":"ok"`, "", true},
+        {"body contains mismatch", "/status", []string{"200"}, "degraded", "", false},
+        {"body regex", "/status", []string{"200"}, "", `"version":"1\.\d+"`, true},
+    }
+    for _, tt := range tests {
+        cfg := health
+        cfg.Path = tt.path
+        cfg.ExpectedStatus = tt.statuses
+        cfg.BodyContains = tt.contains
+        cfg.BodyRegex = tt.regex
+        gen := newHealthTestGeneration(t, cfg, backend.URL)
+        b := gen.pools[config.DefaultPoolName].GetBackends()[0]
+        if got := gen.checkBackendHealth(context.Background(), b); got != tt.expected {
+            t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+        }
+    }
+
+    // A backend's own health path overrides the global one
+    cfg := newTestConfig(backend.URL)
+    cfg.Health = health
+    cfg.Health.Path = "/missing"
+    cfg.LoadBalance.Backends[0].HealthPath = "/ready"
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    gen := server.current.Load()
+    if !gen.checkBackendHealth(context.Background(), gen.pools[config.DefaultPoolName].GetBackends()[0]) {
+        t.Error("Expected per-backend health path to be used")
+    }
+}
+
+// TestHealthTCP verifies TCP checks only need an accepting listener
+func TestHealthTCP(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    health := enabledHealth()
+    health.Type = config.HealthCheckTCP
+    gen := newHealthTestGeneration(t, health, "http://"+listener.Addr().String(), refusedURL(t))
+    backends := gen.pools[config.DefaultPoolName].GetBackends()
+
+    if !gen.checkBackendHealth(context.Background(), backends[0]) {
+        t.Error("Expected open port to pass the TCP check")
+    }
+    if gen.checkBackendHealth(context.Background(), backends[1]) {
+        t.Error("Expected closed port to fail the TCP check")
+    }
+    listener.Close()
+}
+
+// TestHealthGRPC verifies gRPC checks follow the grpc.health.v1 serving status
+func TestHealthGRPC(t *testing.T) {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    healthServer := health.NewServer()
+    grpcServer := grpc.NewServer()
+    healthpb.RegisterHealthServer(grpcServer, healthServer)
+    go grpcServer.Serve(listener)
+    defer grpcServer.Stop()
+
+    cfg := enabledHealth()
+    cfg.Type = config.HealthCheckGRPC
+    cfg.GRPCService = "orders"
+    cfg.Timeout = time.Second
+    gen := newHealthTestGeneration(t, cfg, "http://"+listener.Addr().String())
+    defer gen.health.close()
+    backend := gen.pools[config.DefaultPoolName].GetBackends()[0]
+
+    healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_SERVING)
+    if !gen.checkBackendHealth(context.Background(), backend) {
+        t.Error("Expected SERVING service to pass the gRPC check")
+    }
+    healthServer.SetServingStatus("orders", healthpb.HealthCheckResponse_NOT_SERVING)
+    if gen.checkBackendHealth(context.Background(), backend) {
+        t.Error("Expected NOT_SERVING service to fail the gRPC check")
+    }
+}
+
+// TestHealthJitter verifies intervals stay within the jitter fraction
+func TestHealthJitter(t *testing.T) {
+    cfg := enabledHealth()
+    cfg.Interval = 10 * time.Second
+    cfg.Jitter = 0.2
+    gen := newHealthTestGeneration(t, cfg, "http://backend")
+
+    for i := 0; i < 50; i++ {
+        if interval := gen.health.nextInterval(); interval < 8*time.Second || interval > 12*time.Second {
+            t.Fatalf("Expected interval within 20%% of 10s, got %v", interval)
+        }
+    }
+}
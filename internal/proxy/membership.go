@@ -0,0 +1,153 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// AddBackend adds backend to the named pool of the generation serving requests
+// The change lasts until the next reload, which rebuilds pools from configuration
+// Time Complexity: O(n) where n is number of backends in the pool
+// Space Complexity: O(n) for the pool's new backend list
+func (s *Server) AddBackend(pool string, backend loadbalancer.Backend) error {
+    return s.current.Load().addBackend(pool, backend)
+}
+
+// RemoveBackend removes the backend with url from the named pool at once
+// Time Complexity: O(n) where n is number of backends in the pool
+// Space Complexity: O(n) for the pool's new backend list
+func (s *Server) RemoveBackend(pool, url string) error {
+    return s.current.Load().removeBackend(pool, url)
+}
+
+// DrainBackend stops sending new requests to the backend with url and removes
+// it from the named pool once its in-flight requests have finished
+// Time Complexity: O(n) plus the time in-flight requests take to complete
+// Space Complexity: O(n) for the pool's new backend list
+func (s *Server) DrainBackend(ctx context.Context, pool, url string) error {
+    return s.current.Load().drainBackend(ctx, pool, url)
+}
+
+// addBackend adds backend to pool and starts outlier detection and health
+// metrics for it; active health checks pick it up on their next round
+func (g *generation) addBackend(pool string, backend loadbalancer.Backend) error {
+    lb, ok := g.pools[pool]
+    if !ok {
+        return fmt.Errorf("unknown pool %q", pool)
+    }
+    if err := lb.AddBackend(backend); err != nil {
+        return err
+    }
+    if detector := g.outliers[pool]; detector != nil {
+        detector.Add(backend)
+    }
+    g.metrics.UpdateBackendHealth(pool, backend.GetURL(), backend.IsHealthy())
+    return nil
+}
+
+// removeBackend removes the backend with url from pool and releases its state
+func (g *generation) removeBackend(pool, url string) error {
+    lb, backend, err := g.poolBackend(pool, url)
+    if err != nil {
+        return err
+    }
+    if err := lb.RemoveBackend(url); err != nil {
+        return err
+    }
+    g.release(pool, lb, backend)
+    return nil
+}
+
+// drainBackend drains the backend with url from pool and releases its state
+// once it has been removed; a backend left drained by ctx ending is kept
+func (g *generation) drainBackend(ctx context.Context, pool, url string) error {
+    lb, backend, err := g.poolBackend(pool, url)
+    if err != nil {
+        return err
+    }
+    if err := lb.DrainBackend(ctx, url); err != nil {
+        return err
+    }
+    g.release(pool, lb, backend)
+    return nil
+}
+
+// forgetMetrics deletes the metric series of backend, which left pool
+// Requests still in flight on it delete them once the last one finishes instead,
+// so a late gauge update cannot bring them back
+// Time Complexity: O(1) - map update and label lookups
+// Space Complexity: O(1) until the series are deleted
+func (g *generation) forgetMetrics(pool string, backend loadbalancer.Backend) {
+    g.removed.Store(backend, struct{}{})
+    g.forgetIdle(pool, backend)
+}
+
+// forgetIdle deletes the series of a removed backend once nothing is in flight on it
+// Whichever caller first sees it idle deletes them
+func (g *generation) forgetIdle(pool string, backend loadbalancer.Backend) {
+    if backend.GetConnections() > 0 {
+        return
+    }
+    if _, ok := g.removed.LoadAndDelete(backend); ok {
+        g.metrics.ForgetBackend(pool, backend.GetURL())
+    }
+}
+
+// forgetReplaced deletes the metric series of backends that are no longer
+// part of the same pool in next, the generation replacing g
+// Time Complexity: O(n * m) where n and m are backends per pool in g and next
+// Space Complexity: O(1) - no allocations
+func (g *generation) forgetReplaced(next *generation) {
+    for name, lb := range g.pools {
+        var kept []loadbalancer.Backend
+        if nextLB, ok := next.pools[name]; ok {
+            kept = nextLB.GetBackends()
+        }
+        for _, backend := range lb.GetBackends() {
+            if !slices.ContainsFunc(kept, func(b loadbalancer.Backend) bool { return b.GetURL() == backend.GetURL() }) {
+                g.forgetMetrics(name, backend)
+            }
+        }
+    }
+}
+
+// poolBackend looks up pool and the backend with url in it
+func (g *generation) poolBackend(pool, url string) (loadbalancer.LoadBalancer, loadbalancer.Backend, error) {
+    lb, ok := g.pools[pool]
+    if !ok {
+        return nil, nil, fmt.Errorf("unknown pool %q", pool)
+    }
+    for _, backend := range lb.GetBackends() {
+        if backend.GetURL() == url {
+            return lb, backend, nil
+        }
+    }
+    return nil, nil, fmt.Errorf("%w: %s", loadbalancer.ErrBackendNotFound, url)
+}
+
+// release forgets a backend removed from pool: outlier counters, health check
+// state, metric series and its reverse proxy are dropped, and idle connections
+// of its transport are closed unless the transport is shared with backends still in lb
+// Time Complexity: O(n) where n is number of backends in the pool
+// Space Complexity: O(1) - no allocations
+func (g *generation) release(pool string, lb loadbalancer.LoadBalancer, backend loadbalancer.Backend) {
+    if detector := g.outliers[pool]; detector != nil {
+        detector.Remove(backend)
+    }
+    g.health.forget(backend)
+    g.proxies.Delete(backend)
+    g.forgetMetrics(pool, backend)
+
+    transport := backend.GetTransport()
+    for _, remaining := range lb.GetBackends() {
+        if remaining.GetTransport() == transport {
+            return
+        }
+    }
+    if closer, ok := transport.(interface{ CloseIdleConnections() }); ok {
+        closer.CloseIdleConnections()
+    }
+}
@@ -0,0 +1,122 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// closeCountingTransport counts CloseIdleConnections calls on a real transport
+type closeCountingTransport struct {
+    *http.Transport
+    closed int
+}
+
+// CloseIdleConnections records the call and closes the pooled connections
+func (t *closeCountingTransport) CloseIdleConnections() {
+    t.closed++
+    t.Transport.CloseIdleConnections()
+}
+
+// TestMembershipThroughGeneration verifies a backend added at runtime is
+// subject to outlier detection and that removing it releases its proxy and
+// connections
+func TestMembershipThroughGeneration(t *testing.T) {
+    primary := newTestBackend(t, "primary")
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "unavailable", http.StatusServiceUnavailable)
+    }))
+    t.Cleanup(failing.Close)
+
+    cfg := newTestConfig(primary.URL)
+    cfg.LoadBalance.OutlierDetection.Enabled = true
+    cfg.LoadBalance.OutlierDetection.Consecutive5xx = 2
+    cfg.LoadBalance.OutlierDetection.MaxEjectionPercent = 50
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    transport := &closeCountingTransport{Transport: &http.Transport{}}
+    added, err := loadbalancer.NewHTTPBackendWithTransport(failing.URL, 1, transport)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if err := server.AddBackend("default", added); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    for i := 0; i < 4; i++ {
+        serve(t, server, "/")
+    }
+    if !added.IsEjected() {
+        t.Fatal("Expected the added backend to be ejected after consecutive 5xx responses")
+    }
+
+    gen := server.current.Load()
+    if _, ok := gen.proxies.Load(added); !ok {
+        t.Fatal("Expected a reverse proxy for the added backend")
+    }
+    if err := server.RemoveBackend("default", failing.URL); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if _, ok := gen.proxies.Load(added); ok {
+        t.Error("Expected the removed backend's reverse proxy to be dropped")
+    }
+    if transport.closed != 1 {
+        t.Errorf("Expected the removed backend's idle connections to be closed once, got %d", transport.closed)
+    }
+    if err := server.RemoveBackend("default", failing.URL); !errors.Is(err, loadbalancer.ErrBackendNotFound) {
+        t.Errorf("Expected ErrBackendNotFound, got %v", err)
+    }
+    for i := 0; i < 3; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "primary" {
+            t.Errorf("Expected the remaining backend to answer, got %d %q", code, body)
+        }
+    }
+}
+
+// TestRemovedBackendMetrics verifies the gauge series of a backend are deleted
+// when it is removed, only after its last in-flight request, and when a reload
+// drops it from its pool
+func TestRemovedBackendMetrics(t *testing.T) {
+    kept := newTestBackend(t, "kept")
+    removed := newTestBackend(t, "removed")
+    server, err := NewServer(newTestConfig(kept.URL, removed.URL))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    gen := server.current.Load()
+    _, backend, err := gen.poolBackend(config.DefaultPoolName, removed.URL)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    done := gen.trackConnection(config.DefaultPoolName, backend)
+    if err := server.RemoveBackend(config.DefaultPoolName, removed.URL); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if connections := backendGauges(t, "proxy_backend_active_connections", removed.URL); connections[config.DefaultPoolName] != 1 {
+        t.Errorf("Expected the in-flight request to keep its gauge, got %v", connections)
+    }
+    done()
+    for _, name := range []string{"proxy_backend_health", "proxy_backend_active_connections"} {
+        if gauges := backendGauges(t, name, removed.URL); len(gauges) != 0 {
+            t.Errorf("Expected %s of the removed backend to be deleted, got %v", name, gauges)
+        }
+    }
+
+    replacement := newTestBackend(t, "replacement")
+    if err := server.Reload(newTestConfig(replacement.URL)); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if health := healthGauges(t, kept.URL); len(health) != 0 {
+        t.Errorf("Expected health of a backend dropped by reload to be deleted, got %v", health)
+    }
+    if health := healthGauges(t, replacement.URL); health[config.DefaultPoolName] != 1 {
+        t.Errorf("Expected health of the new backend, got %v", health)
+    }
+}
@@ -0,0 +1,313 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/metrics"
+	"github.com/WillKirkmanM/proxy/internal/response"
+	"github.com/WillKirkmanM/proxy/internal/router"
+)
+
+// Results of mirroring a sampled request, as recorded in metrics
+const (
+    mirrorCompleted    = "completed"
+    mirrorError        = "error"
+    mirrorDropped      = "dropped"
+    mirrorBodyTooLarge = "body_too_large"
+)
+
+// mirrorHopHeaders apply to a single connection and are not copied to the shadow
+var mirrorHopHeaders = []string{
+    "Connection",
+    "Keep-Alive",
+    "Proxy-Connection",
+    "Proxy-Authenticate",
+    "Proxy-Authorization",
+    "Te",
+    "Trailer",
+    "Transfer-Encoding",
+    "Upgrade",
+}
+
+// mirror copies sampled requests of a pool to its shadow backend
+// Shadow requests run in the background on their own transport and context,
+// so neither their latency nor their failures reach the client
+type mirror struct {
+    config    config.MirrorConfig
+    pool      string
+    target    *url.URL
+    transport http.RoundTripper
+    inFlight  chan struct{} // Semaphore bounding outstanding shadow requests
+    metrics   *metrics.Metrics
+}
+
+// newMirrors creates the traffic mirror of every pool with mirroring enabled
+// Time Complexity: O(p) where p is number of pools
+// Space Complexity: O(p) for mirrors and their transports
+func newMirrors(cfg *config.Config, m *metrics.Metrics) (map[string]*mirror, error) {
+    mirrors := make(map[string]*mirror)
+    add := func(pool string, mirrorConfig config.MirrorConfig, transport config.TransportConfig) error {
+        if !mirrorConfig.Enabled {
+            return nil
+        }
+        mirror, err := newMirror(pool, mirrorConfig, transport, m)
+        if err != nil {
+            return fmt.Errorf("failed to create mirror for pool %s: %w", pool, err)
+        }
+        mirrors[pool] = mirror
+        return nil
+    }
+
+    if len(cfg.LoadBalance.Backends) > 0 {
+        if err := add(config.DefaultPoolName, cfg.LoadBalance.Mirror, cfg.LoadBalance.Transport); err != nil {
+            return nil, err
+        }
+    }
+    for _, pool := range cfg.Routing.Pools {
+        if err := add(pool.Name, pool.Mirror, pool.Transport); err != nil {
+            return nil, err
+        }
+    }
+    return mirrors, nil
+}
+
+// newMirror creates the mirror of pool sending to the shadow backend of cfg
+// The shadow gets a transport of its own tuned like the pool's
+// Time Complexity: O(1) plus loading configured certificate files
+// Space Complexity: O(1) per mirror
+func newMirror(pool string, cfg config.MirrorConfig, transport config.TransportConfig, m *metrics.Metrics) (*mirror, error) {
+    target, err := url.Parse(cfg.URL)
+    if err != nil {
+        return nil, fmt.Errorf("invalid shadow URL %q: %w", cfg.URL, err)
+    }
+    roundTripper, err := loadbalancer.NewBackendTransport(transport, cfg.TLS)
+    if err != nil {
+        return nil, err
+    }
+    return &mirror{
+        config:    cfg,
+        pool:      pool,
+        target:    target,
+        transport: roundTripper,
+        inFlight:  make(chan struct{}, cfg.MaxInFlight),
+        metrics:   m,
+    }, nil
+}
+
+// shadowRequest pairs the outcome of a mirrored request with the pool's response
+// Whichever of the two finishes last records the comparison
+type shadowRequest struct {
+    mirror  *mirror
+    body    *mirrorBody   // Body being copied for the shadow, nil when there is none
+    mutex   sync.Mutex
+    done    bool          // The first of the two outcomes has been stored
+    status  int           // Pool response status, 0 when there is nothing to compare
+    latency time.Duration // Pool response time
+    shadow  int           // Shadow response status, 0 when the shadow failed
+    elapsed time.Duration // Shadow response time
+}
+
+// send mirrors r to the shadow backend when it is sampled
+// r.Body is replaced by a copy that fills the mirror's buffer while the pool
+// reads it, so the shadow request only goes out once the pool has the whole
+// body and the client never waits for the mirror. Returns nil when r is not mirrored
+// Time Complexity: O(h) where h is number of headers; the body is copied as it streams
+// Space Complexity: O(h + b) for the cloned request and buffered body
+func (m *mirror) send(r *http.Request) *shadowRequest {
+    if m.config.Percent < 100 && rand.Float64()*100 >= m.config.Percent {
+        return nil
+    }
+    // Upgraded connections cannot be answered twice, and gRPC calls may stream
+    // in both directions so their bodies cannot be read ahead
+    if r.Header.Get("Upgrade") != "" || response.IsGRPC(r) {
+        return nil
+    }
+
+    hasBody := r.Body != nil && r.Body != http.NoBody && r.ContentLength != 0
+    if hasBody && (m.config.MaxBodyBytes <= 0 || r.ContentLength > m.config.MaxBodyBytes) {
+        m.metrics.RecordMirror(m.pool, mirrorBodyTooLarge)
+        return nil
+    }
+
+    // The slot is held from here, so it also bounds the bodies being buffered
+    select {
+    case m.inFlight <- struct{}{}:
+    default:
+        m.metrics.RecordMirror(m.pool, mirrorDropped)
+        return nil
+    }
+
+    shadow := &shadowRequest{mirror: m}
+    out := m.outgoing(r)
+    if !hasBody {
+        go shadow.roundTrip(out, nil)
+        return shadow
+    }
+    shadow.body = &mirrorBody{ReadCloser: r.Body, shadow: shadow, out: out, limit: m.config.MaxBodyBytes}
+    r.Body = shadow.body
+    return shadow
+}
+
+// mirrorBody copies a request body into the mirror's buffer as the pool reads it
+// The shadow request is sent when the body ends within the limit; a body over
+// the limit, or one the pool stopped reading early, is not mirrored
+type mirrorBody struct {
+    io.ReadCloser
+    shadow *shadowRequest
+    out    *http.Request // Shadow request waiting for the body
+    limit  int64
+    mutex  sync.Mutex
+    buffer bytes.Buffer
+    ended  bool // The shadow request was sent or given up
+}
+
+// Read passes the body on to the pool and copies it for the shadow
+// Time Complexity: O(n) where n is number of bytes read
+// Space Complexity: O(n) added to the buffer
+func (b *mirrorBody) Read(p []byte) (int, error) {
+    n, err := b.ReadCloser.Read(p)
+
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    if b.ended {
+        return n, err
+    }
+    if int64(b.buffer.Len()+n) > b.limit {
+        b.endLocked(mirrorBodyTooLarge)
+        return n, err
+    }
+    b.buffer.Write(p[:n])
+    if err == io.EOF {
+        b.ended = true
+        go b.shadow.roundTrip(b.out, b.buffer.Bytes())
+    }
+    return n, err
+}
+
+// Close gives up the shadow request when the pool did not read the whole body
+func (b *mirrorBody) Close() error {
+    b.abandon()
+    return b.ReadCloser.Close()
+}
+
+// abandon gives up the shadow request unless it was already sent
+func (b *mirrorBody) abandon() {
+    b.mutex.Lock()
+    defer b.mutex.Unlock()
+    if !b.ended {
+        b.endLocked(mirrorDropped)
+    }
+}
+
+// endLocked records why the shadow request is not sent and frees its slot
+// Must be called with b.mutex held
+func (b *mirrorBody) endLocked(result string) {
+    b.ended = true
+    m := b.shadow.mirror
+    m.metrics.RecordMirror(m.pool, result)
+    <-m.inFlight
+    b.shadow.finish(func() {})
+}
+
+// outgoing builds the shadow copy of r addressed to the shadow backend
+// The copy is detached from the client's cancellation, so it completes even
+// after the pool has answered, and carries the mirror header naming the pool.
+// Client certificate headers are sanitised as for the pool, so the shadow
+// cannot be handed a spoofed identity. The body is set by roundTrip
+// Time Complexity: O(h) where h is number of headers
+// Space Complexity: O(h) for the cloned headers
+func (m *mirror) outgoing(r *http.Request) *http.Request {
+    out := r.Clone(context.WithoutCancel(r.Context()))
+    out.RequestURI = ""
+    out.Close = false
+
+    // Apply the route's rewrites like the pool's reverse proxy does; the
+    // Host header sent to the pool is kept so the shadow sees the same request
+    if route := router.FromContext(out.Context()); route != nil {
+        route.Rewrite(out)
+    }
+    host := out.Host
+    (&httputil.ProxyRequest{In: r, Out: out}).SetURL(m.target)
+    out.Host = host
+
+    for _, header := range mirrorHopHeaders {
+        out.Header.Del(header)
+    }
+    out.Header.Set(m.config.Header, m.pool)
+    setClientCertHeaders(out)
+    out.Body, out.ContentLength = http.NoBody, 0
+    return out
+}
+
+// roundTrip sends out with body to the shadow backend and discards the response
+// It frees the mirror slot taken by send when done
+// Time Complexity: O(b) where b is the shadow response body size
+// Space Complexity: O(1) - the body is drained without buffering
+func (s *shadowRequest) roundTrip(out *http.Request, body []byte) {
+    m := s.mirror
+    defer func() { <-m.inFlight }()
+    if len(body) > 0 {
+        out.Body, out.ContentLength = io.NopCloser(bytes.NewReader(body)), int64(len(body))
+    }
+
+    ctx, cancel := context.WithTimeout(out.Context(), m.config.Timeout)
+    defer cancel()
+
+    start := time.Now()
+    resp, err := m.transport.RoundTrip(out.WithContext(ctx))
+    elapsed := time.Since(start)
+    if err != nil {
+        m.metrics.RecordMirror(m.pool, mirrorError)
+        s.finish(func() { s.elapsed = elapsed })
+        return
+    }
+    // Draining lets the connection return to the pool
+    io.Copy(io.Discard, resp.Body)
+    resp.Body.Close()
+
+    m.metrics.RecordMirror(m.pool, mirrorCompleted)
+    s.finish(func() { s.shadow, s.elapsed = resp.StatusCode, elapsed })
+}
+
+// compare hands over the pool's response for comparison with the shadow's
+// a is the final attempt, nil when no response came from the pool; nothing is
+// compared for a client that went away. A body the pool left unread is not mirrored
+// Time Complexity: O(1) - lock and metric updates
+// Space Complexity: O(1) - no allocations
+func (s *shadowRequest) compare(ctx context.Context, a *attempt) {
+    if s.body != nil {
+        s.body.abandon()
+    }
+    s.finish(func() {
+        if a != nil && ctx.Err() == nil {
+            s.status, s.latency = a.responseStatus(), a.observedLatency()
+        }
+    })
+}
+
+// finish stores one outcome under the lock and records the comparison once
+// both the pool and the shadow have answered
+func (s *shadowRequest) finish(store func()) {
+    s.mutex.Lock()
+    defer s.mutex.Unlock()
+
+    store()
+    if !s.done {
+        s.done = true
+        return
+    }
+    if s.status != 0 && s.shadow != 0 {
+        s.mirror.metrics.RecordMirrorComparison(s.mirror.pool, s.status, s.shadow, s.elapsed-s.latency)
+    }
+}
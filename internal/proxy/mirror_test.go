@@ -0,0 +1,208 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// mirroredRequest is what a test shadow backend received
+type mirroredRequest struct {
+    path   string
+    header string
+    body   string
+}
+
+// newShadowBackend starts a shadow that reports requests on the returned channel
+// and answers them with handler
+func newShadowBackend(t *testing.T, handler http.HandlerFunc) (*httptest.Server, chan mirroredRequest) {
+    t.Helper()
+    received := make(chan mirroredRequest, 16)
+    shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        received <- mirroredRequest{path: r.URL.Path, header: r.Header.Get("X-Mirrored-From"), body: string(body)}
+        handler(w, r)
+    }))
+    t.Cleanup(shadow.Close)
+    return shadow, received
+}
+
+// newMirrorTestServer builds a server whose default pool mirrors to shadowURL
+func newMirrorTestServer(t *testing.T, shadowURL string, configure func(*config.MirrorConfig), urls ...string) *Server {
+    t.Helper()
+    cfg := newTestConfig(urls...)
+    cfg.LoadBalance.Mirror.Enabled = true
+    cfg.LoadBalance.Mirror.URL = shadowURL
+    if configure != nil {
+        configure(&cfg.LoadBalance.Mirror)
+    }
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return server
+}
+
+// echoBackend starts a backend replying with the request body it received
+func echoBackend(t *testing.T) *httptest.Server {
+    t.Helper()
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        io.Copy(w, r.Body)
+    }))
+    t.Cleanup(backend.Close)
+    return backend
+}
+
+// post sends a POST request with body through handler and returns the response
+func post(t *testing.T, handler http.Handler, path, body string) (int, string) {
+    t.Helper()
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, httptest.NewRequest("POST", path, strings.NewReader(body)))
+    response, _ := io.ReadAll(w.Result().Body)
+    return w.Code, string(response)
+}
+
+// TestMirrorCopiesRequests verifies the shadow receives a tagged copy including
+// the body while the pool still receives the full request
+func TestMirrorCopiesRequests(t *testing.T) {
+    shadow, received := newShadowBackend(t, func(w http.ResponseWriter, r *http.Request) {})
+    server := newMirrorTestServer(t, shadow.URL, nil, echoBackend(t).URL)
+
+    if code, body := post(t, server, "/orders", "payload"); code != http.StatusOK || body != "payload" {
+        t.Fatalf("Expected pool to echo the body, got %d %q", code, body)
+    }
+
+    select {
+    case request := <-received:
+        if request.path != "/orders" || request.body != "payload" {
+            t.Errorf("Expected copy of POST /orders with body, got %+v", request)
+        }
+        if request.header != config.DefaultPoolName {
+            t.Errorf("Expected mirror header naming the pool, got %q", request.header)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected the shadow to receive a mirrored request")
+    }
+}
+
+// TestMirrorNeverAffectsClient verifies slow, failing and unreachable shadows
+// change neither the status nor the latency seen by the client
+func TestMirrorNeverAffectsClient(t *testing.T) {
+    release := make(chan struct{})
+    slow, _ := newShadowBackend(t, func(w http.ResponseWriter, r *http.Request) {
+        <-release
+        http.Error(w, "shadow failure", http.StatusInternalServerError)
+    })
+    defer close(release)
+
+    for name, shadowURL := range map[string]string{"slow": slow.URL, "unreachable": refusedURL(t)} {
+        server := newMirrorTestServer(t, shadowURL, nil, newTestBackend(t, "primary").URL)
+
+        start := time.Now()
+        code, body := serve(t, server, "/")
+        if code != http.StatusOK || body != "primary" {
+            t.Errorf("%s: expected the pool's response, got %d %q", name, code, body)
+        }
+        if elapsed := time.Since(start); elapsed > time.Second {
+            t.Errorf("%s: expected the client not to wait for the shadow, took %v", name, elapsed)
+        }
+    }
+}
+
+// TestMirrorSanitisesClientCertHeaders verifies spoofed client certificate
+// headers are removed from the shadow copy like from the pool's request
+func TestMirrorSanitisesClientCertHeaders(t *testing.T) {
+    headers := make(chan http.Header, 1)
+    shadow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        headers <- r.Header.Clone()
+    }))
+    defer shadow.Close()
+    server := newMirrorTestServer(t, shadow.URL, nil, newTestBackend(t, "primary").URL)
+
+    req := httptest.NewRequest("GET", "/", nil)
+    req.Header.Set(clientCertSubjectHeader, "CN=admin")
+    req.Header.Set(clientCertSANsHeader, "DNS:admin")
+    server.ServeHTTP(httptest.NewRecorder(), req)
+
+    select {
+    case header := <-headers:
+        if header.Get(clientCertSubjectHeader) != "" || header.Get(clientCertSANsHeader) != "" {
+            t.Errorf("Expected spoofed client certificate headers to be removed, got %v", header)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected the shadow to receive a mirrored request")
+    }
+}
+
+// TestMirrorStreamsBody verifies the pool receives the body as the client sends
+// it, before the mirror has the whole body, and the shadow still gets all of it
+func TestMirrorStreamsBody(t *testing.T) {
+    started := make(chan struct{})
+    primary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        first := make([]byte, len("first"))
+        io.ReadFull(r.Body, first)
+        close(started)
+        rest, _ := io.ReadAll(r.Body)
+        w.Write(append(first, rest...))
+    }))
+    defer primary.Close()
+    shadow, received := newShadowBackend(t, func(w http.ResponseWriter, r *http.Request) {})
+    server := newMirrorTestServer(t, shadow.URL, nil, primary.URL)
+
+    body, client := io.Pipe()
+    w := httptest.NewRecorder()
+    done := make(chan struct{})
+    go func() {
+        defer close(done)
+        server.ServeHTTP(w, httptest.NewRequest("POST", "/", body))
+    }()
+
+    client.Write([]byte("first"))
+    select {
+    case <-started:
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected the pool to receive the body before it was complete")
+    }
+    client.Write([]byte(" rest"))
+    client.Close()
+    <-done
+
+    if w.Body.String() != "first rest" {
+        t.Errorf("Expected the pool to receive the whole body, got %q", w.Body.String())
+    }
+    select {
+    case request := <-received:
+        if request.body != "first rest" {
+            t.Errorf("Expected the shadow to receive the whole body, got %q", request.body)
+        }
+    case <-time.After(2 * time.Second):
+        t.Fatal("Expected the shadow to receive a mirrored request")
+    }
+}
+
+// TestMirrorSampling verifies bodies over the cap and unsampled requests are
+// not mirrored
+func TestMirrorSampling(t *testing.T) {
+    shadow, received := newShadowBackend(t, func(w http.ResponseWriter, r *http.Request) {})
+
+    capped := newMirrorTestServer(t, shadow.URL, func(m *config.MirrorConfig) { m.MaxBodyBytes = 4 }, echoBackend(t).URL)
+    if code, body := post(t, capped, "/", "larger than the cap"); code != http.StatusOK || body != "larger than the cap" {
+        t.Fatalf("Expected pool to receive the whole body, got %d %q", code, body)
+    }
+
+    unsampled := newMirrorTestServer(t, shadow.URL, func(m *config.MirrorConfig) { m.Percent = 0 }, newTestBackend(t, "primary").URL)
+    for i := 0; i < 20; i++ {
+        serve(t, unsampled, "/")
+    }
+
+    select {
+    case request := <-received:
+        t.Errorf("Expected no mirrored request, got %+v", request)
+    case <-time.After(200 * time.Millisecond):
+    }
+}
@@ -0,0 +1,211 @@
+package proxy
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/response"
+)
+
+// retryPolicy is the compiled retry configuration of a pool
+type retryPolicy struct {
+    config  config.RetryConfig
+    retryOn map[int]bool // Backend status codes that trigger a retry
+}
+
+// newRetryPolicy compiles cfg, returning nil when retries are disabled
+// Time Complexity: O(s) where s is number of retry-on status codes
+// Space Complexity: O(s) for the status code set
+func newRetryPolicy(cfg config.RetryConfig) *retryPolicy {
+    if !cfg.Enabled || cfg.MaxAttempts <= 1 {
+        return nil
+    }
+    policy := &retryPolicy{config: cfg, retryOn: make(map[int]bool, len(cfg.RetryOn))}
+    for _, code := range cfg.RetryOn {
+        policy.retryOn[code] = true
+    }
+    return policy
+}
+
+// idempotentMethods are safe to send twice per RFC 9110 section 9.2.2
+var idempotentMethods = map[string]bool{
+    http.MethodGet:     true,
+    http.MethodHead:    true,
+    http.MethodOptions: true,
+    http.MethodTrace:   true,
+    http.MethodPut:     true,
+    http.MethodDelete:  true,
+}
+
+// retryable reports whether req may be sent more than once under this policy
+// Requests carrying an Idempotency-Key header are marked retryable by the client,
+// following the same convention as net/http's transport
+// Time Complexity: O(1) - map and header lookups
+// Space Complexity: O(1) - no allocations
+func (p *retryPolicy) retryable(req *http.Request) bool {
+    if p.config.RetryNonIdempotent || idempotentMethods[req.Method] {
+        return true
+    }
+    _, marked := req.Header["Idempotency-Key"]
+    _, xMarked := req.Header["X-Idempotency-Key"]
+    return marked || xMarked
+}
+
+// backoff returns the delay before retry number n (starting at 1)
+// Exponential growth capped at MaxBackoff, with jitter over the upper half so
+// concurrent retries against a recovering backend spread out
+// Time Complexity: O(1) - arithmetic and one random draw
+// Space Complexity: O(1) - no allocations
+func (p *retryPolicy) backoff(n int) time.Duration {
+    delay := p.config.InitialBackoff
+    for i := 1; i < n && delay < p.config.MaxBackoff; i++ {
+        delay *= 2
+    }
+    delay = min(delay, p.config.MaxBackoff)
+    if delay <= 0 {
+        return 0
+    }
+    half := delay / 2
+    return half + rand.N(delay-half+1)
+}
+
+// bufferBody reads the request body into memory so it can be sent more than once
+// Returns ok false when the body exceeds limit or cannot be read; req.Body then
+// still yields everything the client sent so the request can be forwarded once
+// Time Complexity: O(b) where b is body size up to the limit
+// Space Complexity: O(b) for the buffered body
+func bufferBody(req *http.Request, limit int64) ([]byte, bool, error) {
+    if req.Body == nil || req.Body == http.NoBody || req.ContentLength == 0 {
+        return nil, true, nil
+    }
+    if limit <= 0 || req.ContentLength > limit {
+        return nil, false, nil
+    }
+
+    body, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+    if err != nil || int64(len(body)) > limit {
+        req.Body = struct {
+            io.Reader
+            io.Closer
+        }{io.MultiReader(bytes.NewReader(body), req.Body), req.Body}
+    }
+    if err != nil {
+        return nil, false, fmt.Errorf("failed to read request body: %w", err)
+    }
+    if int64(len(body)) > limit {
+        return nil, false, nil
+    }
+    req.Body = io.NopCloser(bytes.NewReader(body))
+    return body, true, nil
+}
+
+// retryStatusError reports a backend response whose status triggers a retry
+type retryStatusError struct {
+    status int
+}
+
+// Error describes the retried status code
+func (e *retryStatusError) Error() string {
+    return fmt.Sprintf("backend responded %d", e.status)
+}
+
+// retryReason labels a swallowed failure for metrics
+func retryReason(err error) string {
+    if statusErr, ok := err.(*retryStatusError); ok {
+        return fmt.Sprint(statusErr.status)
+    }
+    return "error"
+}
+
+// forwardWithRetries sends r to backends of lb, retrying failures under policy
+// Each retry selects a backend not yet tried when the pool has one, waits an
+// exponential backoff with jitter and stops once the attempts or budget run out
+// When no backend is left to retry on, the last attempt's failure is written
+// Returns the last attempt, nil when no attempt answered the client
+// Time Complexity: O(a * n) where a is number of attempts and n is pool size
+// Space Complexity: O(b + a) for the buffered body and tried backend set
+func (g *generation) forwardWithRetries(w http.ResponseWriter, r *http.Request, pool string, lb loadbalancer.LoadBalancer, backend loadbalancer.Backend, policy *retryPolicy) *attempt {
+    // gRPC calls may stream in both directions, so their bodies are never read
+    // ahead; without a replayable body they get a single attempt
+    var body []byte
+    var replayable bool
+    if !response.IsGRPC(r) {
+        var err error
+        if body, replayable, err = bufferBody(r, policy.config.MaxBodyBytes); err != nil {
+            http.Error(w, "Failed to read request body", http.StatusBadRequest)
+            return nil
+        }
+    }
+
+    attempts := policy.config.MaxAttempts
+    if !replayable || !policy.retryable(r) {
+        attempts = 1
+    }
+
+    start := time.Now()
+    tried := map[loadbalancer.Backend]bool{backend: true}
+    for n := 1; ; n++ {
+        delay := policy.backoff(n)
+        withinBudget := policy.config.Budget == 0 || time.Since(start)+delay < policy.config.Budget
+        current := &attempt{policy: policy, sticky: g.sticky[pool], canRetry: n < attempts && withinBudget}
+
+        req := r
+        if body != nil {
+            req = r.Clone(r.Context())
+            req.Body = io.NopCloser(bytes.NewReader(body))
+        }
+        g.forward(w, req, pool, backend, current)
+
+        // Failures are only swallowed while another attempt may follow
+        if !current.canRetry || current.err == nil || r.Context().Err() != nil {
+            return current
+        }
+        g.metrics.RecordRetry(pool, retryReason(current.err))
+
+        timer := time.NewTimer(delay)
+        select {
+        case <-timer.C:
+        case <-r.Context().Done():
+            timer.Stop()
+            return current
+        }
+
+        next, err := selectUntried(lb, r, tried)
+        if err != nil {
+            current.writeFailure(w)
+            return current
+        }
+        tried[next] = true
+        backend = next
+    }
+}
+
+// selectUntried asks the load balancer for a backend not used by this request yet
+// Falls back to a repeated backend when every healthy one has been tried
+// Time Complexity: O(n) selections where n is pool size
+// Space Complexity: O(1) - no allocations
+func selectUntried(lb loadbalancer.LoadBalancer, r *http.Request, tried map[loadbalancer.Backend]bool) (loadbalancer.Backend, error) {
+    var fallback loadbalancer.Backend
+    for i := 0; i < len(lb.GetBackends()); i++ {
+        backend, err := lb.SelectBackend(r)
+        if err != nil {
+            return nil, err
+        }
+        if !tried[backend] {
+            return backend, nil
+        }
+        if fallback == nil {
+            fallback = backend
+        }
+    }
+    if fallback == nil {
+        return lb.SelectBackend(r)
+    }
+    return fallback, nil
+}
@@ -0,0 +1,146 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// newRetryTestServer builds a server whose default pool retries on the given backends
+func newRetryTestServer(t *testing.T, retry config.RetryConfig, urls ...string) *Server {
+    t.Helper()
+    cfg := newTestConfig(urls...)
+    cfg.LoadBalance.Retry = retry
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return server
+}
+
+// enabledRetries returns retry settings with retries on and short backoffs
+func enabledRetries() config.RetryConfig {
+    retry := config.DefaultRetryConfig()
+    retry.Enabled = true
+    retry.InitialBackoff = time.Millisecond
+    retry.MaxBackoff = 2 * time.Millisecond
+    return retry
+}
+
+// refusedURL returns the URL of a server that has already been shut down
+func refusedURL(t *testing.T) string {
+    t.Helper()
+    closed := httptest.NewServer(http.NotFoundHandler())
+    closed.Close()
+    return closed.URL
+}
+
+// TestRetryConnectionRefused verifies failed connections are retried on another backend
+func TestRetryConnectionRefused(t *testing.T) {
+    healthy := newTestBackend(t, "healthy")
+    server := newRetryTestServer(t, enabledRetries(), refusedURL(t), healthy.URL)
+
+    for i := 0; i < 4; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "healthy" {
+            t.Fatalf("Request %d: expected retry to reach healthy backend, got %d %q", i, code, body)
+        }
+    }
+}
+
+// TestRetryOnStatus verifies configured status codes are retried and the final
+// attempt's response is passed through
+func TestRetryOnStatus(t *testing.T) {
+    unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "unavailable", http.StatusServiceUnavailable)
+    }))
+    defer unavailable.Close()
+    healthy := newTestBackend(t, "healthy")
+
+    server := newRetryTestServer(t, enabledRetries(), unavailable.URL, healthy.URL)
+    for i := 0; i < 4; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "healthy" {
+            t.Fatalf("Request %d: expected 503 to be retried, got %d %q", i, code, body)
+        }
+    }
+
+    only := newRetryTestServer(t, enabledRetries(), unavailable.URL)
+    if code, _ := serve(t, only, "/"); code != http.StatusServiceUnavailable {
+        t.Errorf("Expected final 503 to be passed through, got %d", code)
+    }
+}
+
+// TestRetryKeepsLastResult verifies the client receives the last attempt's
+// status when no backend is left to retry on
+func TestRetryKeepsLastResult(t *testing.T) {
+    var server *Server
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        server.current.Load().pools[config.DefaultPoolName].GetBackends()[0].SetHealthy(false)
+        http.Error(w, "timeout", http.StatusGatewayTimeout)
+    }))
+    defer failing.Close()
+
+    server = newRetryTestServer(t, enabledRetries(), failing.URL)
+    if code, _ := serve(t, server, "/"); code != http.StatusGatewayTimeout {
+        t.Errorf("Expected the backend's 504 once no backend is left, got %d", code)
+    }
+}
+
+// TestRetryNonIdempotent verifies POST is only retried when marked and its body fits the buffer
+func TestRetryNonIdempotent(t *testing.T) {
+    var received []string
+    echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        body, _ := io.ReadAll(r.Body)
+        received = append(received, string(body))
+        w.Write(body)
+    }))
+    defer echo.Close()
+
+    retry := enabledRetries()
+    retry.MaxBodyBytes = 1024
+    server := newRetryTestServer(t, retry, refusedURL(t), echo.URL)
+
+    post := func(marked bool) int {
+        req := httptest.NewRequest("POST", "/", strings.NewReader("payload"))
+        if marked {
+            req.Header.Set("Idempotency-Key", "abc")
+        }
+        w := httptest.NewRecorder()
+        server.ServeHTTP(w, req)
+        return w.Code
+    }
+
+    // Round-robin sends the first request to the refused backend
+    if code := post(false); code != http.StatusBadGateway {
+        t.Errorf("Expected unmarked POST to fail without retry, got %d", code)
+    }
+    post(false) // advance to the refused backend again
+    if code := post(true); code != http.StatusOK {
+        t.Errorf("Expected marked POST to be retried, got %d", code)
+    }
+    if received[len(received)-1] != "payload" {
+        t.Errorf("Expected replayed body payload, got %q", received[len(received)-1])
+    }
+}
+
+// TestRetryBackoff verifies backoff grows exponentially within jitter bounds and is capped
+func TestRetryBackoff(t *testing.T) {
+    policy := newRetryPolicy(config.RetryConfig{
+        Enabled:        true,
+        MaxAttempts:    5,
+        InitialBackoff: 100 * time.Millisecond,
+        MaxBackoff:     300 * time.Millisecond,
+    })
+
+    for n, ceiling := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 4: 300 * time.Millisecond} {
+        for i := 0; i < 20; i++ {
+            if delay := policy.backoff(n); delay < ceiling/2 || delay > ceiling {
+                t.Fatalf("Retry %d: expected delay in [%v, %v], got %v", n, ceiling/2, ceiling, delay)
+            }
+        }
+    }
+}
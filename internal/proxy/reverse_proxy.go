@@ -1,50 +1,252 @@
 package proxy
 
 import (
+    "context"
+    "crypto/x509"
+    "errors"
     "net/http"
     "net/http/httputil"
     "net/url"
+    "strings"
+    "sync"
+    "time"
 
     "github.com/WillKirkmanM/proxy/internal/loadbalancer"
+    "github.com/WillKirkmanM/proxy/internal/router"
 )
 
+// bufferPool recycles the buffers reverse proxies use to copy response bodies
+// Shared by all proxies so steady-state forwarding does not allocate copy buffers
+var bufferPool = &copyBufferPool{}
+
+// copyBufferPool implements httputil.BufferPool on top of sync.Pool
+type copyBufferPool struct {
+    pool sync.Pool
+}
+
+// Get returns a 32KB buffer, reusing a released one when available
+func (p *copyBufferPool) Get() []byte {
+    if buf, ok := p.pool.Get().(*[]byte); ok {
+        return *buf
+    }
+    return make([]byte, 32*1024)
+}
+
+// Put releases a buffer for reuse by later responses
+func (p *copyBufferPool) Put(buf []byte) {
+    p.pool.Put(&buf)
+}
+
+// attempt records the outcome of forwarding a request to one backend once
+// The reverse proxy's ErrorHandler and ModifyResponse fill it in through the
+// request context; the proxy path reports it to circuit breakers and uses it to
+// decide between failing the request and leaving it to a retry
+type attempt struct {
+    policy   *retryPolicy  // Retry policy of the pool, nil when retries are disabled
+    sticky   *stickyPolicy // Sticky session policy of the pool, nil when disabled
+    canRetry bool          // Another attempt may follow, so failures must not be written
+    status   int           // Backend response status, 0 when none was received
+    err      error         // Transport failure or retried status, nil on success
+    start    time.Time     // When the attempt was sent
+    latency  time.Duration // Time until response headers arrived, 0 when none did
+}
+
+// attemptKey is the context key under which the current attempt is stored
+type attemptKey struct{}
+
+// attemptFromContext returns the attempt stored in ctx, or nil
+func attemptFromContext(ctx context.Context) *attempt {
+    a, _ := ctx.Value(attemptKey{}).(*attempt)
+    return a
+}
+
+// failed reports whether the backend failed this attempt
+// Transport errors and 5xx responses count; a client that went away does not
+func (a *attempt) failed(ctx context.Context) bool {
+    if ctx.Err() != nil {
+        return false
+    }
+    var statusErr *retryStatusError
+    if a.err != nil && !errors.As(a.err, &statusErr) {
+        return true
+    }
+    return a.status >= 500
+}
+
+// outcome converts the attempt into the form recorded by outlier detection
+func (a *attempt) outcome() loadbalancer.Outcome {
+    var statusErr *retryStatusError
+    return loadbalancer.Outcome{
+        Status:       a.status,
+        ConnectError: a.err != nil && !errors.As(a.err, &statusErr),
+        Latency:      a.latency,
+    }
+}
+
+// observedLatency returns the response time fed back to latency-aware balancers
+// Failed attempts count the time spent until the failure; balancers raise it
+// to their failure penalty so a backend that errors fast is not rewarded
+func (a *attempt) observedLatency() time.Duration {
+    if a.latency > 0 {
+        return a.latency
+    }
+    return time.Since(a.start)
+}
+
+// errBreakerOpen marks an attempt the backend's circuit breaker refused to send
+var errBreakerOpen = errors.New("circuit breaker open")
+
+// responseStatus returns the status the client received from this attempt
+// A transport failure without a response was answered with 502 by ErrorHandler,
+// an attempt refused by the circuit breaker with 503
+func (a *attempt) responseStatus() int {
+    if errors.Is(a.err, errBreakerOpen) {
+        return http.StatusServiceUnavailable
+    }
+    if a.status == 0 && a.err != nil {
+        return http.StatusBadGateway
+    }
+    return a.status
+}
+
+// writeFailure answers the client with a failure this attempt left unwritten
+// because a retry was expected; a retried status is passed on with its code
+func (a *attempt) writeFailure(w http.ResponseWriter) {
+    switch status := a.responseStatus(); {
+    case errors.Is(a.err, errBreakerOpen):
+        http.Error(w, "No healthy backends available", status)
+    case a.status == 0:
+        http.Error(w, "Backend server error", status)
+    default:
+        http.Error(w, http.StatusText(status), status)
+    }
+}
+
 // NewReverseProxy creates a new reverse proxy for the specified backend
 // This function wraps Go's standard httputil.ReverseProxy with custom logic
 // The proxy handles URL rewriting, header modification, and error handling
+// Created once per backend and shared by all requests to it
 // Time Complexity: O(1) - constant time proxy creation
 // Space Complexity: O(1) - single proxy instance per backend
 func NewReverseProxy(backend loadbalancer.Backend) *httputil.ReverseProxy {
     // Parse backend URL for proxy configuration
     // URL parsing is required for proper request forwarding
-    target, _ := url.Parse(backend.GetURL())
+    // The URL string is computed once rather than on every request
+    backendURL := backend.GetURL()
+    target, _ := url.Parse(backendURL)
 
     // Create reverse proxy with custom director function
     // Director function modifies outgoing requests before forwarding
     proxy := httputil.NewSingleHostReverseProxy(target)
 
+    // Use the backend's transport so per-backend client certificates and CA pools apply
+    proxy.Transport = backend.GetTransport()
+    proxy.BufferPool = bufferPool
+
     // Customize request director for additional processing
     // This allows header manipulation, logging, and request modification
     originalDirector := proxy.Director
     proxy.Director = func(req *http.Request) {
-        // Apply original director first to set basic proxy headers
+        // Rewrite path, Host and query for the matched route before the
+        // original director joins the path with the backend URL
+        if route := router.FromContext(req.Context()); route != nil {
+            route.Rewrite(req)
+        }
+
+        // Apply original director to set basic proxy headers
         originalDirector(req)
         
         // Add custom headers for backend identification
         // This helps backends identify requests coming through the proxy
         req.Header.Set("X-Forwarded-By", "go-reverse-proxy")
-        req.Header.Set("X-Backend-URL", backend.GetURL())
+        req.Header.Set("X-Backend-URL", backendURL)
+
+        // Pass the verified client certificate identity on to the backend
+        setClientCertHeaders(req)
+    }
+
+    // Turn retry-on status codes into errors while another attempt may follow,
+    // so the response is discarded instead of being sent to the client
+    proxy.ModifyResponse = func(resp *http.Response) error {
+        a := attemptFromContext(resp.Request.Context())
+        if a == nil {
+            return nil
+        }
+        a.status = resp.StatusCode
+        if !a.start.IsZero() {
+            a.latency = time.Since(a.start)
+        }
+        if a.canRetry && a.policy.retryOn[resp.StatusCode] {
+            return &retryStatusError{status: resp.StatusCode}
+        }
+        if a.sticky != nil {
+            a.sticky.setCookie(resp, backend)
+        }
+        return nil
     }
 
     // Customize error handler for better error reporting
     // Default error handler may not provide sufficient debugging information
     proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
-        // Log error for debugging purposes
-        // In production, this should use structured logging
-        
+        // Leave the response unwritten when the request will be retried
+        if a := attemptFromContext(r.Context()); a != nil {
+            a.err = err
+            if a.canRetry {
+                return
+            }
+        }
+
         // Return appropriate HTTP error status
         // 502 Bad Gateway indicates upstream server error
         http.Error(w, "Backend server error", http.StatusBadGateway)
     }
 
     return proxy
+}
+
+const (
+    // clientCertSubjectHeader carries the verified client certificate subject DN
+    clientCertSubjectHeader = "X-Client-Cert-Subject"
+    // clientCertSANsHeader carries the verified client certificate subject alternative names
+    clientCertSANsHeader = "X-Client-Cert-SANs"
+)
+
+// setClientCertHeaders exposes the verified client certificate to the backend
+// Incoming copies of the headers are always removed so clients cannot spoof them;
+// they are only set when the TLS handshake verified the certificate against the CA bundle
+// Time Complexity: O(s) where s is number of subject alternative names
+// Space Complexity: O(s) for the formatted header value
+func setClientCertHeaders(req *http.Request) {
+    req.Header.Del(clientCertSubjectHeader)
+    req.Header.Del(clientCertSANsHeader)
+
+    if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+        return
+    }
+
+    leaf := req.TLS.VerifiedChains[0][0]
+    req.Header.Set(clientCertSubjectHeader, leaf.Subject.String())
+    if sans := formatSANs(leaf); sans != "" {
+        req.Header.Set(clientCertSANsHeader, sans)
+    }
+}
+
+// formatSANs renders subject alternative names in OpenSSL style, e.g. "DNS:a.example, IP:10.0.0.1"
+// Time Complexity: O(s) where s is number of subject alternative names
+// Space Complexity: O(s) for the joined string
+func formatSANs(cert *x509.Certificate) string {
+    var sans []string
+    for _, name := range cert.DNSNames {
+        sans = append(sans, "DNS:"+name)
+    }
+    for _, ip := range cert.IPAddresses {
+        sans = append(sans, "IP:"+ip.String())
+    }
+    for _, email := range cert.EmailAddresses {
+        sans = append(sans, "email:"+email)
+    }
+    for _, uri := range cert.URIs {
+        sans = append(sans, "URI:"+uri.String())
+    }
+    return strings.Join(sans, ", ")
 }
\ No newline at end of file
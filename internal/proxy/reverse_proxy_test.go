@@ -0,0 +1,104 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// TestSetClientCertHeaders verifies verified client identities reach the backend
+// and that client supplied copies of the headers are discarded
+func TestSetClientCertHeaders(t *testing.T) {
+    leaf := &x509.Certificate{
+        Subject:        pkix.Name{CommonName: "client.example.com", Organization: []string{"Example"}},
+        DNSNames:       []string{"client.example.com"},
+        IPAddresses:    []net.IP{net.ParseIP("10.0.0.1")},
+        EmailAddresses: []string{"ops@example.com"},
+    }
+
+    req := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+    req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+    setClientCertHeaders(req)
+
+    if subject := req.Header.Get(clientCertSubjectHeader); subject != "CN=client.example.com,O=Example" {
+        t.Errorf("Expected subject CN=client.example.com,O=Example, got %q", subject)
+    }
+    expectedSANs := "DNS:client.example.com, IP:10.0.0.1, email:ops@example.com"
+    if sans := req.Header.Get(clientCertSANsHeader); sans != expectedSANs {
+        t.Errorf("Expected SANs %q, got %q", expectedSANs, sans)
+    }
+
+    spoofed := httptest.NewRequest("GET", "https://proxy.example.com/", nil)
+    spoofed.Header.Set(clientCertSubjectHeader, "CN=admin")
+    spoofed.Header.Set(clientCertSANsHeader, "DNS:admin")
+    spoofed.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}
+    setClientCertHeaders(spoofed)
+
+    if spoofed.Header.Get(clientCertSubjectHeader) != "" || spoofed.Header.Get(clientCertSANsHeader) != "" {
+        t.Errorf("Expected unverified request to carry no client certificate headers, got %v", spoofed.Header)
+    }
+}
+
+// newBenchmarkBackend returns a load balancer backend for an upstream replying "ok"
+func newBenchmarkBackend(b *testing.B) loadbalancer.Backend {
+    b.Helper()
+    upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("ok"))
+    }))
+    b.Cleanup(upstream.Close)
+
+    backend, err := loadbalancer.NewHTTPBackendWithTransport(upstream.URL, 1, loadbalancer.NewTransport(config.DefaultTransportConfig()))
+    if err != nil {
+        b.Fatalf("failed to create backend: %v", err)
+    }
+    return backend
+}
+
+// BenchmarkReverseProxyPerRequest measures building a reverse proxy for every request
+// This was the behaviour before proxies were cached per backend
+func BenchmarkReverseProxyPerRequest(b *testing.B) {
+    backend := newBenchmarkBackend(b)
+    req := httptest.NewRequest("GET", "/", nil)
+
+    b.ReportAllocs()
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        NewReverseProxy(backend).ServeHTTP(httptest.NewRecorder(), req)
+    }
+}
+
+// BenchmarkReverseProxyShared measures forwarding through the cached per-backend proxy
+func BenchmarkReverseProxyShared(b *testing.B) {
+    backend := newBenchmarkBackend(b)
+    gen := &generation{}
+    req := httptest.NewRequest("GET", "/", nil)
+
+    b.ReportAllocs()
+    b.ResetTimer()
+
+    for i := 0; i < b.N; i++ {
+        gen.reverseProxy(backend).ServeHTTP(httptest.NewRecorder(), req)
+    }
+}
+
+// TestReverseProxyCachedPerBackend verifies each backend gets exactly one proxy
+func TestReverseProxyCachedPerBackend(t *testing.T) {
+    first, _ := loadbalancer.NewHTTPBackend("http://a.internal", 1)
+    second, _ := loadbalancer.NewHTTPBackend("http://b.internal", 1)
+    gen := &generation{}
+
+    if gen.reverseProxy(first) != gen.reverseProxy(first) {
+        t.Error("Expected the same proxy for repeated requests to one backend")
+    }
+    if gen.reverseProxy(first) == gen.reverseProxy(second) {
+        t.Error("Expected distinct proxies for distinct backends")
+    }
+}
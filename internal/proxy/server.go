@@ -3,22 +3,31 @@ package proxy
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
-	"time"
+	"reflect"
+	"sync"
+	"sync/atomic"
 
+	"github.com/WillKirkmanM/proxy/internal/certs"
 	"github.com/WillKirkmanM/proxy/internal/config"
 	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/metrics"
 	"github.com/WillKirkmanM/proxy/internal/middleware"
 )
 
 // Server represents the main proxy server instance
 // This struct encapsulates all server dependencies using dependency injection pattern
 // The composition approach allows for easy testing and component substitution
+// Configuration-derived components live in a generation that is swapped atomically on reload
 type Server struct {
-    httpServer   *http.Server
-    loadBalancer loadbalancer.LoadBalancer
-    middleware   []middleware.Middleware
-    config       *config.Config
+    httpServer        *http.Server
+    metrics           *metrics.Metrics           // Prometheus collector shared by all components
+    metricsMiddleware middleware.Middleware      // Shared across generations, registered once with Prometheus
+    current           atomic.Pointer[generation] // Generation serving new requests
+    reloadMutex       sync.Mutex                 // Serialises reloads and health check lifecycle
+    runCtx            context.Context            // Parent context for health checks, set by Start
+    certificates      *certs.Store               // Serves TLS certificates by SNI, nil when serving plain HTTP
 }
 
 // NewServer creates a new proxy server instance using factory pattern
@@ -27,36 +36,44 @@ type Server struct {
 // Time Complexity: O(n) where n is number of backends for load balancer initialisation
 // Space Complexity: O(n) for storing backend configurations and middleware chain
 func NewServer(cfg *config.Config) (*Server, error) {
-    // Create load balancer using factory pattern based on configuration
-    // This allows runtime selection of load balancing algorithms
-    lb, err := loadbalancer.NewLoadBalancer(cfg.LoadBalance.Algorithm, cfg.LoadBalance.Backends)
-    if err != nil {
-        return nil, fmt.Errorf("failed to create load balancer: %w", err)
+    m := metrics.NewMetrics()
+    s := &Server{
+        metrics:           m,
+        metricsMiddleware: middleware.NewMetricsWith(m), // prometheus metrics
     }
 
-    // Build middleware chain using chain of responsibility pattern
-    // Order matters: rate limiting before caching to prevent cache pollution
-    middlewares := []middleware.Middleware{
-        middleware.NewRateLimiter(cfg.RateLimit),
-        middleware.NewCache(cfg.Cache),
-        middleware.NewMetrics(), // prometheus metrics
+    gen, err := newGeneration(cfg, nil, s.metrics, s.metricsMiddleware)
+    if err != nil {
+        return nil, err
     }
+    s.current.Store(gen)
 
     // Create HTTP server with configured timeouts
     // Timeouts are critical for preventing resource exhaustion attacks
-    server := &http.Server{
+    // The server itself is the handler so each request picks up the current generation
+    s.httpServer = &http.Server{
         Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
+        Handler:      s,
         ReadTimeout:  cfg.Server.ReadTimeout,
         WriteTimeout: cfg.Server.WriteTimeout,
         IdleTimeout:  cfg.Server.IdleTimeout,
     }
 
-    return &Server{
-        httpServer:   server,
-        loadBalancer: lb,
-        middleware:   middlewares,
-        config:       cfg,
-    }, nil
+    // Terminate TLS when a certificate and key are configured
+    if err := s.configureTLS(cfg.Server); err != nil {
+        return nil, err
+    }
+
+    // Without TLS there is no ALPN, so HTTP/2 clients such as gRPC connect
+    // with prior knowledge; HTTP/1.1 keeps working on the same port
+    if cfg.Server.H2C && !cfg.Server.TLSEnabled() {
+        protocols := &http.Protocols{}
+        protocols.SetHTTP1(true)
+        protocols.SetUnencryptedHTTP2(true)
+        s.httpServer.Protocols = protocols
+    }
+
+    return s, nil
 }
 
 // Start begins serving HTTP requests with graceful shutdown support
@@ -64,24 +81,35 @@ func NewServer(cfg *config.Config) (*Server, error) {
 // Time Complexity: O(1) for startup, O(∞) for request serving until context cancellation
 // Space Complexity: O(1) for server state, O(n) for concurrent request handling
 func (s *Server) Start(ctx context.Context) error {
-    // Set up HTTP handler with middleware chain
-    // The handler implements the template method pattern
-    s.httpServer.Handler = s.buildHandler()
-
     // Channel for server errors - prevents blocking on error conditions
     errChan := make(chan error, 1)
 
     // Start HTTP server in separate goroutine
     // This prevents blocking the main goroutine and allows concurrent shutdown handling
     go func() {
-        if err := s.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+        var err error
+        if s.certificates != nil {
+            // Certificates come from TLSConfig.GetCertificate, not from file arguments
+            err = s.httpServer.ListenAndServeTLS("", "")
+        } else {
+            err = s.httpServer.ListenAndServe()
+        }
+        if err != nil && err != http.ErrServerClosed {
             errChan <- fmt.Errorf("HTTP server error: %w", err)
         }
     }()
 
+    // Watch certificate files so renewals apply without a restart
+    if interval := s.Config().Server.TLSReloadInterval; s.certificates != nil && interval > 0 {
+        go s.certificates.Watch(ctx, interval)
+    }
+
     // Start health checking in background
     // Health checks run independently to avoid blocking request processing
-    go s.startHealthChecks(ctx)
+    s.reloadMutex.Lock()
+    s.runCtx = ctx
+    s.current.Load().startHealthChecks(ctx)
+    s.reloadMutex.Unlock()
 
     // Wait for either error or context cancellation
     // This implements the select pattern for concurrent event handling
@@ -98,136 +126,81 @@ func (s *Server) Start(ctx context.Context) error {
 // Time Complexity: O(1) for shutdown initiation, depends on active connection count
 // Space Complexity: O(1) - no additional memory allocation during shutdown
 func (s *Server) Shutdown(ctx context.Context) error {
+    // Stop health checks of the active generation however shutdown ends
+    // Earlier generations stopped theirs when they were replaced
+    defer func() {
+        s.reloadMutex.Lock()
+        s.current.Load().stopHealthChecks()
+        s.reloadMutex.Unlock()
+    }()
+
     // Shutdown HTTP server with context timeout
     // This ensures shutdown completes within reasonable time bounds
     if err := s.httpServer.Shutdown(ctx); err != nil {
         return fmt.Errorf("failed to shutdown HTTP server: %w", err)
     }
 
-    // Additional cleanup for load balancer and other components would go here
-    // For now, context cancellation handles background goroutine cleanup
+    // Hijacked connections are not covered by the HTTP server's shutdown
+    return s.current.Load().upgrades.shutdown(ctx)
+}
 
-    return nil
+// ServeHTTP dispatches a request to the generation current at arrival time
+// The request keeps its generation until completion, so a reload never
+// changes backends or middleware underneath an in-flight request
+// Time Complexity: O(1) atomic load plus generation handler cost
+// Space Complexity: O(1) - no additional allocations
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+    s.current.Load().handler.ServeHTTP(w, r)
 }
 
-// buildHandler constructs the HTTP handler with middleware chain
-// Implements chain of responsibility pattern for request processing
-// Each middleware can modify request/response or short-circuit the chain
-// Time Complexity: O(m) where m is number of middleware for chain construction
-// Space Complexity: O(m) for middleware chain storage
-func (s *Server) buildHandler() http.Handler {
-    // Start with the core proxy handler
-    // This is the final handler in the chain that performs actual proxying
-    var handler http.Handler = http.HandlerFunc(s.proxyHandler)
-
-    // Apply middleware in reverse order to build chain correctly
-    // Middleware wrapping creates nested function calls: middleware1(middleware2(handler))
-    for i := len(s.middleware) - 1; i >= 0; i-- {
-        handler = s.middleware[i].Wrap(handler)
+// Reload validates cfg and atomically swaps in a new generation built from it
+// The load balancer backend set, middleware chain and health settings are replaced;
+// on any error the running generation is left untouched and the error returned
+// Listener settings (port, timeouts, TLS) cannot change on a live socket and
+// only take effect after a restart
+// Time Complexity: O(n) where n is number of backends
+// Space Complexity: O(n) for the new generation
+func (s *Server) Reload(cfg *config.Config) error {
+    if err := cfg.Validate(loadbalancer.Algorithms()); err != nil {
+        return err
     }
 
-    return handler
-}
+    s.reloadMutex.Lock()
+    defer s.reloadMutex.Unlock()
 
-// proxyHandler performs the core reverse proxy functionality
-// This is where actual request forwarding happens using the selected backend
-// Time Complexity: O(log n) for backend selection with balanced algorithms
-// Space Complexity: O(1) for request processing, O(k) for request/response buffering
-func (s *Server) proxyHandler(w http.ResponseWriter, r *http.Request) {
-    // Select backend using configured load balancing algorithm
-    // Load balancer handles backend health and availability
-    backend, err := s.loadBalancer.SelectBackend(r)
+    previous := s.current.Load()
+    next, err := newGeneration(cfg, previous, s.metrics, s.metricsMiddleware)
     if err != nil {
-        http.Error(w, "No healthy backends available", http.StatusServiceUnavailable)
-        return
+        return err
     }
 
-    // Create reverse proxy for selected backend
-    // Each request gets a fresh proxy instance to avoid state issues
-    proxy := NewReverseProxy(backend)
-    
-    // Forward request to selected backend
-    // The reverse proxy handles URL rewriting, header forwarding, and response copying
-    proxy.ServeHTTP(w, r)
-}
-
-// startHealthChecks begins background health monitoring for all backends
-// Health checks run on configurable intervals to detect backend failures
-// Uses observer pattern to notify load balancer of backend status changes
-// Time Complexity: O(n) per check interval where n is number of backends
-// Space Complexity: O(1) for health check state per backend
-func (s *Server) startHealthChecks(ctx context.Context) {
-    // Create ticker for periodic health checks
-    // Ticker ensures consistent check intervals regardless of check duration
-    ticker := time.NewTicker(s.config.Health.Interval)
-    defer ticker.Stop()
-
-    // Perform initial health check before starting periodic checks
-    // This ensures backend status is known at startup
-    s.performHealthChecks()
-
-    // Run health checks on configured intervals until context cancellation
-    // This implements the observer pattern for backend health monitoring
-    for {
-        select {
-        case <-ticker.C:
-            s.performHealthChecks()
-        case <-ctx.Done():
-            return
-        }
+    if !reflect.DeepEqual(cfg.Server, previous.config.Server) {
+        log.Printf("Server listener settings changed; they take effect after restart")
     }
-}
 
-// performHealthChecks executes health checks for all configured backends
-// Each backend is checked concurrently to minimize total check time
-// Results are reported to load balancer using observer pattern
-// Time Complexity: O(n) where n is number of backends (concurrent execution)
-// Space Complexity: O(n) for goroutine stacks during concurrent health checks
-func (s *Server) performHealthChecks() {
-    // Get all backends from load balancer for health checking
-    // This ensures we check all backends regardless of current health status
-    backends := s.loadBalancer.GetBackends()
-
-    // Check each backend concurrently to minimize total check time
-    // Concurrent checks prevent one slow backend from delaying others
-    for _, backend := range backends {
-        go func(b loadbalancer.Backend) {
-            // Perform HTTP health check with configured timeout
-            // Timeout prevents health checks from hanging indefinitely
-            healthy := s.checkBackendHealth(b)
-            
-            // Notify load balancer of backend health status
-            // Load balancer updates routing decisions based on health status
-            s.loadBalancer.UpdateBackendHealth(b.GetURL(), healthy)
-        }(backend)
+    // A reload is also a prompt to pick up rotated certificates immediately
+    if s.certificates != nil {
+        if err := s.certificates.Reload(); err != nil {
+            log.Printf("Certificate reload failed, keeping current certificate: %v", err)
+        }
     }
-}
 
-// checkBackendHealth verifies if a specific backend is healthy and responsive
-// Uses HTTP GET request to configured health check endpoint
-// Timeout ensures health checks don't block other operations
-// Time Complexity: O(1) - single HTTP request with bounded timeout
-// Space Complexity: O(1) - minimal request/response buffering
-func (s *Server) checkBackendHealth(backend loadbalancer.Backend) bool {
-    // Create HTTP client with health check timeout
-    // Dedicated client prevents interference with proxy requests
-    client := &http.Client{
-        Timeout: s.config.Health.Timeout,
+    // Publish the new generation before stopping the old health checks
+    // so there is never a window without an active health checker
+    s.current.Store(next)
+    if s.runCtx != nil {
+        next.startHealthChecks(s.runCtx)
     }
+    previous.stopHealthChecks()
+    previous.closeIdleConnections()
+    previous.forgetReplaced(next)
 
-    // Construct health check URL by appending health path to backend URL
-    // This allows backends to implement custom health check endpoints
-    healthURL := backend.GetURL() + s.config.Health.Path
-
-    // Perform GET request to health check endpoint
-    // GET is used as it's idempotent and widely supported for health checks
-    resp, err := client.Get(healthURL)
-    if err != nil {
-        return false
-    }
-    defer resp.Body.Close()
+    return nil
+}
 
-    // Consider backend healthy if HTTP status is 2xx
-    // This is a common convention for health check endpoints
-    return resp.StatusCode >= 200 && resp.StatusCode < 300
+// Config returns the configuration of the generation currently serving requests
+// Time Complexity: O(1) - atomic load
+// Space Complexity: O(1) - returns shared pointer
+func (s *Server) Config() *config.Config {
+    return s.current.Load().config
 }
\ No newline at end of file
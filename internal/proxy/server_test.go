@@ -0,0 +1,630 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// newTestBackend starts an HTTP server that replies with its name
+// Returns the server so tests can use its URL as a backend
+func newTestBackend(t *testing.T, name string) *httptest.Server {
+    t.Helper()
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(name))
+    }))
+    t.Cleanup(backend.Close)
+    return backend
+}
+
+// newTestConfig returns a valid configuration pointing at the given backends
+// Cache and rate limiting are disabled so every request reaches a backend
+func newTestConfig(urls ...string) *config.Config {
+    cfg := config.DefaultConfig()
+    cfg.Cache.Enabled = false
+    cfg.RateLimit.Enabled = false
+    cfg.Health.Enabled = false
+    for _, url := range urls {
+        cfg.LoadBalance.Backends = append(cfg.LoadBalance.Backends, config.BackendConfig{URL: url, Weight: 1})
+    }
+    return cfg
+}
+
+// serve sends a GET request through the server and returns the response body
+func serve(t *testing.T, handler http.Handler, path string) (int, string) {
+    t.Helper()
+    w := httptest.NewRecorder()
+    handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+    body, _ := io.ReadAll(w.Result().Body)
+    return w.Code, string(body)
+}
+
+// TestReloadSwapsBackends verifies a reload routes new requests to the new backend set
+func TestReloadSwapsBackends(t *testing.T) {
+    oldBackend := newTestBackend(t, "old")
+    newBackend := newTestBackend(t, "new")
+
+    server, err := NewServer(newTestConfig(oldBackend.URL))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if _, body := serve(t, server, "/"); body != "old" {
+        t.Fatalf("Expected response from old backend, got %q", body)
+    }
+
+    if err := server.Reload(newTestConfig(newBackend.URL)); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+
+    if _, body := serve(t, server, "/"); body != "new" {
+        t.Errorf("Expected response from new backend after reload, got %q", body)
+    }
+    if server.Config().LoadBalance.Backends[0].URL != newBackend.URL {
+        t.Error("Expected Config to return reloaded configuration")
+    }
+}
+
+// TestReloadRejectsInvalidConfig verifies a bad config keeps the old generation serving
+func TestReloadRejectsInvalidConfig(t *testing.T) {
+    backend := newTestBackend(t, "old")
+
+    server, err := NewServer(newTestConfig(backend.URL))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    invalid := newTestConfig("not a url")
+    invalid.LoadBalance.Algorithm = "unknown"
+    if err := server.Reload(invalid); err == nil {
+        t.Fatal("Expected reload of invalid config to fail")
+    }
+
+    if _, body := serve(t, server, "/"); body != "old" {
+        t.Errorf("Expected old backend to keep serving, got %q", body)
+    }
+}
+
+// TestReloadPreservesHealth verifies backend health carries over by pool and URL
+// A backend known to be down must not receive traffic right after reload
+func TestReloadPreservesHealth(t *testing.T) {
+    up := newTestBackend(t, "up")
+    down := newTestBackend(t, "down")
+
+    server, err := NewServer(newTestConfig(up.URL, down.URL))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    server.current.Load().pools[config.DefaultPoolName].UpdateBackendHealth(down.URL, false)
+
+    if err := server.Reload(newTestConfig(up.URL, down.URL)); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+
+    for i := 0; i < 4; i++ {
+        if _, body := serve(t, server, "/"); body != "up" {
+            t.Fatalf("Expected only healthy backend after reload, got %q", body)
+        }
+    }
+}
+
+// TestReloadPreservesHealthPerPool verifies pools sharing a backend URL keep
+// their own health across a reload and in the health gauge
+func TestReloadPreservesHealthPerPool(t *testing.T) {
+    shared := newTestBackend(t, "shared")
+
+    cfg := newTestConfig(shared.URL)
+    cfg.Routing.Pools = []config.PoolConfig{
+        {Name: "api", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: shared.URL, Weight: 1}}},
+    }
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    server.current.Load().pools[config.DefaultPoolName].UpdateBackendHealth(shared.URL, false)
+
+    if err := server.Reload(cfg); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+
+    pools := server.current.Load().pools
+    if pools[config.DefaultPoolName].GetBackends()[0].IsHealthy() {
+        t.Error("Expected the default pool to keep its backend down")
+    }
+    if !pools["api"].GetBackends()[0].IsHealthy() {
+        t.Error("Expected the api pool to keep its backend up")
+    }
+
+    if health := healthGauges(t, shared.URL); health[config.DefaultPoolName] != 0 || health["api"] != 1 {
+        t.Errorf("Expected health gauge 0 for default and 1 for api, got %v", health)
+    }
+}
+
+// healthGauges returns proxy_backend_health of the backend with url by pool
+func healthGauges(t *testing.T, url string) map[string]float64 {
+    t.Helper()
+    return backendGauges(t, "proxy_backend_health", url)
+}
+
+// backendGauges returns the per-backend gauge name of the backend with url by pool
+func backendGauges(t *testing.T, name, url string) map[string]float64 {
+    t.Helper()
+    families, err := prometheus.DefaultGatherer.Gather()
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    gauges := map[string]float64{}
+    for _, family := range families {
+        if family.GetName() != name {
+            continue
+        }
+        for _, metric := range family.GetMetric() {
+            labels := map[string]string{}
+            for _, label := range metric.GetLabel() {
+                labels[label.GetName()] = label.GetValue()
+            }
+            if labels["backend_url"] == url {
+                gauges[labels["pool"]] = metric.GetGauge().GetValue()
+            }
+        }
+    }
+    return gauges
+}
+
+// TestReloadUnsubscribesHealthObservers verifies a replaced generation stops
+// reporting health transitions of its pools
+func TestReloadUnsubscribesHealthObservers(t *testing.T) {
+    backend := newTestBackend(t, "backend")
+
+    server, err := NewServer(newTestConfig(backend.URL))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    previous := server.current.Load()
+    if err := server.Reload(newTestConfig(backend.URL)); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+
+    previous.pools[config.DefaultPoolName].UpdateBackendHealth(backend.URL, false)
+    if health := healthGauges(t, backend.URL); health[config.DefaultPoolName] != 1 {
+        t.Errorf("Expected the replaced pool to leave the health gauge alone, got %v", health)
+    }
+}
+
+// TestReloadReusesUnchangedMiddleware verifies cache state survives reloads
+// that change neither the cache settings nor where requests are routed
+func TestReloadReusesUnchangedMiddleware(t *testing.T) {
+    backend := newTestBackend(t, "cached")
+
+    cfg := newTestConfig(backend.URL)
+    cfg.Cache.Enabled = true
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    previousCache := server.current.Load().cache
+
+    next := newTestConfig(backend.URL)
+    next.Cache.Enabled = true
+    next.LoadBalance.Algorithm = "least-connections"
+    if err := server.Reload(next); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+    if server.current.Load().cache != previousCache {
+        t.Error("Expected unchanged cache configuration to reuse cache")
+    }
+
+    next = newTestConfig(backend.URL)
+    next.Cache.Enabled = true
+    next.Cache.TTL *= 2
+    if err := server.Reload(next); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+    if server.current.Load().cache == previousCache {
+        t.Error("Expected changed cache TTL to build a new cache")
+    }
+
+    // Responses cached under the old routes must not outlive them
+    previousCache = server.current.Load().cache
+    next = newTestConfig(backend.URL)
+    next.Cache.Enabled = true
+    next.Cache.TTL *= 2
+    next.Routing.Routes = []config.RouteConfig{{PathPrefix: "/api/", Rewrite: config.RewriteConfig{StripPrefix: "/api"}, Pool: config.DefaultPoolName}}
+    if err := server.Reload(next); err != nil {
+        t.Fatalf("unexpected reload error: %v", err)
+    }
+    if server.current.Load().cache == previousCache {
+        t.Error("Expected changed routes to build a new cache")
+    }
+}
+
+// TestRoutingToPools verifies routes dispatch to named pools and unmatched requests get 404
+func TestRoutingToPools(t *testing.T) {
+    api := newTestBackend(t, "api")
+
+    cfg := newTestConfig()
+    cfg.Routing.Pools = []config.PoolConfig{
+        {Name: "api", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: api.URL, Weight: 1}}},
+    }
+    cfg.Routing.Routes = []config.RouteConfig{{Name: "api", PathPrefix: "/api/", Pool: "api"}}
+    cfg.Routing.DefaultPool = ""
+
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if code, body := serve(t, server, "/api/users"); code != http.StatusOK || body != "api" {
+        t.Errorf("Expected api pool to serve /api/users, got %d %q", code, body)
+    }
+    if code, _ := serve(t, server, "/other"); code != http.StatusNotFound {
+        t.Errorf("Expected 404 for unmatched request, got %d", code)
+    }
+}
+
+// TestCacheKeysByRoute verifies host routes sharing a path keep separate cached responses
+func TestCacheKeysByRoute(t *testing.T) {
+    first := newTestBackend(t, "first")
+    second := newTestBackend(t, "second")
+
+    cfg := newTestConfig()
+    cfg.Cache.Enabled = true
+    cfg.Routing.Pools = []config.PoolConfig{
+        {Name: "first", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: first.URL, Weight: 1}}},
+        {Name: "second", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: second.URL, Weight: 1}}},
+    }
+    cfg.Routing.Routes = []config.RouteConfig{
+        {Name: "first", Host: "first.example.com", Pool: "first"},
+        {Name: "second", Host: "second.example.com", Pool: "second"},
+    }
+    cfg.Routing.DefaultPool = ""
+
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    for i := 0; i < 2; i++ {
+        for _, host := range []string{"first", "second"} {
+            w := httptest.NewRecorder()
+            req := httptest.NewRequest("GET", "/shared", nil)
+            req.Host = host + ".example.com"
+            server.ServeHTTP(w, req)
+            if body := w.Body.String(); body != host {
+                t.Errorf("Expected %s to be served by its own pool, got %q", req.Host, body)
+            }
+            if cached := w.Header().Get("X-Cache-Status") == "HIT"; cached != (i == 1) {
+                t.Errorf("Expected cache hit %v for %s on request %d", i == 1, req.Host, i+1)
+            }
+        }
+    }
+}
+
+// TestCacheKeysBySplitSide verifies the stable and canary side of a split never
+// answer each other's requests from the cache
+func TestCacheKeysBySplitSide(t *testing.T) {
+    stable := newTestBackend(t, "stable")
+    canary := newTestBackend(t, "canary")
+
+    cfg := newTestConfig(stable.URL)
+    cfg.Cache.Enabled = true
+    cfg.Routing.Pools = []config.PoolConfig{
+        {Name: "canary", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: canary.URL, Weight: 1}}},
+    }
+    cfg.Routing.Splits = []config.SplitConfig{{
+        Name:    "web",
+        Stable:  config.DefaultPoolName,
+        Canary:  "canary",
+        Headers: []config.HeaderMatchConfig{{Name: "X-Canary", Value: "1"}},
+    }}
+    cfg.Routing.DefaultPool = "web"
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    for i := 0; i < 2; i++ {
+        for _, side := range []string{"stable", "canary"} {
+            req := httptest.NewRequest("GET", "/shared", nil)
+            if side == "canary" {
+                req.Header.Set("X-Canary", "1")
+            }
+            w := httptest.NewRecorder()
+            server.ServeHTTP(w, req)
+            if body := w.Body.String(); body != side {
+                t.Errorf("Expected the %s side to answer request %d, got %q", side, i+1, body)
+            }
+        }
+    }
+}
+
+// TestRouteRewriteAndRedirect verifies rewrites reach the backend and redirects never do
+func TestRouteRewriteAndRedirect(t *testing.T) {
+    echo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte(r.Host + r.URL.RequestURI()))
+    }))
+    defer echo.Close()
+
+    cfg := newTestConfig(echo.URL)
+    cfg.Routing.Routes = []config.RouteConfig{
+        {
+            PathPrefix: "/api/",
+            Pool:       config.DefaultPoolName,
+            Rewrite:    config.RewriteConfig{StripPrefix: "/api", Host: "api.internal", AddQuery: map[string]string{"via": "proxy"}},
+        },
+        {PathPrefix: "/legacy/", Redirect: config.RedirectConfig{URL: "/api/", Status: http.StatusPermanentRedirect}},
+    }
+
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if _, body := serve(t, server, "/api/users"); body != "api.internal/users?via=proxy" {
+        t.Errorf("Expected rewritten request api.internal/users?via=proxy, got %q", body)
+    }
+
+    w := httptest.NewRecorder()
+    server.ServeHTTP(w, httptest.NewRequest("GET", "/legacy/users", nil))
+    if w.Code != http.StatusPermanentRedirect || w.Header().Get("Location") != "/api/" {
+        t.Errorf("Expected 308 to /api/, got %d %q", w.Code, w.Header().Get("Location"))
+    }
+}
+
+// TestConnectionTracking verifies in-flight requests are counted against their backend
+// until the streamed response has finished
+func TestConnectionTracking(t *testing.T) {
+    started := make(chan struct{})
+    release := make(chan struct{})
+    slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        w.Write([]byte("partial"))
+        w.(http.Flusher).Flush()
+        close(started)
+        <-release
+    }))
+    defer slow.Close()
+
+    cfg := newTestConfig(slow.URL)
+    cfg.LoadBalance.Algorithm = "least-connections"
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    backend := server.current.Load().pools[config.DefaultPoolName].GetBackends()[0]
+
+    finished := make(chan struct{})
+    go func() {
+        serve(t, server, "/stream")
+        close(finished)
+    }()
+
+    <-started
+    if connections := backend.GetConnections(); connections != 1 {
+        t.Errorf("Expected 1 connection while streaming, got %d", connections)
+    }
+
+    close(release)
+    <-finished
+    if connections := backend.GetConnections(); connections != 0 {
+        t.Errorf("Expected 0 connections after completion, got %d", connections)
+    }
+}
+
+// TestCircuitBreakerRemovesFailingBackend verifies a backend answering 5xx is
+// taken out of rotation once its breaker trips
+func TestCircuitBreakerRemovesFailingBackend(t *testing.T) {
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "broken", http.StatusInternalServerError)
+    }))
+    defer failing.Close()
+    healthy := newTestBackend(t, "healthy")
+
+    cfg := newTestConfig(failing.URL, healthy.URL)
+    cfg.LoadBalance.CircuitBreaker.Enabled = true
+    cfg.LoadBalance.CircuitBreaker.ConsecutiveFailures = 2
+    cfg.LoadBalance.CircuitBreaker.ErrorRate = 0
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    // Round-robin alternates until the failing backend has failed twice
+    for i := 0; i < 4; i++ {
+        serve(t, server, "/")
+    }
+    for i := 0; i < 4; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "healthy" {
+            t.Fatalf("Request %d: expected only the healthy backend, got %d %q", i, code, body)
+        }
+    }
+}
+
+// TestOutlierDetectionEjectsFailingBackend verifies live 5xx responses eject a
+// backend even when active health checks would pass it
+func TestOutlierDetectionEjectsFailingBackend(t *testing.T) {
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "broken", http.StatusBadGateway)
+    }))
+    defer failing.Close()
+    healthy := newTestBackend(t, "healthy")
+
+    cfg := newTestConfig(failing.URL, healthy.URL)
+    cfg.LoadBalance.OutlierDetection = config.DefaultOutlierDetectionConfig()
+    cfg.LoadBalance.OutlierDetection.Enabled = true
+    cfg.LoadBalance.OutlierDetection.Consecutive5xx = 2
+    cfg.LoadBalance.OutlierDetection.MaxEjectionPercent = 50
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    for i := 0; i < 4; i++ {
+        serve(t, server, "/")
+    }
+    for i := 0; i < 4; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "healthy" {
+            t.Fatalf("Request %d: expected ejected backend to be skipped, got %d %q", i, code, body)
+        }
+    }
+}
+
+// TestP2CPenalisesFastFailures verifies a backend failing immediately loses the
+// p2c-ewma choice to a slower backend that answers
+func TestP2CPenalisesFastFailures(t *testing.T) {
+    failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        http.Error(w, "broken", http.StatusServiceUnavailable)
+    }))
+    defer failing.Close()
+    slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        time.Sleep(10 * time.Millisecond)
+        w.Write([]byte("slow"))
+    }))
+    defer slow.Close()
+
+    cfg := newTestConfig(failing.URL, slow.URL)
+    cfg.LoadBalance.Algorithm = config.P2CEWMAAlgorithm
+    cfg.LoadBalance.CircuitBreaker.Enabled = false
+    cfg.LoadBalance.OutlierDetection.Enabled = false
+    cfg.LoadBalance.Retry.Enabled = false
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    // Unsampled backends score zero, so the first requests reach both
+    for i := 0; i < 2; i++ {
+        serve(t, server, "/")
+    }
+    for i := 0; i < 8; i++ {
+        if code, body := serve(t, server, "/"); code != http.StatusOK || body != "slow" {
+            t.Fatalf("Request %d: expected the answering backend to win, got %d %q", i, code, body)
+        }
+    }
+}
+
+// TestStickySessions verifies the first response pins the client to its backend,
+// later requests honour the cookie and an unavailable backend is replaced
+func TestStickySessions(t *testing.T) {
+    first := newTestBackend(t, "first")
+    second := newTestBackend(t, "second")
+
+    cfg := newTestConfig(first.URL, second.URL)
+    cfg.LoadBalance.Sticky = config.DefaultStickyConfig()
+    cfg.LoadBalance.Sticky.Enabled = true
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    request := func(cookie *http.Cookie) (string, *http.Cookie) {
+        req := httptest.NewRequest("GET", "/", nil)
+        if cookie != nil {
+            req.AddCookie(cookie)
+        }
+        w := httptest.NewRecorder()
+        server.ServeHTTP(w, req)
+        var set *http.Cookie
+        for _, c := range w.Result().Cookies() {
+            if c.Name == "proxy_backend" {
+                set = c
+            }
+        }
+        return w.Body.String(), set
+    }
+
+    pinned, cookie := request(nil)
+    if cookie == nil || !cookie.HttpOnly {
+        t.Fatalf("Expected an HttpOnly sticky cookie on the first response, got %v", cookie)
+    }
+    for i := 0; i < 4; i++ {
+        body, again := request(cookie)
+        if body != pinned {
+            t.Fatalf("Request %d: expected sticky backend %q, got %q", i, pinned, body)
+        }
+        if again != nil {
+            t.Errorf("Request %d: expected no new cookie while pinned", i)
+        }
+    }
+
+    // Take the pinned backend down; the cookie must then be ignored and replaced
+    gen := server.current.Load()
+    for _, backend := range gen.pools[config.DefaultPoolName].GetBackends() {
+        if stickyValue(backend) == cookie.Value {
+            backend.SetHealthy(false)
+        }
+    }
+    body, replaced := request(cookie)
+    if body == pinned || replaced == nil || replaced.Value == cookie.Value {
+        t.Errorf("Expected a new backend and cookie once the pinned one is down, got %q %v", body, replaced)
+    }
+}
+
+// TestTrafficSplit verifies a split route sends matched requests and its
+// percentage to the canary pool, pins percentage choices only and follows reloads
+func TestTrafficSplit(t *testing.T) {
+    stable := newTestBackend(t, "stable")
+    canary := newTestBackend(t, "canary")
+
+    cfg := newTestConfig(stable.URL)
+    cfg.Routing.Pools = []config.PoolConfig{
+        {Name: "canary", Algorithm: "round-robin", Backends: []config.BackendConfig{{URL: canary.URL, Weight: 1}}},
+    }
+    cfg.Routing.Splits = []config.SplitConfig{{
+        Name:    "web",
+        Stable:  config.DefaultPoolName,
+        Canary:  "canary",
+        Headers: []config.HeaderMatchConfig{{Name: "X-Canary", Value: "1"}},
+        Sticky:  config.SplitStickyConfig{Enabled: true, Cookie: "proxy_variant"},
+    }}
+    cfg.Routing.DefaultPool = "web"
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    request := func(header string, cookie *http.Cookie) (string, *http.Cookie) {
+        req := httptest.NewRequest("GET", "/", nil)
+        if header != "" {
+            req.Header.Set("X-Canary", header)
+        }
+        if cookie != nil {
+            req.AddCookie(cookie)
+        }
+        w := httptest.NewRecorder()
+        server.ServeHTTP(w, req)
+        var set *http.Cookie
+        for _, c := range w.Result().Cookies() {
+            if c.Name == "proxy_variant" {
+                set = c
+            }
+        }
+        return w.Body.String(), set
+    }
+
+    body, pin := request("", nil)
+    if body != "stable" || pin == nil || pin.Value != "stable" {
+        t.Fatalf("Expected stable response and pin at 0%%, got %q %v", body, pin)
+    }
+    // A one-off header match neither uses nor replaces the stable pin
+    if body, repinned := request("1", pin); body != "canary" || repinned != nil {
+        t.Errorf("Expected X-Canary to reach the canary without a pin, got %q %v", body, repinned)
+    }
+    if body, _ := request("", pin); body != "stable" {
+        t.Errorf("Expected the stable pin to hold after a header match, got %q", body)
+    }
+
+    // The stable pin is ignored once a reload sends the stable side no traffic
+    cfg.Routing.Splits[0].Percent = 100
+    if err := server.Reload(cfg); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    body, repinned := request("", pin)
+    if body != "canary" || repinned == nil || repinned.Value != "canary" {
+        t.Errorf("Expected canary response and new pin at 100%%, got %q %v", body, repinned)
+    }
+}
@@ -0,0 +1,27 @@
+package proxy
+
+import (
+	"fmt"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/metrics"
+)
+
+// newSplits composes the balancers of pools into the traffic splits of cfg
+// The percentage only changes with the configuration, so a reload is the way
+// to shift traffic between the sides
+// Time Complexity: O(s * m) where s is number of splits and m match conditions
+// Space Complexity: O(s * m) for compiled splits
+func newSplits(cfg *config.Config, pools map[string]loadbalancer.LoadBalancer, m *metrics.Metrics) (map[string]*loadbalancer.SplitBalancer, error) {
+    splits := make(map[string]*loadbalancer.SplitBalancer, len(cfg.Routing.Splits))
+    for _, splitConfig := range cfg.Routing.Splits {
+        split, err := loadbalancer.NewSplitBalancer(splitConfig, pools[splitConfig.Stable], pools[splitConfig.Canary])
+        if err != nil {
+            return nil, fmt.Errorf("failed to create split %s: %w", splitConfig.Name, err)
+        }
+        m.SetSplitPercent(splitConfig.Name, split.Percent())
+        splits[splitConfig.Name] = split
+    }
+    return splits, nil
+}
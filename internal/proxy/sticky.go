@@ -0,0 +1,82 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"net/http"
+	"strconv"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// stickyPolicy pins clients of a pool to one backend with a cookie
+type stickyPolicy struct {
+    config config.StickyConfig
+}
+
+// newStickyPolicies returns the sticky session policy of every pool with it enabled
+// Time Complexity: O(p) where p is number of pools
+// Space Complexity: O(p) for the policies
+func newStickyPolicies(cfg *config.Config) map[string]*stickyPolicy {
+    policies := make(map[string]*stickyPolicy)
+    if cfg.LoadBalance.Sticky.Enabled {
+        policies[config.DefaultPoolName] = &stickyPolicy{config: cfg.LoadBalance.Sticky}
+    }
+    for _, pool := range cfg.Routing.Pools {
+        if pool.Sticky.Enabled {
+            policies[pool.Name] = &stickyPolicy{config: pool.Sticky}
+        }
+    }
+    return policies
+}
+
+// stickyValue identifies backend in the cookie without exposing its URL
+func stickyValue(backend loadbalancer.Backend) string {
+    h := fnv.New64a()
+    h.Write([]byte(backend.GetURL()))
+    return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// backend returns the available backend of lb named by the request's cookie
+// Returns nil when there is no cookie or its backend cannot take the request,
+// in which case the load balancer picks one and the cookie is replaced
+// Time Complexity: O(n) where n is number of backends in the pool
+// Space Complexity: O(n) for the backend list copy
+func (p *stickyPolicy) backend(r *http.Request, lb loadbalancer.LoadBalancer) loadbalancer.Backend {
+    cookie, err := r.Cookie(p.config.Cookie)
+    if err != nil {
+        return nil
+    }
+    for _, backend := range lb.GetBackends() {
+        if stickyValue(backend) == cookie.Value {
+            if backend.IsAvailable() {
+                return backend
+            }
+            return nil
+        }
+    }
+    return nil
+}
+
+// setCookie pins the client to backend unless its request already names it
+// Time Complexity: O(1) - single header addition
+// Space Complexity: O(1) - one cookie
+func (p *stickyPolicy) setCookie(resp *http.Response, backend loadbalancer.Backend) {
+    value := stickyValue(backend)
+    if cookie, err := resp.Request.Cookie(p.config.Cookie); err == nil && cookie.Value == value {
+        return
+    }
+
+    cookie := &http.Cookie{
+        Name:     p.config.Cookie,
+        Value:    value,
+        Path:     "/",
+        Secure:   p.config.Secure,
+        HttpOnly: p.config.HTTPOnly,
+        SameSite: http.SameSiteLaxMode,
+    }
+    if p.config.TTL > 0 {
+        cookie.MaxAge = int(p.config.TTL.Seconds())
+    }
+    resp.Header.Add("Set-Cookie", cookie.String())
+}
@@ -0,0 +1,367 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/metrics"
+)
+
+// WebSocket close status codes sent by the proxy (RFC 6455 section 7.4.1)
+const (
+    closeNormal    uint16 = 1000 // Idle timeout or maximum lifetime reached
+    closeGoingAway uint16 = 1001 // Proxy shutting down
+)
+
+// isUpgradeRequest reports whether r asks to switch protocols
+func isUpgradeRequest(r *http.Request) bool {
+    return r.Header.Get("Upgrade") != ""
+}
+
+// upgradeRegistry tracks connections upgraded through the proxy
+// Hijacked connections are invisible to http.Server.Shutdown, so the registry
+// lets the server close them gracefully. It outlives reloads: generations share it
+type upgradeRegistry struct {
+    mutex   sync.Mutex
+    conns   map[*upgradedConn]struct{}
+    metrics *metrics.Metrics
+}
+
+// newUpgradeRegistry creates an empty registry reporting WebSockets to m
+func newUpgradeRegistry(m *metrics.Metrics) *upgradeRegistry {
+    return &upgradeRegistry{conns: make(map[*upgradedConn]struct{}), metrics: m}
+}
+
+// upgradeWriter intercepts the hijack of an upgrade request so the client
+// connection is tracked with the backend that accepted the upgrade
+type upgradeWriter struct {
+    http.ResponseWriter
+    registry  *upgradeRegistry
+    pool      string
+    backend   string
+    limits    config.WebSocketConfig
+    websocket bool
+}
+
+// writer wraps w for the upgrade request r forwarded to backend of pool
+func (u *upgradeRegistry) writer(w http.ResponseWriter, r *http.Request, pool string, backend loadbalancer.Backend, limits config.WebSocketConfig) http.ResponseWriter {
+    return &upgradeWriter{
+        ResponseWriter: w,
+        registry:       u,
+        pool:           pool,
+        backend:        backend.GetURL(),
+        limits:         limits,
+        websocket:      strings.EqualFold(r.Header.Get("Upgrade"), "websocket"),
+    }
+}
+
+// Hijack takes over the client connection once the backend switched protocols
+// Deadlines left by the server's read and write timeouts are cleared; the
+// idle and lifetime limits apply instead
+func (w *upgradeWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+    if err != nil {
+        return nil, nil, err
+    }
+    conn.SetDeadline(time.Time{})
+    return w.registry.track(conn, w.pool, w.backend, w.limits, w.websocket), rw, nil
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (w *upgradeWriter) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
+
+// track registers conn and starts enforcing limits on it
+// Time Complexity: O(1) - map insertion
+// Space Complexity: O(1) per connection plus one watcher goroutine
+func (u *upgradeRegistry) track(conn net.Conn, pool, backend string, limits config.WebSocketConfig, websocket bool) *upgradedConn {
+    c := &upgradedConn{
+        Conn:      conn,
+        registry:  u,
+        pool:      pool,
+        backend:   backend,
+        limits:    limits,
+        websocket: websocket,
+        frames:    frameTracker{header: make([]byte, 0, maxFrameHeader)},
+        done:      make(chan struct{}),
+    }
+    c.touch()
+
+    u.mutex.Lock()
+    u.conns[c] = struct{}{}
+    u.mutex.Unlock()
+    if websocket {
+        u.metrics.IncrementWebSockets(pool, backend)
+    }
+
+    go c.watch()
+    return c
+}
+
+// shutdown closes every upgraded connection and waits for them to finish
+// WebSocket clients are sent a going-away close frame and given CloseTimeout
+// to complete the closing handshake; other upgrades are closed at once
+// Time Complexity: O(c) where c is number of upgraded connections
+// Space Complexity: O(c) for the connection snapshot
+func (u *upgradeRegistry) shutdown(ctx context.Context) error {
+    u.mutex.Lock()
+    conns := make([]*upgradedConn, 0, len(u.conns))
+    for c := range u.conns {
+        conns = append(conns, c)
+    }
+    u.mutex.Unlock()
+
+    for _, c := range conns {
+        c.closeGracefully(closeGoingAway)
+    }
+    for _, c := range conns {
+        select {
+        case <-c.done:
+        case <-ctx.Done():
+            for _, c := range conns {
+                c.Close()
+            }
+            return fmt.Errorf("failed to close upgraded connections: %w", ctx.Err())
+        }
+    }
+    return nil
+}
+
+// upgradedConn is a client connection after a protocol switch
+// Both directions pass through it, so it sees activity for the idle timeout.
+// For WebSockets it follows frame boundaries of the backend's stream so a close
+// frame is never inserted in the middle of a frame
+type upgradedConn struct {
+    net.Conn
+    registry   *upgradeRegistry
+    pool       string
+    backend    string
+    limits     config.WebSocketConfig
+    websocket  bool
+    lastActive atomic.Int64  // Unix nanoseconds of the last read or write
+    writeMutex sync.Mutex    // Serialises writes with close frame insertion
+    frames     frameTracker  // Position in the frames written to the client, guarded by writeMutex
+    closeCode  uint16        // Close frame waiting for a frame boundary, guarded by writeMutex
+    closeSent  bool          // A close frame has been written, guarded by writeMutex
+    closeOnce  sync.Once
+    done       chan struct{} // Closed once the connection is closed
+}
+
+// touch records traffic on the connection
+func (c *upgradedConn) touch() {
+    c.lastActive.Store(time.Now().UnixNano())
+}
+
+// Read reads from the client and counts as activity
+func (c *upgradedConn) Read(p []byte) (int, error) {
+    n, err := c.Conn.Read(p)
+    if n > 0 {
+        c.touch()
+    }
+    return n, err
+}
+
+// Write sends backend data to the client and counts as activity
+// While a close frame is pending, the current frame is completed and the close
+// frame follows it; anything after the close frame is dropped
+// Time Complexity: O(n) where n is len(p), for frame tracking
+// Space Complexity: O(1) - no allocations
+func (c *upgradedConn) Write(p []byte) (int, error) {
+    c.touch()
+    if !c.websocket {
+        return c.Conn.Write(p)
+    }
+
+    c.writeMutex.Lock()
+    defer c.writeMutex.Unlock()
+    switch {
+    case c.closeSent:
+        return len(p), nil
+    case c.closeCode != 0:
+        n := c.frames.advance(p)
+        if _, err := c.Conn.Write(p[:n]); err != nil {
+            return 0, err
+        }
+        if c.frames.atBoundary() {
+            if err := c.sendClose(); err != nil {
+                return 0, err
+            }
+        }
+        return len(p), nil
+    }
+    c.frames.consume(p)
+    return c.Conn.Write(p)
+}
+
+// sendClose writes the pending close frame; the caller holds writeMutex
+// Server-to-client frames are unmasked: FIN and opcode 0x8, then the 2 byte status
+func (c *upgradedConn) sendClose() error {
+    c.closeSent = true
+    frame := []byte{0x88, 0x02, 0, 0}
+    binary.BigEndian.PutUint16(frame[2:], c.closeCode)
+    _, err := c.Conn.Write(frame)
+    return err
+}
+
+// closeGracefully starts closing the connection with code
+// WebSocket clients receive a close frame at the next frame boundary and the
+// connection is closed after CloseTimeout unless the handshake ends it first
+func (c *upgradedConn) closeGracefully(code uint16) {
+    if !c.websocket {
+        c.Close()
+        return
+    }
+
+    // Armed first so a client that stopped reading cannot hold up the close
+    time.AfterFunc(c.limits.CloseTimeout, func() { c.Close() })
+
+    c.writeMutex.Lock()
+    defer c.writeMutex.Unlock()
+    if c.closeCode == 0 {
+        c.closeCode = code
+        if c.frames.atBoundary() {
+            c.sendClose()
+        }
+    }
+}
+
+// watch closes the connection once it has been idle for IdleTimeout or open
+// for MaxLifetime, and returns when the connection is closed
+func (c *upgradedConn) watch() {
+    var idle, lifetime <-chan time.Time
+    var idleTimer *time.Timer
+    if c.limits.IdleTimeout > 0 {
+        idleTimer = time.NewTimer(c.limits.IdleTimeout)
+        defer idleTimer.Stop()
+        idle = idleTimer.C
+    }
+    if c.limits.MaxLifetime > 0 {
+        lifetimeTimer := time.NewTimer(c.limits.MaxLifetime)
+        defer lifetimeTimer.Stop()
+        lifetime = lifetimeTimer.C
+    }
+
+    for {
+        select {
+        case <-idle:
+            // Traffic since the timer was armed postpones the deadline
+            if quiet := time.Since(time.Unix(0, c.lastActive.Load())); quiet < c.limits.IdleTimeout {
+                idleTimer.Reset(c.limits.IdleTimeout - quiet)
+                continue
+            }
+        case <-lifetime:
+        case <-c.done:
+            return
+        }
+        c.closeGracefully(closeNormal)
+        return
+    }
+}
+
+// Close closes the connection once and removes it from the registry
+func (c *upgradedConn) Close() error {
+    err := net.ErrClosed
+    c.closeOnce.Do(func() {
+        close(c.done)
+        c.registry.mutex.Lock()
+        delete(c.registry.conns, c)
+        c.registry.mutex.Unlock()
+        if c.websocket {
+            c.registry.metrics.DecrementWebSockets(c.pool, c.backend)
+        }
+        err = c.Conn.Close()
+    })
+    return err
+}
+
+// maxFrameHeader is the longest WebSocket frame header: 2 bytes, 8 bytes of
+// extended payload length and a 4 byte masking key
+const maxFrameHeader = 14
+
+// frameTracker follows WebSocket frame boundaries in a byte stream (RFC 6455 section 5.2)
+type frameTracker struct {
+    header    []byte // Header bytes of the next frame seen so far
+    remaining uint64 // Payload bytes left in the current frame
+}
+
+// atBoundary reports whether the stream is between two frames
+func (t *frameTracker) atBoundary() bool {
+    return t.remaining == 0 && len(t.header) == 0
+}
+
+// advance consumes p up to the end of the current frame and returns the
+// number of bytes consumed, len(p) when the frame continues beyond p
+// Time Complexity: O(h) where h is header bytes consumed
+// Space Complexity: O(1) - the header buffer is reused
+func (t *frameTracker) advance(p []byte) int {
+    n := 0
+    for n < len(p) {
+        if t.remaining > 0 {
+            step := min(t.remaining, uint64(len(p)-n))
+            t.remaining -= step
+            n += int(step)
+            if t.remaining == 0 {
+                return n
+            }
+            continue
+        }
+
+        t.header = append(t.header, p[n])
+        n++
+        if length, complete := parseFrameHeader(t.header); complete {
+            t.header = t.header[:0]
+            t.remaining = length
+            if length == 0 {
+                return n
+            }
+        }
+    }
+    return n
+}
+
+// consume tracks every frame boundary in p
+func (t *frameTracker) consume(p []byte) {
+    for len(p) > 0 {
+        p = p[t.advance(p):]
+    }
+}
+
+// parseFrameHeader returns the payload length once header holds a complete frame header
+func parseFrameHeader(header []byte) (uint64, bool) {
+    if len(header) < 2 {
+        return 0, false
+    }
+    length := uint64(header[1] & 0x7f)
+    size := 2
+    switch length {
+    case 126:
+        size += 2
+    case 127:
+        size += 8
+    }
+    if header[1]&0x80 != 0 {
+        size += 4
+    }
+    if len(header) < size {
+        return 0, false
+    }
+
+    switch length {
+    case 126:
+        length = uint64(binary.BigEndian.Uint16(header[2:4]))
+    case 127:
+        length = binary.BigEndian.Uint64(header[2:10])
+    }
+    return length, true
+}
@@ -0,0 +1,235 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/metrics"
+)
+
+// newUpgradeBackend starts a backend that accepts WebSocket upgrades and echoes
+// every byte it receives afterwards
+func newUpgradeBackend(t *testing.T) *httptest.Server {
+    t.Helper()
+    backend := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+        if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+            http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+            return
+        }
+        conn, rw, err := http.NewResponseController(w).Hijack()
+        if err != nil {
+            return
+        }
+        defer conn.Close()
+        rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n\r\n")
+        rw.Flush()
+        io.Copy(conn, rw)
+    }))
+    t.Cleanup(backend.Close)
+    return backend
+}
+
+// dialUpgrade performs a WebSocket handshake with the proxy at proxyURL
+func dialUpgrade(t *testing.T, proxyURL string) (net.Conn, *bufio.Reader) {
+    t.Helper()
+    conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    req, _ := http.NewRequest("GET", proxyURL+"/socket", nil)
+    req.Header.Set("Connection", "Upgrade")
+    req.Header.Set("Upgrade", "websocket")
+    if err := req.Write(conn); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    reader := bufio.NewReader(conn)
+    resp, err := http.ReadResponse(reader, req)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if resp.StatusCode != http.StatusSwitchingProtocols {
+        t.Fatalf("Expected 101 Switching Protocols, got %d", resp.StatusCode)
+    }
+    return conn, reader
+}
+
+// textFrame returns an unmasked WebSocket text frame carrying payload
+func textFrame(payload string) []byte {
+    return append([]byte{0x81, byte(len(payload))}, payload...)
+}
+
+// closeFrame returns the close frame the proxy sends with code
+func closeFrame(code uint16) []byte {
+    return []byte{0x88, 0x02, byte(code >> 8), byte(code)}
+}
+
+// newUpgradeTestServer serves the proxy for backendURL over HTTP with the
+// cache and rate limiter in the chain
+func newUpgradeTestServer(t *testing.T, limits config.WebSocketConfig, backendURL string) (*Server, *httptest.Server) {
+    t.Helper()
+    cfg := newTestConfig(backendURL)
+    cfg.Cache.Enabled = true
+    cfg.RateLimit.Enabled = true
+    cfg.WebSocket = limits
+    server, err := NewServer(cfg)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    frontend := httptest.NewServer(server)
+    t.Cleanup(frontend.Close)
+    return server, frontend
+}
+
+// TestUpgradeThroughMiddleware verifies a WebSocket passes every middleware
+// and is tracked until it closes
+func TestUpgradeThroughMiddleware(t *testing.T) {
+    server, frontend := newUpgradeTestServer(t, config.DefaultConfig().WebSocket, newUpgradeBackend(t).URL)
+    conn, reader := dialUpgrade(t, frontend.URL)
+
+    frame := textFrame("hello")
+    conn.Write(frame)
+    echoed := make([]byte, len(frame))
+    if _, err := io.ReadFull(reader, echoed); err != nil || !bytes.Equal(echoed, frame) {
+        t.Fatalf("Expected frame echoed through the proxy, got %q (err %v)", echoed, err)
+    }
+
+    upgrades := server.current.Load().upgrades
+    upgrades.mutex.Lock()
+    open := len(upgrades.conns)
+    upgrades.mutex.Unlock()
+    if open != 1 {
+        t.Errorf("Expected one tracked connection, got %d", open)
+    }
+
+    conn.Close()
+    deadline := time.Now().Add(2 * time.Second)
+    for time.Now().Before(deadline) {
+        upgrades.mutex.Lock()
+        open = len(upgrades.conns)
+        upgrades.mutex.Unlock()
+        if open == 0 {
+            return
+        }
+        time.Sleep(10 * time.Millisecond)
+    }
+    t.Errorf("Expected the closed connection to be released, %d still tracked", open)
+}
+
+// TestUpgradeIdleTimeout verifies an idle WebSocket receives a close frame and is closed
+func TestUpgradeIdleTimeout(t *testing.T) {
+    limits := config.WebSocketConfig{IdleTimeout: 100 * time.Millisecond, CloseTimeout: 100 * time.Millisecond}
+    _, frontend := newUpgradeTestServer(t, limits, newUpgradeBackend(t).URL)
+    conn, reader := dialUpgrade(t, frontend.URL)
+
+    conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+    received, err := io.ReadAll(reader)
+    if err != nil {
+        t.Fatalf("Expected the proxy to close the connection, got %v", err)
+    }
+    if !bytes.Equal(received, closeFrame(closeNormal)) {
+        t.Errorf("Expected a normal closure frame, got %x", received)
+    }
+}
+
+// TestUpgradeShutdown verifies shutdown sends going-away close frames and
+// waits for upgraded connections
+func TestUpgradeShutdown(t *testing.T) {
+    limits := config.WebSocketConfig{CloseTimeout: 100 * time.Millisecond}
+    server, frontend := newUpgradeTestServer(t, limits, newUpgradeBackend(t).URL)
+    conn, reader := dialUpgrade(t, frontend.URL)
+
+    ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+    defer cancel()
+    if err := server.Shutdown(ctx); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    conn.SetReadDeadline(time.Now().Add(time.Second))
+    received, _ := io.ReadAll(reader)
+    if !bytes.Equal(received, closeFrame(closeGoingAway)) {
+        t.Errorf("Expected a going-away close frame, got %x", received)
+    }
+}
+
+// TestShutdownStopsHealthChecksOnError verifies health checks stop even when
+// upgraded connections outlive the shutdown context
+func TestShutdownStopsHealthChecksOnError(t *testing.T) {
+    limits := config.WebSocketConfig{CloseTimeout: time.Second}
+    server, frontend := newUpgradeTestServer(t, limits, newUpgradeBackend(t).URL)
+    dialUpgrade(t, frontend.URL)
+
+    ctx, cancel := context.WithCancel(context.Background())
+    cancel()
+    if err := server.Shutdown(ctx); err == nil {
+        t.Fatal("Expected shutdown to report the connection still open")
+    }
+
+    health := server.current.Load().health
+    health.mutex.Lock()
+    closed := health.closed
+    health.mutex.Unlock()
+    if !closed {
+        t.Error("Expected health checks to be stopped after a failed shutdown")
+    }
+}
+
+// TestCloseFrameAtBoundary verifies a close frame waits for the frame being
+// written to finish and nothing follows it
+func TestCloseFrameAtBoundary(t *testing.T) {
+    client, proxy := net.Pipe()
+    registry := newUpgradeRegistry(metrics.NewMetrics())
+    conn := registry.track(proxy, config.DefaultPoolName, "http://backend", config.WebSocketConfig{CloseTimeout: time.Second}, true)
+
+    received := make(chan []byte)
+    go func() {
+        data, _ := io.ReadAll(client)
+        received <- data
+    }()
+
+    first := textFrame("first")
+    conn.Write(first[:3])
+    conn.closeGracefully(closeGoingAway)
+    conn.Write(append(first[3:], textFrame("second")...))
+    conn.Close()
+
+    expected := append(append([]byte{}, first...), closeFrame(closeGoingAway)...)
+    if data := <-received; !bytes.Equal(data, expected) {
+        t.Errorf("Expected %x, got %x", expected, data)
+    }
+}
+
+// TestFrameTracker verifies boundaries across split headers and extended lengths
+func TestFrameTracker(t *testing.T) {
+    long := append([]byte{0x82, 126, 0x01, 0x00}, make([]byte, 256)...)
+    masked := []byte{0x81, 0x82, 1, 2, 3, 4, 'h', 'i'}
+    stream := append(append(append([]byte{}, long...), masked...), 0x89, 0x00)
+
+    for _, chunk := range []int{1, 3, 7, len(stream)} {
+        tracker := frameTracker{}
+        boundaries := 0
+        for offset := 0; offset < len(stream); {
+            end := min(offset+chunk, len(stream))
+            for part := stream[offset:end]; len(part) > 0; {
+                part = part[tracker.advance(part):]
+                if tracker.atBoundary() {
+                    boundaries++
+                }
+            }
+            offset = end
+        }
+        if boundaries != 3 || !tracker.atBoundary() {
+            t.Errorf("Chunks of %d: expected 3 frame boundaries, got %d", chunk, boundaries)
+        }
+    }
+}
@@ -0,0 +1,60 @@
+package response
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+)
+
+// IsGRPC reports whether r is a gRPC call
+// gRPC requests carry application/grpc, optionally with a codec suffix such as +proto
+// Time Complexity: O(1) - header lookup and prefix checks
+// Space Complexity: O(1) - no allocations
+func IsGRPC(r *http.Request) bool {
+    contentType := r.Header.Get("Content-Type")
+    if !strings.HasPrefix(contentType, "application/grpc") {
+        return false
+    }
+    rest := contentType[len("application/grpc"):]
+    return rest == "" || rest[0] == '+' || rest[0] == ';'
+}
+
+// GRPCStatus returns the gRPC status code of the response, e.g. "0" for OK
+// The code is taken from the grpc-status trailer, or from the header of a
+// trailers-only response. Responses without one, such as errors the proxy
+// answered itself, are mapped from the HTTP status the way gRPC clients do
+// Call it once the handler has returned so trailers have been set
+// Time Complexity: O(1) - header lookups
+// Space Complexity: O(1) - no allocations
+func (w *Writer) GRPCStatus() string {
+    header := w.Header()
+    if status := header.Get("Grpc-Status"); status != "" {
+        return status
+    }
+    // Trailers the backend did not announce are set with TrailerPrefix
+    if status := header.Get(http.TrailerPrefix + "Grpc-Status"); status != "" {
+        return status
+    }
+    return strconv.Itoa(int(grpcCodeForHTTP(w.Status())))
+}
+
+// grpcCodeForHTTP maps an HTTP status without grpc-status to a gRPC code
+// following the gRPC HTTP to gRPC status code mapping
+func grpcCodeForHTTP(status int) codes.Code {
+    switch status {
+    case http.StatusBadRequest:
+        return codes.Internal
+    case http.StatusUnauthorized:
+        return codes.Unauthenticated
+    case http.StatusForbidden:
+        return codes.PermissionDenied
+    case http.StatusNotFound:
+        return codes.Unimplemented
+    case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+        return codes.Unavailable
+    default:
+        return codes.Unknown
+    }
+}
@@ -0,0 +1,57 @@
+package response
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestIsGRPC verifies gRPC calls are recognised by content type
+func TestIsGRPC(t *testing.T) {
+    cases := map[string]bool{
+        "application/grpc":               true,
+        "application/grpc+proto":         true,
+        "application/grpc;charset=utf-8": true,
+        "application/grpc-web":           false,
+        "application/json":               false,
+        "":                               false,
+    }
+    for contentType, expected := range cases {
+        req := httptest.NewRequest("POST", "/pkg.Service/Method", nil)
+        req.Header.Set("Content-Type", contentType)
+        if IsGRPC(req) != expected {
+            t.Errorf("%q: expected %v", contentType, expected)
+        }
+    }
+}
+
+// TestGRPCStatus verifies the code is read from trailers or headers and
+// mapped from the HTTP status when the backend sent none
+func TestGRPCStatus(t *testing.T) {
+    announced := NewWriter(httptest.NewRecorder())
+    announced.Header().Set("Trailer", "Grpc-Status")
+    announced.Write([]byte("message"))
+    announced.Header().Set("Grpc-Status", "5")
+
+    unannounced := NewWriter(httptest.NewRecorder())
+    unannounced.Write([]byte("message"))
+    unannounced.Header().Set(http.TrailerPrefix+"Grpc-Status", "0")
+
+    unavailable := NewWriter(httptest.NewRecorder())
+    unavailable.WriteHeader(http.StatusBadGateway)
+
+    cases := []struct {
+        name     string
+        writer   *Writer
+        expected string
+    }{
+        {"announced trailer", announced, "5"},
+        {"unannounced trailer", unannounced, "0"},
+        {"proxy error", unavailable, "14"},
+    }
+    for _, c := range cases {
+        if status := c.writer.GRPCStatus(); status != c.expected {
+            t.Errorf("%s: expected grpc-status %s, got %s", c.name, c.expected, status)
+        }
+    }
+}
@@ -0,0 +1,145 @@
+// Package response provides the response writer wrapper shared by middleware
+package response
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+)
+
+// Writer wraps an http.ResponseWriter to record the status code and body size
+// Every optional interface of the wrapped writer stays reachable: Flush and
+// Hijack go through http.ResponseController, Push and ReadFrom are forwarded
+// when supported, and Unwrap lets http.ResponseController reach the rest.
+// Trailers set through Header after the body pass straight to the wrapped
+// writer, so streaming responses, server-sent events and upgrades behave as
+// if the wrapper were not there
+// Time Complexity: O(1) per call plus the wrapped writer's cost
+// Space Complexity: O(1) - nothing is buffered unless Body is set
+type Writer struct {
+    http.ResponseWriter
+
+    // OnHeader, when set, is called once just before the status line is sent,
+    // while the header map can still be inspected or changed
+    OnHeader func(status int)
+    // Body, when set, receives a copy of everything written to the response body
+    Body io.Writer
+
+    status      int
+    written     int64
+    wroteHeader bool
+    hijacked    bool
+}
+
+// NewWriter wraps w
+func NewWriter(w http.ResponseWriter) *Writer {
+    return &Writer{ResponseWriter: w}
+}
+
+// Status returns the status sent to the client: 200 when the handler wrote
+// nothing or only a body, 101 after the connection was hijacked for an upgrade
+func (w *Writer) Status() int {
+    if w.status == 0 {
+        return http.StatusOK
+    }
+    return w.status
+}
+
+// BytesWritten returns the number of body bytes written to the client
+func (w *Writer) BytesWritten() int64 {
+    return w.written
+}
+
+// Hijacked reports whether the connection was taken over by the handler
+func (w *Writer) Hijacked() bool {
+    return w.hijacked
+}
+
+// WriteHeader records the final status and sends it
+// Informational 1xx statuses are passed on without ending the header phase
+func (w *Writer) WriteHeader(code int) {
+    if w.wroteHeader {
+        return
+    }
+    if code >= 200 || code == http.StatusSwitchingProtocols {
+        w.wroteHeader = true
+        w.status = code
+        if w.OnHeader != nil {
+            w.OnHeader(code)
+        }
+    }
+    w.ResponseWriter.WriteHeader(code)
+}
+
+// Write sends p to the client, implicitly with status 200 like net/http
+func (w *Writer) Write(p []byte) (int, error) {
+    if !w.wroteHeader {
+        w.WriteHeader(http.StatusOK)
+    }
+    n, err := w.ResponseWriter.Write(p)
+    w.written += int64(n)
+    if w.Body != nil && n > 0 {
+        w.Body.Write(p[:n])
+    }
+    return n, err
+}
+
+// ReadFrom copies r into the response, letting the wrapped writer use
+// sendfile or splice when it can and no copy of the body is wanted
+// Time Complexity: O(n) where n is bytes copied
+// Space Complexity: O(1) - copies through a fixed buffer
+func (w *Writer) ReadFrom(r io.Reader) (int64, error) {
+    if !w.wroteHeader {
+        w.WriteHeader(http.StatusOK)
+    }
+    if from, ok := w.ResponseWriter.(io.ReaderFrom); ok && w.Body == nil {
+        n, err := from.ReadFrom(r)
+        w.written += n
+        return n, err
+    }
+    // Hide ReadFrom so io.Copy does not call back into this method
+    return io.Copy(struct{ io.Writer }{w}, r)
+}
+
+// FlushError sends buffered data to the client, as net/http does committing
+// the header with status 200 if nothing was written yet
+func (w *Writer) FlushError() error {
+    if !w.wroteHeader {
+        w.WriteHeader(http.StatusOK)
+    }
+    return http.NewResponseController(w.ResponseWriter).Flush()
+}
+
+// Flush implements http.Flusher for callers that type-assert it
+func (w *Writer) Flush() {
+    w.FlushError()
+}
+
+// Hijack hands the connection over to the handler
+// A successful hijack is recorded as 101 Switching Protocols, since the
+// handshake response of an upgrade is written to the connection directly
+func (w *Writer) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+    conn, rw, err := http.NewResponseController(w.ResponseWriter).Hijack()
+    if err == nil {
+        w.hijacked = true
+        if !w.wroteHeader {
+            w.wroteHeader = true
+            w.status = http.StatusSwitchingProtocols
+        }
+    }
+    return conn, rw, err
+}
+
+// Push initiates an HTTP/2 server push when the wrapped writer supports it
+func (w *Writer) Push(target string, opts *http.PushOptions) error {
+    if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+        return pusher.Push(target, opts)
+    }
+    return http.ErrNotSupported
+}
+
+// Unwrap exposes the wrapped writer to http.ResponseController
+func (w *Writer) Unwrap() http.ResponseWriter {
+    return w.ResponseWriter
+}
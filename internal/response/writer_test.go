@@ -0,0 +1,93 @@
+package response
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestWriterRecordsStatusAndBytes verifies status and body size across Write and ReadFrom
+func TestWriterRecordsStatusAndBytes(t *testing.T) {
+    w := NewWriter(httptest.NewRecorder())
+    if w.Status() != http.StatusOK {
+        t.Errorf("Expected implicit status 200, got %d", w.Status())
+    }
+
+    w.WriteHeader(http.StatusCreated)
+    w.WriteHeader(http.StatusInternalServerError)
+    w.Write([]byte("hello "))
+    io.Copy(w, strings.NewReader("world"))
+
+    if w.Status() != http.StatusCreated {
+        t.Errorf("Expected first final status 201, got %d", w.Status())
+    }
+    if w.BytesWritten() != 11 {
+        t.Errorf("Expected 11 bytes written, got %d", w.BytesWritten())
+    }
+}
+
+// TestWriterPreservesInterfaces verifies flushing, pushing and body capture
+// work through nested wrappers
+func TestWriterPreservesInterfaces(t *testing.T) {
+    recorder := httptest.NewRecorder()
+    var captured strings.Builder
+    inner := NewWriter(recorder)
+    inner.Body = &captured
+    headers := 0
+    inner.OnHeader = func(status int) { headers++ }
+    outer := NewWriter(inner)
+
+    outer.Write([]byte("data: tick\n\n"))
+    if err := http.NewResponseController(outer).Flush(); err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    if !recorder.Flushed {
+        t.Error("Expected flush to reach the underlying writer")
+    }
+    if captured.String() != "data: tick\n\n" || headers != 1 {
+        t.Errorf("Expected body captured and one header callback, got %q and %d", captured.String(), headers)
+    }
+    if err := outer.Push("/style.css", nil); !errors.Is(err, http.ErrNotSupported) {
+        t.Errorf("Expected ErrNotSupported from a writer without push, got %v", err)
+    }
+}
+
+// TestWriterHijackAndTrailers verifies upgrades and trailers through a real server
+func TestWriterHijackAndTrailers(t *testing.T) {
+    hijacked := make(chan *Writer, 4)
+    server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+        w := NewWriter(NewWriter(rw))
+        if r.URL.Path == "/upgrade" {
+            conn, _, err := http.NewResponseController(w).Hijack()
+            if err != nil {
+                t.Errorf("unexpected hijack error: %v", err)
+                return
+            }
+            conn.Close()
+            hijacked <- w
+            return
+        }
+        w.Header().Set("Trailer", "Grpc-Status")
+        w.Write([]byte("body"))
+        w.Header().Set("Grpc-Status", "0")
+    }))
+    defer server.Close()
+
+    resp, err := http.Get(server.URL + "/trailers")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    io.ReadAll(resp.Body)
+    resp.Body.Close()
+    if resp.Trailer.Get("Grpc-Status") != "0" {
+        t.Errorf("Expected trailer forwarded, got %v", resp.Trailer)
+    }
+
+    http.Get(server.URL + "/upgrade")
+    if w := <-hijacked; !w.Hijacked() || w.Status() != http.StatusSwitchingProtocols {
+        t.Error("Expected hijack to be recorded as 101 Switching Protocols")
+    }
+}
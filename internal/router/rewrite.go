@@ -0,0 +1,125 @@
+package router
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// routeKey is the context key under which the matched route is stored
+type routeKey struct{}
+
+// WithRoute returns a context carrying the route matched for a request
+// The reverse proxy Director reads it back to apply the route's rewrites
+// Time Complexity: O(1) - context wrapping
+// Space Complexity: O(1) - single context node
+func WithRoute(ctx context.Context, route *Route) context.Context {
+    return context.WithValue(ctx, routeKey{}, route)
+}
+
+// FromContext returns the route stored by WithRoute, or nil
+// Time Complexity: O(d) where d is context depth
+// Space Complexity: O(1) - no allocations
+func FromContext(ctx context.Context) *Route {
+    route, _ := ctx.Value(routeKey{}).(*Route)
+    return route
+}
+
+// Redirect returns the redirect location and status for req
+// ok is false when the route proxies the request, including HTTPS redirect
+// routes receiving a request that already arrived over TLS
+// Time Complexity: O(n) where n is length of the request URL
+// Space Complexity: O(n) for the location string
+func (r *Route) Redirect(req *http.Request) (location string, status int, ok bool) {
+    if !r.redirect.Enabled() {
+        return "", 0, false
+    }
+
+    status = r.redirect.Status
+    if status == 0 {
+        status = http.StatusFound
+    }
+
+    if r.redirect.HTTPS {
+        if req.TLS != nil {
+            return "", 0, false
+        }
+        host := req.Host
+        if h, _, err := net.SplitHostPort(host); err == nil {
+            host = h
+        }
+        if port := r.redirect.HTTPSPort; port != 0 && port != 443 {
+            host = net.JoinHostPort(host, strconv.Itoa(port))
+        }
+        return "https://" + host + req.URL.RequestURI(), status, true
+    }
+
+    location = r.expand(r.redirect.URL, req.URL.Path)
+    if req.URL.RawQuery != "" && !strings.Contains(location, "?") {
+        location += "?" + req.URL.RawQuery
+    }
+    return location, status, true
+}
+
+// Rewrite applies the route's path, Host and query rewrites to an outgoing request
+// Must run before the reverse proxy joins the path with the backend URL
+// Capture groups are taken from pathRegex matched against the original path
+// Time Complexity: O(n + q) where n is path length and q is number of query parameters
+// Space Complexity: O(n + q) for the rewritten path and query
+func (r *Route) Rewrite(req *http.Request) {
+    rewrite := &r.rewrite
+    if rewrite.IsZero() {
+        return
+    }
+
+    original := req.URL.Path
+    path := original
+    switch {
+    case rewrite.Path != "":
+        path = r.expand(rewrite.Path, original)
+    case rewrite.StripPrefix != "" || rewrite.AddPrefix != "":
+        path = strings.TrimPrefix(path, rewrite.StripPrefix)
+        if !strings.HasPrefix(path, "/") {
+            path = "/" + path
+        }
+        if rewrite.AddPrefix != "" {
+            path = strings.TrimSuffix(rewrite.AddPrefix, "/") + path
+        }
+    }
+    if path != original {
+        req.URL.Path = path
+        req.URL.RawPath = ""
+    }
+
+    if rewrite.Host != "" {
+        req.Host = r.expand(rewrite.Host, original)
+    }
+
+    if len(rewrite.AddQuery) > 0 || len(rewrite.RemoveQuery) > 0 {
+        query := req.URL.Query()
+        for _, name := range rewrite.RemoveQuery {
+            query.Del(name)
+        }
+        for name, value := range rewrite.AddQuery {
+            query.Set(name, r.expand(value, original))
+        }
+        req.URL.RawQuery = query.Encode()
+    }
+}
+
+// expand substitutes pathRegex capture groups ($1, ${name}) into template
+// Templates are returned unchanged when the route has no path regex
+// Time Complexity: O(n + t) where n is path length and t is template length
+// Space Complexity: O(t) for the expanded result
+func (r *Route) expand(template, path string) string {
+    if r.pathRegex == nil || !strings.Contains(template, "$") {
+        return template
+    }
+    match := r.pathRegex.FindStringSubmatchIndex(path)
+    if match == nil {
+        return template
+    }
+    return string(r.pathRegex.ExpandString(nil, template, path, match))
+}
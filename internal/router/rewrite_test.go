@@ -0,0 +1,93 @@
+package router
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+)
+
+// compileRoute compiles a single route against a pool named "api"
+func compileRoute(t *testing.T, cfg config.RouteConfig) *Route {
+    t.Helper()
+    route, err := compile(cfg, testPools(t, "api"))
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    return route
+}
+
+// TestRewritePrefix verifies prefixes are stripped and replaced
+func TestRewritePrefix(t *testing.T) {
+    cases := []struct {
+        rewrite  config.RewriteConfig
+        target   string
+        expected string
+    }{
+        {config.RewriteConfig{StripPrefix: "/api"}, "/api/users", "/users"},
+        {config.RewriteConfig{StripPrefix: "/api"}, "/api", "/"},
+        {config.RewriteConfig{StripPrefix: "/api/v1", AddPrefix: "/v2/"}, "/api/v1/users", "/v2/users"},
+        {config.RewriteConfig{AddPrefix: "/internal"}, "/users", "/internal/users"},
+    }
+    for _, c := range cases {
+        route := compileRoute(t, config.RouteConfig{Pool: "api", Rewrite: c.rewrite})
+        req := httptest.NewRequest("GET", c.target, nil)
+        route.Rewrite(req)
+        if req.URL.Path != c.expected {
+            t.Errorf("%+v on %s: Expected %s, got %s", c.rewrite, c.target, c.expected, req.URL.Path)
+        }
+    }
+}
+
+// TestRewriteCaptureGroups verifies path, Host and query templates expand capture groups
+func TestRewriteCaptureGroups(t *testing.T) {
+    route := compileRoute(t, config.RouteConfig{
+        Pool:      "api",
+        PathRegex: `^/users/(?P<id>[0-9]+)/(.*)$`,
+        Rewrite: config.RewriteConfig{
+            Path:        "/v2/accounts/${id}/$2",
+            Host:        "accounts.internal",
+            AddQuery:    map[string]string{"user": "$1"},
+            RemoveQuery: []string{"debug"},
+        },
+    })
+
+    req := httptest.NewRequest("GET", "http://example.com/users/42/profile?debug=1&lang=en", nil)
+    route.Rewrite(req)
+
+    if req.URL.Path != "/v2/accounts/42/profile" {
+        t.Errorf("Expected rewritten path /v2/accounts/42/profile, got %s", req.URL.Path)
+    }
+    if req.Host != "accounts.internal" {
+        t.Errorf("Expected Host accounts.internal, got %s", req.Host)
+    }
+    if req.URL.RawQuery != "lang=en&user=42" {
+        t.Errorf("Expected query lang=en&user=42, got %s", req.URL.RawQuery)
+    }
+}
+
+// TestRedirect verifies URL templates, status defaults and HTTPS upgrades
+func TestRedirect(t *testing.T) {
+    moved := compileRoute(t, config.RouteConfig{
+        PathRegex: `^/old/(.*)$`,
+        Redirect:  config.RedirectConfig{URL: "https://new.example.com/$1", Status: http.StatusMovedPermanently},
+    })
+    location, status, ok := moved.Redirect(httptest.NewRequest("GET", "/old/page?x=1", nil))
+    if !ok || status != http.StatusMovedPermanently || location != "https://new.example.com/page?x=1" {
+        t.Errorf("Expected 301 to https://new.example.com/page?x=1, got %v %d %s", ok, status, location)
+    }
+
+    upgrade := compileRoute(t, config.RouteConfig{Pool: "api", Redirect: config.RedirectConfig{HTTPS: true, HTTPSPort: 8443}})
+    location, status, ok = upgrade.Redirect(httptest.NewRequest("GET", "http://example.com:8080/a?b=c", nil))
+    if !ok || status != http.StatusFound || location != "https://example.com:8443/a?b=c" {
+        t.Errorf("Expected 302 to https://example.com:8443/a?b=c, got %v %d %s", ok, status, location)
+    }
+
+    secure := httptest.NewRequest("GET", "https://example.com/a", nil)
+    secure.TLS = &tls.ConnectionState{}
+    if _, _, ok := upgrade.Redirect(secure); ok {
+        t.Error("Expected HTTPS request to be proxied rather than redirected")
+    }
+}
@@ -0,0 +1,246 @@
+package router
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+	"github.com/WillKirkmanM/proxy/internal/response"
+)
+
+// Route is a compiled routing rule bound to the pool that serves it
+// Match conditions are evaluated in order of increasing cost: method, host,
+// gRPC service and method, path prefix, path regex and finally headers
+type Route struct {
+    Name       string                    // Route name used in logs and metrics
+    Pool       string                    // Name of the pool serving matched requests
+    Balancer   loadbalancer.LoadBalancer // Load balancer of the pool, nil for redirect-only routes
+    priority   int
+    methods    map[string]bool // Uppercase methods, nil matches any method
+    host       string          // Lowercase exact host, or suffix ".example.com" for wildcards
+    wildcard   bool
+    grpcPath   string // "/package.Service/Method", or "/package.Service/" matching every method
+    pathPrefix string
+    pathRegex  *regexp.Regexp
+    headers    []headerMatcher
+    rewrite    config.RewriteConfig
+    redirect   config.RedirectConfig
+}
+
+// headerMatcher tests a single request header
+type headerMatcher struct {
+    name  string
+    value string
+    regex *regexp.Regexp
+}
+
+// Router dispatches requests to named pools using an ordered route table
+// Routes are immutable after construction so Match needs no locking
+type Router struct {
+    routes   []*Route
+    fallback *Route // Serves unmatched requests, nil responds 404
+}
+
+// New compiles routes against pools into a router
+// Routes are sorted by descending priority; equal priorities keep configuration order
+// so evaluation is deterministic across reloads
+// defaultPool names the pool for unmatched requests, empty means respond 404
+// Time Complexity: O(r log r) where r is number of routes
+// Space Complexity: O(r) for compiled routes
+func New(routes []config.RouteConfig, pools map[string]loadbalancer.LoadBalancer, defaultPool string) (*Router, error) {
+    router := &Router{routes: make([]*Route, 0, len(routes))}
+
+    for i, cfg := range routes {
+        route, err := compile(cfg, pools)
+        if err != nil {
+            return nil, fmt.Errorf("failed to compile route %d: %w", i, err)
+        }
+        if route.Name == "" {
+            route.Name = fmt.Sprintf("route-%d", i)
+        }
+        router.routes = append(router.routes, route)
+    }
+
+    sort.SliceStable(router.routes, func(i, j int) bool {
+        return router.routes[i].priority > router.routes[j].priority
+    })
+
+    if defaultPool != "" {
+        balancer, ok := pools[defaultPool]
+        if !ok {
+            return nil, fmt.Errorf("unknown default pool %q", defaultPool)
+        }
+        router.fallback = &Route{Name: "default", Pool: defaultPool, Balancer: balancer}
+    }
+
+    return router, nil
+}
+
+// compile converts a route configuration into its matcher form
+// Time Complexity: O(h) where h is number of header conditions plus regex compilation
+// Space Complexity: O(h) for header matchers
+func compile(cfg config.RouteConfig, pools map[string]loadbalancer.LoadBalancer) (*Route, error) {
+    // Redirect routes answer requests themselves and may leave the pool empty
+    balancer, ok := pools[cfg.Pool]
+    if !ok && (cfg.Pool != "" || !cfg.Redirect.Enabled()) {
+        return nil, fmt.Errorf("unknown pool %q", cfg.Pool)
+    }
+
+    route := &Route{
+        Name:       cfg.Name,
+        Pool:       cfg.Pool,
+        Balancer:   balancer,
+        priority:   cfg.Priority,
+        pathPrefix: cfg.PathPrefix,
+        rewrite:    cfg.Rewrite,
+        redirect:   cfg.Redirect,
+    }
+
+    if len(cfg.Methods) > 0 {
+        route.methods = make(map[string]bool, len(cfg.Methods))
+        for _, method := range cfg.Methods {
+            route.methods[strings.ToUpper(method)] = true
+        }
+    }
+
+    route.host = strings.ToLower(cfg.Host)
+    if strings.HasPrefix(route.host, "*.") {
+        route.host = route.host[1:]
+        route.wildcard = true
+    }
+
+    if cfg.GRPC.Enabled() {
+        route.grpcPath = "/" + cfg.GRPC.Service + "/" + cfg.GRPC.Method
+    }
+
+    if cfg.PathRegex != "" {
+        regex, err := regexp.Compile(cfg.PathRegex)
+        if err != nil {
+            return nil, fmt.Errorf("invalid path regex: %w", err)
+        }
+        route.pathRegex = regex
+    }
+
+    for _, header := range cfg.Headers {
+        matcher := headerMatcher{name: header.Name, value: header.Value}
+        if header.Regex != "" {
+            regex, err := regexp.Compile(header.Regex)
+            if err != nil {
+                return nil, fmt.Errorf("invalid regex for header %s: %w", header.Name, err)
+            }
+            matcher.regex = regex
+        }
+        route.headers = append(route.headers, matcher)
+    }
+
+    return route, nil
+}
+
+// Match returns the first route matching req, or the default route
+// Returns nil when nothing matches and no default pool is configured
+// Time Complexity: O(r) where r is number of routes
+// Space Complexity: O(1) - no allocations beyond host normalisation
+func (rt *Router) Match(req *http.Request) *Route {
+    host := requestHost(req)
+    for _, route := range rt.routes {
+        if route.matches(req, host) {
+            return route
+        }
+    }
+    return rt.fallback
+}
+
+// Routes returns the compiled routes in evaluation order
+// Time Complexity: O(1) - returns internal slice
+// Space Complexity: O(1) - no allocations
+func (rt *Router) Routes() []*Route {
+    return rt.routes
+}
+
+// matches reports whether every configured condition holds for req
+// Time Complexity: O(h) where h is number of header conditions
+// Space Complexity: O(1) - no allocations
+func (r *Route) matches(req *http.Request, host string) bool {
+    if r.methods != nil && !r.methods[req.Method] {
+        return false
+    }
+    if r.host != "" {
+        if r.wildcard {
+            if !strings.HasSuffix(host, r.host) {
+                return false
+            }
+        } else if host != r.host {
+            return false
+        }
+    }
+    if r.grpcPath != "" && !r.matchesGRPC(req) {
+        return false
+    }
+    if r.pathPrefix != "" && !strings.HasPrefix(req.URL.Path, r.pathPrefix) {
+        return false
+    }
+    if r.pathRegex != nil && !r.pathRegex.MatchString(req.URL.Path) {
+        return false
+    }
+    for _, header := range r.headers {
+        if !header.matches(req.Header) {
+            return false
+        }
+    }
+    return true
+}
+
+// matchesGRPC reports whether req is a gRPC call to the route's service and method
+// Time Complexity: O(n) where n is path length
+// Space Complexity: O(1) - no allocations
+func (r *Route) matchesGRPC(req *http.Request) bool {
+    if !response.IsGRPC(req) {
+        return false
+    }
+    if strings.HasSuffix(r.grpcPath, "/") {
+        return strings.HasPrefix(req.URL.Path, r.grpcPath) && !strings.Contains(req.URL.Path[len(r.grpcPath):], "/")
+    }
+    return req.URL.Path == r.grpcPath
+}
+
+// matches tests the header against the exact value, regex or mere presence
+// Any of multiple header values may satisfy the condition
+// Time Complexity: O(v) where v is number of values for the header
+// Space Complexity: O(1) - no allocations
+func (h headerMatcher) matches(header http.Header) bool {
+    values := header.Values(h.name)
+    if len(values) == 0 {
+        return false
+    }
+    for _, value := range values {
+        switch {
+        case h.regex != nil:
+            if h.regex.MatchString(value) {
+                return true
+            }
+        case h.value != "":
+            if value == h.value {
+                return true
+            }
+        default:
+            return true
+        }
+    }
+    return false
+}
+
+// requestHost returns the lowercase request host without port or trailing dot
+// Time Complexity: O(n) where n is host length
+// Space Complexity: O(n) for the lowercased copy
+func requestHost(req *http.Request) string {
+    host := req.Host
+    if h, _, err := net.SplitHostPort(host); err == nil {
+        host = h
+    }
+    return strings.TrimSuffix(strings.ToLower(host), ".")
+}
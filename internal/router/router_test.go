@@ -0,0 +1,145 @@
+package router
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/WillKirkmanM/proxy/internal/config"
+	"github.com/WillKirkmanM/proxy/internal/loadbalancer"
+)
+
+// testPools returns one single-backend pool per name
+func testPools(t *testing.T, names ...string) map[string]loadbalancer.LoadBalancer {
+    t.Helper()
+    pools := make(map[string]loadbalancer.LoadBalancer, len(names))
+    for _, name := range names {
+        lb, err := loadbalancer.NewLoadBalancer("round-robin", []config.BackendConfig{{URL: "http://" + name + ".internal", Weight: 1}})
+        if err != nil {
+            t.Fatalf("failed to create pool %s: %v", name, err)
+        }
+        pools[name] = lb
+    }
+    return pools
+}
+
+// matchPool returns the pool chosen for a request, or "" when nothing matches
+func matchPool(rt *Router, method, target string, headers map[string]string) string {
+    req := httptest.NewRequest(method, target, nil)
+    for name, value := range headers {
+        req.Header.Set(name, value)
+    }
+    if route := rt.Match(req); route != nil {
+        return route.Pool
+    }
+    return ""
+}
+
+// TestRouterMatchConditions verifies host, path, method and header conditions
+func TestRouterMatchConditions(t *testing.T) {
+    routes := []config.RouteConfig{
+        {Name: "admin", Host: "admin.example.com", Pool: "admin"},
+        {Name: "tenants", Host: "*.tenants.example.com", Pool: "tenants"},
+        {Name: "api-write", PathPrefix: "/api/", Methods: []string{"post", "PUT"}, Pool: "writes"},
+        {Name: "api", PathPrefix: "/api/", Pool: "api"},
+        {Name: "images", PathRegex: `\.(png|jpe?g)$`, Pool: "static"},
+        {Name: "beta", Headers: []config.HeaderMatchConfig{{Name: "X-Beta", Regex: "^(1|true)$"}}, Pool: "beta"},
+    }
+    rt, err := New(routes, testPools(t, "admin", "tenants", "writes", "api", "static", "beta", "default"), "default")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    cases := []struct {
+        method, target string
+        headers        map[string]string
+        expected       string
+    }{
+        {"GET", "http://ADMIN.example.com:8080/api/users", nil, "admin"},
+        {"GET", "http://a.tenants.example.com/", nil, "tenants"},
+        {"GET", "http://tenants.example.com/", nil, "default"},
+        {"POST", "http://example.com/api/users", nil, "writes"},
+        {"GET", "http://example.com/api/users", nil, "api"},
+        {"GET", "http://example.com/img/logo.png", nil, "static"},
+        {"GET", "http://example.com/", map[string]string{"X-Beta": "true"}, "beta"},
+        {"GET", "http://example.com/", map[string]string{"X-Beta": "no"}, "default"},
+    }
+    for _, c := range cases {
+        if pool := matchPool(rt, c.method, c.target, c.headers); pool != c.expected {
+            t.Errorf("%s %s: Expected pool %q, got %q", c.method, c.target, c.expected, pool)
+        }
+    }
+}
+
+// TestRouterGRPC verifies gRPC calls route by service and method
+func TestRouterGRPC(t *testing.T) {
+    routes := []config.RouteConfig{
+        {Name: "watch", GRPC: config.GRPCMatchConfig{Service: "orders.v1.Orders", Method: "Watch"}, Pool: "streaming"},
+        {Name: "orders", GRPC: config.GRPCMatchConfig{Service: "orders.v1.Orders"}, Pool: "orders"},
+    }
+    rt, err := New(routes, testPools(t, "streaming", "orders", "default"), "default")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    grpc := map[string]string{"Content-Type": "application/grpc+proto"}
+    cases := []struct {
+        target   string
+        headers  map[string]string
+        expected string
+    }{
+        {"/orders.v1.Orders/Watch", grpc, "streaming"},
+        {"/orders.v1.Orders/Get", grpc, "orders"},
+        {"/orders.v1.OrdersAdmin/Get", grpc, "default"},
+        {"/orders.v1.Orders/Get/extra", grpc, "default"},
+        {"/orders.v1.Orders/Get", map[string]string{"Content-Type": "application/json"}, "default"},
+        {"/orders.v1.Orders/Get", map[string]string{"Content-Type": "application/grpc-web"}, "default"},
+    }
+    for _, c := range cases {
+        if pool := matchPool(rt, "POST", c.target, c.headers); pool != c.expected {
+            t.Errorf("%s (%s): Expected pool %q, got %q", c.target, c.headers["Content-Type"], c.expected, pool)
+        }
+    }
+}
+
+// TestRouterPriority verifies higher priorities win and ties keep configuration order
+func TestRouterPriority(t *testing.T) {
+    routes := []config.RouteConfig{
+        {Name: "first", PathPrefix: "/", Pool: "first"},
+        {Name: "second", PathPrefix: "/", Pool: "second"},
+        {Name: "specific", PathPrefix: "/v2/", Priority: 10, Pool: "specific"},
+    }
+    rt, err := New(routes, testPools(t, "first", "second", "specific"), "")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if pool := matchPool(rt, "GET", "/v2/items", nil); pool != "specific" {
+        t.Errorf("Expected higher priority route to win, got %q", pool)
+    }
+    if pool := matchPool(rt, "GET", "/v1/items", nil); pool != "first" {
+        t.Errorf("Expected first configured route on equal priority, got %q", pool)
+    }
+}
+
+// TestRouterNoDefault verifies unmatched requests yield no route without a default pool
+func TestRouterNoDefault(t *testing.T) {
+    routes := []config.RouteConfig{{Host: "api.example.com", Pool: "api"}}
+    rt, err := New(routes, testPools(t, "api"), "")
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+
+    if pool := matchPool(rt, "GET", "http://www.example.com/", nil); pool != "" {
+        t.Errorf("Expected no match, got %q", pool)
+    }
+}
+
+// TestRouterUnknownPool verifies routes referencing missing pools are rejected
+func TestRouterUnknownPool(t *testing.T) {
+    if _, err := New([]config.RouteConfig{{Pool: "missing"}}, testPools(t, "api"), ""); err == nil {
+        t.Error("Expected error for unknown route pool")
+    }
+    if _, err := New(nil, testPools(t, "api"), "missing"); err == nil {
+        t.Error("Expected error for unknown default pool")
+    }
+}